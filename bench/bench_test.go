@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"errors"
+	"flag"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+var sizes = flag.String("bench.sizes", "1K", "benchmark sizes, e.g. 1K,1M,10M")
+
+// go test -bench Suite -bench.sizes 1K,1M
+func BenchmarkSuite(b *testing.B) {
+	list, err := ParseSizes(*sizes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = forEach(Config{Sizes: list, Seed: 1}, func(r Result, bench func(b *testing.B)) {
+		b.Run(r.Name(), bench)
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+}
+
+// Test_Structures 各结构随机操作的结果一致
+func Test_Structures(t *testing.T) {
+	rd := rand.New(rand.NewSource(1))
+	keys := make([]int, 500)
+	for i := range keys {
+		keys[i] = rd.Intn(100)
+	}
+	var list []Structure
+	for _, name := range Structures {
+		s, _ := NewStructure(name)
+		s.Load(keys)
+		defer s.Close()
+		list = append(list, s)
+	}
+	for i := 0; i < 5000; i++ {
+		key := rd.Intn(110)
+		var want [3]int //数据、是否存在、排名
+		switch rd.Intn(4) {
+		case 0:
+			for _, s := range list {
+				s.Insert(key, i)
+			}
+		case 1:
+			ok := list[0].Delete(key)
+			for k, s := range list[1:] {
+				if s.Delete(key) != ok {
+					t.Fatalf("%s Delete(%d) != %v", Structures[k+1], key, ok)
+				}
+			}
+		case 2:
+			for k, s := range list {
+				data, ok := s.Get(key)
+				got := [3]int{data, 0, s.Rank(key)}
+				if ok {
+					got[1] = 1
+				}
+				if k == 0 {
+					want = got
+				} else if got != want {
+					t.Fatalf("%s Get/Rank(%d) = %v want %v", Structures[k], key, got, want)
+				}
+			}
+		case 3:
+			start := 1 + rd.Intn(list[0].Len()+10)
+			for k, s := range list {
+				if n := s.Range(start, 50); n != list[0].Range(start, 50) || s.Len() != list[0].Len() {
+					t.Fatalf("%s Range(%d) = %d", Structures[k], start, n)
+				}
+			}
+		}
+	}
+}
+
+// Test_Close 遍历结束后各结构都已关闭，不残留跳表的层数生成协程
+func Test_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+	cfg := Config{Sizes: []int{10, 20}, Seed: 1}
+	for i := 0; i < 10; i++ {
+		if err := forEach(cfg, func(r Result, bench func(b *testing.B)) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("遍历后协程数 %d -> %d", before, n)
+	}
+}
+
+func Test_Config(t *testing.T) {
+	sizes, err := ParseSizes("1K, 2M,10")
+	if err != nil || len(sizes) != 3 || sizes[0] != 1000 || sizes[1] != 2000000 || sizes[2] != 10 {
+		t.Fatalf("ParseSizes = %v, %v", sizes, err)
+	}
+	if SizeLabel(10000000) != "10M" || SizeLabel(1000) != "1K" || SizeLabel(1500) != "1500" {
+		t.Fatal("SizeLabel 错误")
+	}
+	if _, err = ParseSizes("1"); !errors.Is(err, sizeErr) {
+		t.Fatalf("want sizeErr, got %v", err)
+	}
+	noop := func(Result) {}
+	if err = Run(Config{Sizes: []int{10}, Structures: []string{"btree"}}, noop); !errors.Is(err, structureErr) {
+		t.Fatalf("want structureErr, got %v", err)
+	}
+	if err = Run(Config{Sizes: []int{10}, Ops: []string{"scan"}}, noop); !errors.Is(err, opErr) {
+		t.Fatalf("want opErr, got %v", err)
+	}
+	if err = Run(Config{Sizes: []int{10}, Distributions: []string{"zipf"}}, noop); !errors.Is(err, distributionErr) {
+		t.Fatalf("want distributionErr, got %v", err)
+	}
+	base := []Result{{Structure: "skiplist", Distribution: "random", Size: 1000, Op: "get", NsPerOp: 100}}
+	current := []Result{{Structure: "skiplist", Distribution: "random", Size: 1000, Op: "get", NsPerOp: 130}}
+	if list := Compare(base, current, 0.2); len(list) != 1 || list[0].Ratio != 1.3 {
+		t.Fatalf("Compare = %+v", list)
+	}
+	if list := Compare(base, current, 0.5); len(list) != 0 {
+		t.Fatalf("Compare = %+v", list)
+	}
+}
@@ -0,0 +1,10 @@
+package bench
+
+import "errors"
+
+var (
+	structureErr    = errors.New("unknown structure")
+	distributionErr = errors.New("unknown distribution")
+	opErr           = errors.New("unknown op")
+	sizeErr         = errors.New("size must grater than 1")
+)
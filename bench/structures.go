@@ -0,0 +1,247 @@
+package bench
+
+import (
+	"sort"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+// 被测的有序结构  key允许重复，相同key按插入顺序排列
+type Structure interface {
+	Load(keys []int)            //批量加载，data 为key在 keys 中的下标
+	Insert(key, data int)       //插入
+	Get(key int) (int, bool)    //查找key的第一个数据
+	Rank(key int) int           //key的第一个结点的排名 (从1开始)，不存在时返回-1
+	Range(start, count int) int //从第start名开始顺序访问count个结点，返回访问的结点数
+	Delete(key int) bool        //删除key的第一个结点
+	Len() int
+	Close() //释放结构持有的资源 (跳表的层数生成协程)
+}
+
+// 结构名称
+const (
+	StructSkipList    = "skiplist"    //skiplist.SkipList
+	StructSortedSlice = "sortedslice" //按key排序的切片，二分查找，插入删除移动元素
+	StructMapSort     = "mapsort"     //map 保存数据，排名及区间查询时对key排序 (写入后第一次查询时重新排序)
+)
+
+// 创建指定名称的结构
+func NewStructure(name string) (Structure, error) {
+	switch name {
+	case StructSkipList:
+		sl, err := skiplist.New(skiplist.Ordered[int]())
+		if err != nil {
+			return nil, err
+		}
+		return &skipListStructure{sl: sl}, nil
+	case StructSortedSlice:
+		return &sortedSlice{}, nil
+	case StructMapSort:
+		return &mapSort{}, nil
+	}
+	return nil, structureErr
+}
+
+type skipListStructure struct {
+	sl *skiplist.SkipList
+}
+
+// 按key排序后通过句柄顺序插入，加载不计入测试时间但需要足够快
+func (s *skipListStructure) Load(keys []int) {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+	hint := &skiplist.Hint{}
+	for _, i := range order {
+		s.sl.InsertAfterHint(hint, keys[i], i)
+	}
+}
+
+func (s *skipListStructure) Insert(key, data int) {
+	s.sl.Insert(key, data)
+}
+
+func (s *skipListStructure) Get(key int) (int, bool) {
+	data := s.sl.GetFirstByKey(key)
+	if data == nil {
+		return 0, false
+	}
+	return data.(int), true
+}
+
+func (s *skipListStructure) Rank(key int) int {
+	_, rank := s.sl.GetFirstWithRankByKey(key)
+	return rank
+}
+
+func (s *skipListStructure) Range(start, count int) int {
+	return len(s.sl.GetByRankRange(start, start+count-1))
+}
+
+func (s *skipListStructure) Delete(key int) bool {
+	_, rank := s.sl.GetFirstWithRankByKey(key)
+	return s.sl.DeleteByRank(rank)
+}
+
+func (s *skipListStructure) Len() int {
+	return s.sl.GetLength()
+}
+
+func (s *skipListStructure) Close() {
+	s.sl.Close()
+}
+
+// 保存遍历结果，避免遍历被视为无用代码
+var sink int
+
+type entry struct {
+	key, data int
+}
+
+type sortedSlice struct {
+	list []entry
+}
+
+// 第一个大于等于key的下标
+func (s *sortedSlice) lowerBound(key int) int {
+	return sort.Search(len(s.list), func(i int) bool { return s.list[i].key >= key })
+}
+
+func (s *sortedSlice) Load(keys []int) {
+	s.list = make([]entry, len(keys))
+	for i, key := range keys {
+		s.list[i] = entry{key, i}
+	}
+	sort.SliceStable(s.list, func(i, j int) bool { return s.list[i].key < s.list[j].key })
+}
+
+func (s *sortedSlice) Insert(key, data int) {
+	i := sort.Search(len(s.list), func(i int) bool { return s.list[i].key > key })
+	s.list = append(s.list, entry{})
+	copy(s.list[i+1:], s.list[i:])
+	s.list[i] = entry{key, data}
+}
+
+func (s *sortedSlice) Get(key int) (int, bool) {
+	if i := s.lowerBound(key); i < len(s.list) && s.list[i].key == key {
+		return s.list[i].data, true
+	}
+	return 0, false
+}
+
+func (s *sortedSlice) Rank(key int) int {
+	if i := s.lowerBound(key); i < len(s.list) && s.list[i].key == key {
+		return i + 1
+	}
+	return -1
+}
+
+func (s *sortedSlice) Range(start, count int) int {
+	visited, sum := 0, 0
+	for i := start - 1; i >= 0 && i < len(s.list) && visited < count; i++ {
+		sum += s.list[i].data
+		visited++
+	}
+	sink = sum
+	return visited
+}
+
+func (s *sortedSlice) Delete(key int) bool {
+	i := s.lowerBound(key)
+	if i == len(s.list) || s.list[i].key != key {
+		return false
+	}
+	s.list = append(s.list[:i], s.list[i+1:]...)
+	return true
+}
+
+func (s *sortedSlice) Len() int {
+	return len(s.list)
+}
+
+func (s *sortedSlice) Close() {}
+
+type mapSort struct {
+	data   map[int][]int //相同key的数据按插入顺序保存
+	keys   []int         //排序后的key (包含重复)
+	length int
+	dirty  bool //写入后 keys 需要重新排序
+}
+
+// 重新排序key
+func (m *mapSort) sorted() []int {
+	if m.dirty {
+		m.keys = m.keys[:0]
+		for key, list := range m.data {
+			for range list {
+				m.keys = append(m.keys, key)
+			}
+		}
+		sort.Ints(m.keys)
+		m.dirty = false
+	}
+	return m.keys
+}
+
+func (m *mapSort) Load(keys []int) {
+	m.data = make(map[int][]int, len(keys))
+	m.keys, m.length, m.dirty = nil, 0, true
+	for i, key := range keys {
+		m.Insert(key, i)
+	}
+}
+
+func (m *mapSort) Insert(key, data int) {
+	m.data[key] = append(m.data[key], data)
+	m.length++
+	m.dirty = true
+}
+
+func (m *mapSort) Get(key int) (int, bool) {
+	if list := m.data[key]; len(list) > 0 {
+		return list[0], true
+	}
+	return 0, false
+}
+
+func (m *mapSort) Rank(key int) int {
+	keys := m.sorted()
+	if i := sort.SearchInts(keys, key); i < len(keys) && keys[i] == key {
+		return i + 1
+	}
+	return -1
+}
+
+func (m *mapSort) Range(start, count int) int {
+	keys := m.sorted()
+	visited, sum := 0, 0
+	for i := start - 1; i >= 0 && i < len(keys) && visited < count; i++ {
+		sum += len(m.data[keys[i]])
+		visited++
+	}
+	sink = sum
+	return visited
+}
+
+func (m *mapSort) Delete(key int) bool {
+	list := m.data[key]
+	if len(list) == 0 {
+		return false
+	}
+	if len(list) == 1 {
+		delete(m.data, key)
+	} else {
+		m.data[key] = list[1:]
+	}
+	m.length--
+	m.dirty = true
+	return true
+}
+
+func (m *mapSort) Len() int {
+	return m.length
+}
+
+func (m *mapSort) Close() {}
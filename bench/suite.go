@@ -0,0 +1,296 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// key分布
+const (
+	DistRandom     = "random"     //随机key
+	DistSequential = "sequential" //递增key，新插入的key在末尾
+	DistDuplicate  = "duplicate"  //key在 n/100 个值中随机，大量重复
+)
+
+// 操作
+const (
+	OpInsert = "insert" //插入新key
+	OpGet    = "get"    //按key查找已存在的结点
+	OpRank   = "rank"   //查找已存在key的排名
+	OpRange  = "range"  //从随机排名开始顺序访问 RangeCount 个结点
+	OpDelete = "delete" //删除已存在的key
+)
+
+const (
+	RangeCount = 100  //区间遍历的结点数
+	batchSize  = 1024 //插入、删除测试每批的结点数，每批结束后停止计时并恢复结构大小
+	probeSize  = 4096 //预先生成的查找key数量
+)
+
+var (
+	Structures    = []string{StructSkipList, StructSortedSlice, StructMapSort}
+	Distributions = []string{DistRandom, DistSequential, DistDuplicate}
+	Ops           = []string{OpGet, OpRank, OpRange, OpInsert, OpDelete}
+)
+
+// 测试参数
+type Config struct {
+	Sizes         []int    //结点数
+	Structures    []string //为空时测试所有结构
+	Distributions []string //为空时测试所有分布
+	Ops           []string //为空时测试所有操作
+	Seed          int64
+}
+
+// 一项测试结果
+type Result struct {
+	Structure    string  `json:"structure"`
+	Distribution string  `json:"distribution"`
+	Size         int     `json:"size"`
+	Op           string  `json:"op"`
+	NsPerOp      float64 `json:"ns_per_op"`
+	AllocsPerOp  int64   `json:"allocs_per_op"`
+	BytesPerOp   int64   `json:"bytes_per_op"`
+}
+
+// 结果的唯一名称  例如 skiplist/random/1K/insert
+func (r Result) Name() string {
+	return strings.Join([]string{r.Structure, r.Distribution, SizeLabel(r.Size), r.Op}, "/")
+}
+
+// 一组测试数据
+type workload struct {
+	keys   []int //加载的key，按插入顺序
+	probes []int //查找用的已存在key
+	fresh  []int //插入测试使用的新key
+	victim []int //删除测试使用的已存在key，下标互不相同
+	starts []int //区间遍历的起始排名
+}
+
+// 生成n个结点的测试数据
+func newWorkload(dist string, n int, rd *rand.Rand) (*workload, error) {
+	w := &workload{keys: make([]int, n)}
+	gen := func() int { return rd.Int() }
+	switch dist {
+	case DistRandom:
+	case DistSequential:
+		next := 0
+		gen = func() int {
+			next++
+			return next
+		}
+	case DistDuplicate:
+		distinct := n/100 + 1
+		gen = func() int { return rd.Intn(distinct) }
+	default:
+		return nil, distributionErr
+	}
+	for i := range w.keys {
+		w.keys[i] = gen()
+	}
+	batch := min(batchSize, n/2)
+	for i := 0; i < batch; i++ {
+		w.fresh = append(w.fresh, gen())
+		w.victim = append(w.victim, w.keys[i*(n/batch)])
+	}
+	for i := 0; i < probeSize; i++ {
+		w.probes = append(w.probes, w.keys[rd.Intn(n)])
+		w.starts = append(w.starts, 1+rd.Intn(max(1, n-RangeCount)))
+	}
+	return w, nil
+}
+
+// 执行一项操作的测试  结构在测试前后大小不变，可以依次用于各项操作
+func runOp(b *testing.B, op string, s Structure, w *workload) {
+	switch op {
+	case OpGet:
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Get(w.probes[i%probeSize])
+		}
+	case OpRank:
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Rank(w.probes[i%probeSize])
+		}
+	case OpRange:
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s.Range(w.starts[i%probeSize], RangeCount)
+		}
+	case OpInsert:
+		batch(b, w.fresh, func(key int) { s.Insert(key, -1) }, func(key int) { s.Delete(key) })
+	case OpDelete:
+		batch(b, w.victim, func(key int) { s.Delete(key) }, func(key int) { s.Insert(key, -1) })
+	}
+}
+
+// 按批执行测试操作  每批结束后停止计时，执行恢复操作
+func batch(b *testing.B, keys []int, do, undo func(key int)) {
+	b.ResetTimer()
+	j := 0
+	for i := 0; i < b.N; i++ {
+		do(keys[j])
+		if j++; j == len(keys) {
+			b.StopTimer()
+			for _, key := range keys {
+				undo(key)
+			}
+			j = 0
+			b.StartTimer()
+		}
+	}
+	b.StopTimer()
+	for _, key := range keys[:j] {
+		undo(key)
+	}
+}
+
+// 默认值填充及参数校验
+func (cfg *Config) normalize() error {
+	if len(cfg.Structures) == 0 {
+		cfg.Structures = Structures
+	}
+	if len(cfg.Distributions) == 0 {
+		cfg.Distributions = Distributions
+	}
+	if len(cfg.Ops) == 0 {
+		cfg.Ops = Ops
+	}
+	for _, n := range cfg.Sizes {
+		if n < 2 {
+			return sizeErr
+		}
+	}
+	for _, name := range cfg.Structures {
+		s, err := NewStructure(name)
+		if err != nil {
+			return fmt.Errorf("bench: %q: %w", name, err)
+		}
+		s.Close()
+	}
+	for _, op := range cfg.Ops {
+		if !contains(Ops, op) {
+			return fmt.Errorf("bench: %q: %w", op, opErr)
+		}
+	}
+	for _, dist := range cfg.Distributions {
+		if !contains(Distributions, dist) {
+			return fmt.Errorf("bench: %q: %w", dist, distributionErr)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+遍历各项测试  每个 (结点数, 分布, 结构) 只加载一次，依次执行各项操作
+fn 为每项测试的执行函数，由 Run 使用 testing.Benchmark 执行，go test 中使用 b.Run 执行
+*/
+func forEach(cfg Config, fn func(r Result, bench func(b *testing.B))) error {
+	if err := cfg.normalize(); err != nil {
+		return err
+	}
+	for _, n := range cfg.Sizes {
+		for _, dist := range cfg.Distributions {
+			w, err := newWorkload(dist, n, rand.New(rand.NewSource(cfg.Seed)))
+			if err != nil {
+				return err
+			}
+			for _, name := range cfg.Structures {
+				s, _ := NewStructure(name)
+				s.Load(w.keys)
+				for _, op := range cfg.Ops {
+					r := Result{Structure: name, Distribution: dist, Size: n, Op: op}
+					fn(r, func(b *testing.B) {
+						b.ReportAllocs()
+						runOp(b, op, s, w)
+					})
+				}
+				s.Close()
+			}
+		}
+	}
+	return nil
+}
+
+// 执行测试，每完成一项调用一次 report
+func Run(cfg Config, report func(Result)) error {
+	return forEach(cfg, func(r Result, bench func(b *testing.B)) {
+		res := testing.Benchmark(bench)
+		r.NsPerOp = float64(res.T.Nanoseconds()) / float64(res.N)
+		r.AllocsPerOp, r.BytesPerOp = res.AllocsPerOp(), res.AllocedBytesPerOp()
+		report(r)
+	})
+}
+
+// 结点数的简写  1000 -> 1K，1000000 -> 1M
+func SizeLabel(n int) string {
+	switch {
+	case n >= 1000000 && n%1000000 == 0:
+		return strconv.Itoa(n/1000000) + "M"
+	case n >= 1000 && n%1000 == 0:
+		return strconv.Itoa(n/1000) + "K"
+	}
+	return strconv.Itoa(n)
+}
+
+// 解析逗号分隔的结点数，支持 K、M 后缀  例如 "1K,1M,10M"
+func ParseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		unit := 1
+		switch {
+		case strings.HasSuffix(item, "K"):
+			unit, item = 1000, strings.TrimSuffix(item, "K")
+		case strings.HasSuffix(item, "M"):
+			unit, item = 1000000, strings.TrimSuffix(item, "M")
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("bench: size %q: %w", item, err)
+		}
+		if n*unit < 2 {
+			return nil, sizeErr
+		}
+		sizes = append(sizes, n*unit)
+	}
+	return sizes, nil
+}
+
+// 性能回退的测试项
+type Regression struct {
+	Base, Current Result
+	Ratio         float64 //Current.NsPerOp / Base.NsPerOp
+}
+
+// 与基准结果比较，返回耗时超过基准 (1+threshold) 倍的测试项
+func Compare(base, current []Result, threshold float64) []Regression {
+	index := make(map[string]Result, len(base))
+	for _, r := range base {
+		index[r.Name()] = r
+	}
+	var list []Regression
+	for _, r := range current {
+		b, ok := index[r.Name()]
+		if !ok || b.NsPerOp <= 0 {
+			continue
+		}
+		if ratio := r.NsPerOp / b.NsPerOp; ratio > 1+threshold {
+			list = append(list, Regression{Base: b, Current: r, Ratio: ratio})
+		}
+	}
+	return list
+}
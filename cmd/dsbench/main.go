@@ -0,0 +1,140 @@
+/*
+有序结构的性能测试报告
+
+	go run ./cmd/dsbench -sizes 1K,1M,10M
+	go run ./cmd/dsbench -sizes 1K,1M -json base.json
+	go run ./cmd/dsbench -sizes 1K,1M -baseline base.json -threshold 0.2
+
+按结点数、key分布、操作输出各结构的 ns/op 与 allocs/op，每项测试的时长可以通过 -test.benchtime 设置; 指定 -baseline 时与之前保存的结果比较，
+存在耗时超过基准 (1+threshold) 倍的测试项时以状态码1退出，可用于发现性能回退
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/yytany/ds/bench"
+)
+
+func main() {
+	testing.Init()
+	sizes := flag.String("sizes", "1K,1M,10M", "comma separated sizes, K/M suffix allowed")
+	structures := flag.String("structures", "", "comma separated structures (default all: "+strings.Join(bench.Structures, ",")+")")
+	dists := flag.String("dists", "", "comma separated key distributions (default all: "+strings.Join(bench.Distributions, ",")+")")
+	ops := flag.String("ops", "", "comma separated ops (default all: "+strings.Join(bench.Ops, ",")+")")
+	seed := flag.Int64("seed", 1, "random seed of the workloads")
+	jsonPath := flag.String("json", "", "write results to this file as JSON")
+	baseline := flag.String("baseline", "", "compare with results saved by -json")
+	threshold := flag.Float64("threshold", 0.1, "allowed slowdown against the baseline, 0.1 means 10%")
+	flag.Parse()
+
+	cfg := bench.Config{
+		Structures:    split(*structures),
+		Distributions: split(*dists),
+		Ops:           split(*ops),
+		Seed:          *seed,
+	}
+	var err error
+	if cfg.Sizes, err = bench.ParseSizes(*sizes); err != nil {
+		fatal(err)
+	}
+	var results []bench.Result
+	err = bench.Run(cfg, func(r bench.Result) {
+		fmt.Fprintf(os.Stderr, "%-40s %12.1f ns/op\n", r.Name(), r.NsPerOp)
+		results = append(results, r)
+	})
+	if err != nil {
+		fatal(err)
+	}
+	printReport(results)
+
+	if *jsonPath != "" {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		if err = os.WriteFile(*jsonPath, b, 0644); err != nil {
+			fatal(err)
+		}
+	}
+	if *baseline != "" {
+		b, err := os.ReadFile(*baseline)
+		if err != nil {
+			fatal(err)
+		}
+		var base []bench.Result
+		if err = json.Unmarshal(b, &base); err != nil {
+			fatal(err)
+		}
+		if regressions := bench.Compare(base, results, *threshold); len(regressions) > 0 {
+			fmt.Printf("\n%d regressions (threshold %.0f%%):\n", len(regressions), *threshold*100)
+			for _, r := range regressions {
+				fmt.Printf("  %-40s %12.1f -> %12.1f ns/op  x%.2f\n", r.Current.Name(), r.Base.NsPerOp, r.Current.NsPerOp, r.Ratio)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("\nno regressions")
+	}
+}
+
+// 按 结点数/分布/操作 分行，每个结构一列
+func printReport(results []bench.Result) {
+	var structures, rows []string
+	cells := map[string]map[string]bench.Result{}
+	for _, r := range results {
+		row := strings.Join([]string{bench.SizeLabel(r.Size), r.Distribution, r.Op}, "\t")
+		if cells[row] == nil {
+			cells[row] = map[string]bench.Result{}
+			rows = append(rows, row)
+		}
+		if !contains(structures, r.Structure) {
+			structures = append(structures, r.Structure)
+		}
+		cells[row][r.Structure] = r
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "size\tdist\top\t")
+	for _, s := range structures {
+		fmt.Fprintf(w, "%s ns/op\t%s allocs\t", s, s)
+	}
+	fmt.Fprintln(w)
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t", row)
+		for _, s := range structures {
+			if r, ok := cells[row][s]; ok {
+				fmt.Fprintf(w, "%.1f\t%d\t", r.NsPerOp, r.AllocsPerOp)
+			} else {
+				fmt.Fprintf(w, "-\t-\t")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
@@ -0,0 +1,13 @@
+package hashring
+
+import "errors"
+
+var (
+	emptyRingErr      = errors.New("hash ring is empty")
+	memberExistsErr   = errors.New("member already exists")
+	memberNotFoundErr = errors.New("member not found")
+	weightErr         = errors.New("weight must grater than 0")
+	virtualNodesErr   = errors.New("virtual nodes must grater than 0")
+	loadFactorErr     = errors.New("load factor must grater than 1")
+	hashErr           = errors.New("hash function is nil")
+)
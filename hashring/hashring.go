@@ -0,0 +1,259 @@
+package hashring
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+const (
+	defaultVirtualNodes = 160  //默认每单位权重的虚拟结点数
+	defaultLoadFactor   = 1.25 //默认负载因子
+	maxCollisionRetry   = 16   //虚拟结点哈希冲突时的重试次数
+)
+
+// 成员信息
+type member struct {
+	name   string
+	weight int
+	hashes []uint64 //该成员的虚拟结点哈希值
+	load   int      //有界负载模式下当前分配到的负载
+}
+
+/*
+一致性哈希环
+虚拟结点以哈希值为key存储在 skiplist.SkipList 中，查找时取第一个大于等于key哈希值的虚拟结点，超过末尾时回到环首
+*/
+type Ring struct {
+	mu           sync.RWMutex
+	sl           *skiplist.SkipList //hash -> *member
+	members      map[string]*member
+	virtualNodes int
+	hash         func(data []byte) uint64
+	loadFactor   float64
+	totalLoad    int
+}
+
+func New(options ...Option) (*Ring, error) {
+	sl, err := skiplist.New(skiplist.Ordered[uint64](), skiplist.WithAllowTheSameKey(false))
+	if err != nil {
+		return nil, err
+	}
+	r := &Ring{
+		sl:           sl,
+		members:      map[string]*member{},
+		virtualNodes: defaultVirtualNodes,
+		hash:         defaultHash,
+		loadFactor:   defaultLoadFactor,
+	}
+	for k := range options {
+		if err := options[k](r); err != nil {
+			sl.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// 关闭哈希环  结束跳表的层数生成协程，不再使用时需要调用，关闭后仍可增删成员及查找
+func (r *Ring) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sl.Close()
+}
+
+// 默认哈希  fnv-1a 后再做一次 splitmix64 混合，改善短字符串的分布
+func defaultHash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// 添加成员  虚拟结点数 = weight * 每单位权重的虚拟结点数
+func (r *Ring) Add(name string, weight int) error {
+	if weight < 1 {
+		return weightErr
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.members[name]; ok {
+		return memberExistsErr
+	}
+	m := &member{name: name, weight: weight}
+	count := weight * r.virtualNodes
+	for i := 0; i < count; i++ {
+		//与已有虚拟结点冲突时换一个后缀重试，仍冲突则放弃该虚拟结点
+		for retry := 0; retry < maxCollisionRetry; retry++ {
+			h := r.hash([]byte(name + "#" + strconv.Itoa(i) + "#" + strconv.Itoa(retry)))
+			if _, ok := r.sl.Insert(h, m); ok {
+				m.hashes = append(m.hashes, h)
+				break
+			}
+		}
+	}
+	r.members[name] = m
+	return nil
+}
+
+// 移除成员
+func (r *Ring) Remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.members[name]
+	if !ok {
+		return memberNotFoundErr
+	}
+	for _, h := range m.hashes {
+		r.sl.DeleteByKey(h)
+	}
+	r.totalLoad -= m.load
+	delete(r.members, name)
+	return nil
+}
+
+// 成员列表
+func (r *Ring) Members() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.members))
+	for name := range r.members {
+		names = append(names, name)
+	}
+	return names
+}
+
+// 虚拟结点数量
+func (r *Ring) VirtualNodes() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.sl.GetLength()
+}
+
+// 顺时针找到第一个虚拟结点的排位，超过末尾时回到环首
+func (r *Ring) locate(key string) int {
+	_, rank := r.sl.GetCeilingWithRankByKey(r.hash([]byte(key)))
+	if rank < 1 {
+		return 1
+	}
+	return rank
+}
+
+/*
+从key所在的虚拟结点开始顺时针遍历，沿第0层后继前进，超过末尾时回到环首
+每个虚拟结点最多访问一次，fn 返回 false 时停止
+*/
+func (r *Ring) walk(key string, fn func(m *member) bool) {
+	it := skiplist.NewIterator(r.sl)
+	if it.Seek(r.hash([]byte(key))); !it.Valid() {
+		it.InitHead()
+		it.Next(0)
+	}
+	for i := r.sl.GetLength(); i > 0 && fn(it.Data().(*member)); i-- {
+		if it.Next(0) == nil {
+			it.InitHead()
+			it.Next(0)
+		}
+	}
+}
+
+// 查找key所属的成员
+func (r *Ring) Lookup(key string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.sl.GetLength() == 0 {
+		return "", emptyRingErr
+	}
+	return r.sl.GetByRank(r.locate(key)).(*member).name, nil
+}
+
+/*
+查找key的 n 个不同成员 (用于副本)，按顺时针顺序返回
+第一个与 Lookup 的结果相同; 成员不足 n 个时返回全部成员
+*/
+func (r *Ring) GetN(key string, n int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.sl.GetLength() == 0 {
+		return nil, emptyRingErr
+	}
+	if n > len(r.members) {
+		n = len(r.members)
+	}
+	names := make([]string, 0, n)
+	seen := make(map[*member]bool, n)
+	r.walk(key, func(m *member) bool {
+		if !seen[m] {
+			seen[m] = true
+			names = append(names, m.name)
+		}
+		return len(names) < n
+	})
+	return names, nil
+}
+
+/*
+有界负载查找 (Consistent Hashing with Bounded Loads)
+顺时针找到第一个负载未达到上限的成员并为其增加一个负载，上限为 ceil(loadFactor * (总负载+1) / 总权重 * 成员权重)。
+使用完后需要调用 Release 归还负载
+*/
+func (r *Ring) Acquire(key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sl.GetLength() == 0 {
+		return "", emptyRingErr
+	}
+	totalWeight := 0
+	for _, m := range r.members {
+		totalWeight += m.weight
+	}
+	average := float64(r.totalLoad+1) / float64(totalWeight)
+	var found *member
+	r.walk(key, func(m *member) bool {
+		if float64(m.load) < math.Ceil(r.loadFactor*average*float64(m.weight)) {
+			found = m
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		//上限按平均负载向上取整，总能找到未满的成员，这里只作兜底
+		return "", emptyRingErr
+	}
+	found.load++
+	r.totalLoad++
+	return found.name, nil
+}
+
+// 归还 Acquire 分配的负载
+func (r *Ring) Release(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.members[name]
+	if !ok {
+		return memberNotFoundErr
+	}
+	if m.load > 0 {
+		m.load--
+		r.totalLoad--
+	}
+	return nil
+}
+
+// 成员当前的负载
+func (r *Ring) Load(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if m, ok := r.members[name]; ok {
+		return m.load
+	}
+	return 0
+}
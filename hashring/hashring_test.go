@@ -0,0 +1,194 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func lookupAll(t *testing.T, r *Ring, keys []string) map[string]string {
+	t.Helper()
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owner, err := r.Lookup(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[key] = owner
+	}
+	return owners
+}
+
+func makeKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("cache:key:%d", i)
+	}
+	return keys
+}
+
+// TestRedistribution 增删成员时只有少量key迁移，且只在变化的成员上迁移
+func TestRedistribution(t *testing.T) {
+	r, _ := New()
+	if _, err := r.Lookup("a"); err != emptyRingErr {
+		t.Fatalf("空环应返回错误, got: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		r.Add(fmt.Sprintf("worker-%d", i), 1)
+	}
+	keys := makeKeys(20000)
+	before := lookupAll(t, r, keys)
+
+	r.Add("worker-4", 1)
+	after := lookupAll(t, r, keys)
+	moved := 0
+	for _, key := range keys {
+		if before[key] != after[key] {
+			moved++
+			if after[key] != "worker-4" {
+				t.Fatalf("key %s 从 %s 迁移到了 %s", key, before[key], after[key])
+			}
+		}
+	}
+	//期望迁移 1/5
+	if ratio := float64(moved) / float64(len(keys)); ratio < 0.15 || ratio > 0.25 {
+		t.Fatalf("新增成员后迁移比例异常: %.3f", ratio)
+	}
+
+	r.Remove("worker-1")
+	removed := lookupAll(t, r, keys)
+	for _, key := range keys {
+		if after[key] != "worker-1" && after[key] != removed[key] {
+			t.Fatalf("key %s 不属于被移除的成员，不应迁移", key)
+		}
+		if removed[key] == "worker-1" {
+			t.Fatal("被移除的成员不应再被选中")
+		}
+	}
+	if err := r.Remove("worker-1"); err != memberNotFoundErr {
+		t.Fatalf("重复移除应失败, got: %v", err)
+	}
+	if err := r.Add("worker-0", 1); err != memberExistsErr {
+		t.Fatalf("重复添加应失败, got: %v", err)
+	}
+}
+
+// TestWeight 负载与权重成正比
+func TestWeight(t *testing.T) {
+	r, _ := New()
+	r.Add("small", 1)
+	r.Add("large", 3)
+	if r.VirtualNodes() != 4*defaultVirtualNodes {
+		t.Fatalf("虚拟结点数错误: %d", r.VirtualNodes())
+	}
+	counts := map[string]int{}
+	for _, owner := range lookupAll(t, r, makeKeys(40000)) {
+		counts[owner]++
+	}
+	if ratio := float64(counts["large"]) / float64(counts["small"]); ratio < 2.4 || ratio > 3.6 {
+		t.Fatalf("权重 3:1 的分布比例异常: %v", counts)
+	}
+}
+
+// TestGetN 副本成员不重复且第一个与 Lookup 一致
+func TestGetN(t *testing.T) {
+	r, _ := New(WithVirtualNodes(20))
+	for i := 0; i < 5; i++ {
+		r.Add(fmt.Sprintf("worker-%d", i), 1)
+	}
+	for _, key := range makeKeys(500) {
+		owner, _ := r.Lookup(key)
+		replicas, err := r.GetN(key, 3)
+		if err != nil || len(replicas) != 3 || replicas[0] != owner {
+			t.Fatalf("GetN(%s) = %v, %v, owner %s", key, replicas, err, owner)
+		}
+		if replicas[0] == replicas[1] || replicas[1] == replicas[2] || replicas[0] == replicas[2] {
+			t.Fatalf("副本成员重复: %v", replicas)
+		}
+	}
+	if all, _ := r.GetN("x", 10); len(all) != 5 {
+		t.Fatalf("成员不足时应返回全部成员: %v", all)
+	}
+	//与按排位顺时针查找的结果对比，覆盖越过末尾回到环首的情况
+	length := r.sl.GetLength()
+	for _, key := range append(makeKeys(500), "x") {
+		var want []string
+		seen := map[string]bool{}
+		for i, rank := 0, r.locate(key); i < length && len(want) < 4; i++ {
+			if name := r.sl.GetByRank(rank).(*member).name; !seen[name] {
+				seen[name] = true
+				want = append(want, name)
+			}
+			if rank++; rank > length {
+				rank = 1
+			}
+		}
+		if got, _ := r.GetN(key, 4); !slices.Equal(got, want) {
+			t.Fatalf("GetN(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// TestBoundedLoad 有界负载模式下任何成员的负载都不超过上限
+func TestBoundedLoad(t *testing.T) {
+	r, _ := New(WithVirtualNodes(10), WithLoadFactor(1.25))
+	for i := 0; i < 4; i++ {
+		r.Add(fmt.Sprintf("worker-%d", i), 1)
+	}
+	owners := map[string]string{}
+	for _, key := range makeKeys(1000) {
+		owner, err := r.Acquire(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[key] = owner
+	}
+	limit := int(math.Ceil(1.25 * 1000 / 4))
+	for _, name := range r.Members() {
+		if load := r.Load(name); load > limit {
+			t.Fatalf("%s 的负载 %d 超过上限 %d", name, load, limit)
+		}
+	}
+	for key, owner := range owners {
+		if err := r.Release(owner); err != nil {
+			t.Fatalf("Release(%s) for %s: %v", owner, key, err)
+		}
+	}
+	for _, name := range r.Members() {
+		if r.Load(name) != 0 {
+			t.Fatalf("归还后负载应为0: %s %d", name, r.Load(name))
+		}
+	}
+	if _, err := New(WithLoadFactor(1)); err != loadFactorErr {
+		t.Fatalf("负载因子校验错误: %v", err)
+	}
+}
+
+// TestClose 测试关闭后协程退出且仍可增删成员及查找
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	list := []*Ring{}
+	for i := 0; i < 20; i++ {
+		r, _ := New(WithVirtualNodes(10))
+		r.Add("worker-0", 1)
+		list = append(list, r)
+	}
+	for _, r := range list {
+		r.Close()
+	}
+	if _, err := New(WithVirtualNodes(0)); err == nil {
+		t.Fatal("want error")
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	r := list[0]
+	if err := r.Add("worker-1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := r.GetN("x", 2); err != nil || len(all) != 2 || r.VirtualNodes() != 20 {
+		t.Fatalf("关闭后读写错误: %v, %v", all, err)
+	}
+}
@@ -0,0 +1,36 @@
+package hashring
+
+type Option func(*Ring) error
+
+// 设置每单位权重的虚拟结点数
+func WithVirtualNodes(n int) Option {
+	return func(r *Ring) error {
+		if n < 1 {
+			return virtualNodesErr
+		}
+		r.virtualNodes = n
+		return nil
+	}
+}
+
+// 设置哈希函数  需要在各进程间保持一致
+func WithHash(hash func(data []byte) uint64) Option {
+	return func(r *Ring) error {
+		if hash == nil {
+			return hashErr
+		}
+		r.hash = hash
+		return nil
+	}
+}
+
+// 设置有界负载的负载因子  每个成员的负载上限为 ceil(loadFactor * 平均负载)
+func WithLoadFactor(loadFactor float64) Option {
+	return func(r *Ring) error {
+		if loadFactor <= 1 {
+			return loadFactorErr
+		}
+		r.loadFactor = loadFactor
+		return nil
+	}
+}
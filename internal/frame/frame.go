@@ -0,0 +1,153 @@
+// Package frame 是 wal 日志记录与 replication 消息共用的帧编码
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+)
+
+/*
+帧格式
+	uint32(payload length) | uint32(crc32 of payload) | payload
+	payload: byte(type) | uvarint(seq) | uvarint(rank) | uvarint(len(key)) key | uvarint(len(data)) data
+*/
+
+const (
+	HeaderSize = 8
+	MaxSize    = 1 << 30  //单帧上限，长度字段超过该值视为损坏
+	readChunk  = 64 << 10 //payload 按块读取，分配的空间不超过实际读到的数据，不会按长度字段一次分配
+)
+
+var CorruptedErr = errors.New("frame is corrupted")
+
+// 一帧  type 的含义由使用方定义，key/data 为编码后的字节
+type Frame struct {
+	Type byte
+	Seq  uint64
+	Rank int
+	Key  []byte
+	Data []byte
+}
+
+// 编码帧(含头部)
+func (f *Frame) Marshal() []byte {
+	buf := make([]byte, HeaderSize, HeaderSize+1+4*binary.MaxVarintLen64+len(f.Key)+len(f.Data))
+	buf = append(buf, f.Type)
+	buf = binary.AppendUvarint(buf, f.Seq)
+	buf = binary.AppendUvarint(buf, uint64(f.Rank))
+	buf = binary.AppendUvarint(buf, uint64(len(f.Key)))
+	buf = append(buf, f.Key...)
+	buf = binary.AppendUvarint(buf, uint64(len(f.Data)))
+	buf = append(buf, f.Data...)
+	payload := buf[HeaderSize:]
+	binary.LittleEndian.PutUint32(buf[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	return buf
+}
+
+// 解码payload
+func (f *Frame) Unmarshal(payload []byte) error {
+	if len(payload) < 1 {
+		return CorruptedErr
+	}
+	f.Type = payload[0]
+	payload = payload[1:]
+	var n int
+	if f.Seq, n = binary.Uvarint(payload); n <= 0 {
+		return CorruptedErr
+	}
+	payload = payload[n:]
+	rank, n := binary.Uvarint(payload)
+	if n <= 0 || rank > math.MaxInt {
+		return CorruptedErr
+	}
+	f.Rank = int(rank)
+	payload = payload[n:]
+	var err error
+	if f.Key, payload, err = readBytes(payload); err != nil {
+		return err
+	}
+	if f.Data, payload, err = readBytes(payload); err != nil {
+		return err
+	}
+	if len(payload) != 0 {
+		return CorruptedErr
+	}
+	return nil
+}
+
+func readBytes(b []byte) ([]byte, []byte, error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < l {
+		return nil, nil, CorruptedErr
+	}
+	b = b[n:]
+	return b[:l:l], b[l:], nil
+}
+
+// 按块读取n个字节的payload  数据不足时返回读取错误
+func readPayload(r io.Reader, n int) ([]byte, error) {
+	payload := make([]byte, 0, min(n, readChunk))
+	for len(payload) < n {
+		chunk := min(n-len(payload), readChunk)
+		payload = slices.Grow(payload, chunk)
+		m, err := io.ReadFull(r, payload[len(payload):len(payload)+chunk])
+		payload = payload[:len(payload)+m]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payload, nil
+}
+
+// 顺序读取帧
+type Reader struct {
+	r      *bufio.Reader
+	Offset int64 //最后一个完整帧的结束位置
+	End    int64 //最后读取的帧按长度字段计算的结束位置，包括损坏的帧
+}
+
+func NewReader(r io.Reader) *Reader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return &Reader{r: br}
+	}
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+/*
+读取下一帧
+返回 io.EOF 表示正常结束; 返回 io.ErrUnexpectedEOF 表示尾部存在写了一半的帧;
+返回 CorruptedErr 表示帧损坏，End 没有超过数据末尾时损坏的帧之后还有数据
+*/
+func (fr *Reader) Next() (*Frame, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(fr.r, header); err != nil {
+		return nil, err
+	}
+	length := binary.LittleEndian.Uint32(header[0:])
+	fr.End = fr.Offset + HeaderSize + int64(length)
+	if length > MaxSize {
+		return nil, CorruptedErr
+	}
+	payload, err := readPayload(fr.r, int(length))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, CorruptedErr
+	}
+	f := &Frame{}
+	if err := f.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+	fr.Offset += int64(HeaderSize + len(payload))
+	return f, nil
+}
@@ -0,0 +1,98 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"testing"
+)
+
+// TestRoundTrip 编码后顺序读取，Offset 为最后一个完整帧的结束位置
+func TestRoundTrip(t *testing.T) {
+	frames := []*Frame{
+		{Type: 1, Seq: 1, Rank: 3, Key: []byte("key"), Data: []byte("data")},
+		{Type: 2, Seq: 1 << 60, Key: []byte{}, Data: []byte{}},
+	}
+	var buf bytes.Buffer
+	for _, f := range frames {
+		buf.Write(f.Marshal())
+	}
+	size := int64(buf.Len())
+	fr := NewReader(&buf)
+	for _, want := range frames {
+		f, err := fr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Type != want.Type || f.Seq != want.Seq || f.Rank != want.Rank ||
+			!bytes.Equal(f.Key, want.Key) || !bytes.Equal(f.Data, want.Data) {
+			t.Fatalf("got %+v want %+v", f, want)
+		}
+	}
+	if _, err := fr.Next(); err != io.EOF {
+		t.Fatalf("want io.EOF, got %v", err)
+	}
+	if fr.Offset != size {
+		t.Fatalf("Offset = %d want %d", fr.Offset, size)
+	}
+}
+
+// TestCorrupted 写了一半的帧返回 io.ErrUnexpectedEOF，校验失败或字段越界返回 CorruptedErr
+func TestCorrupted(t *testing.T) {
+	good := (&Frame{Type: 1, Seq: 1, Key: []byte("key")}).Marshal()
+	torn := append(append([]byte{}, good...), good[:len(good)-1]...)
+	fr := NewReader(bytes.NewReader(torn))
+	if _, err := fr.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fr.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+	}
+	if fr.Offset != int64(len(good)) {
+		t.Fatalf("Offset = %d want %d", fr.Offset, len(good))
+	}
+
+	crc := append([]byte{}, good...)
+	crc[len(crc)-1] ^= 0xff
+	if _, err := NewReader(bytes.NewReader(crc)).Next(); !errors.Is(err, CorruptedErr) {
+		t.Fatalf("want CorruptedErr, got %v", err)
+	}
+
+	//rank 超过 int 范围
+	payload := []byte{1, 1}
+	payload = binary.AppendUvarint(payload, 1<<63)
+	payload = append(payload, 0, 0)
+	if err := (&Frame{}).Unmarshal(payload); !errors.Is(err, CorruptedErr) {
+		t.Fatalf("want CorruptedErr, got %v", err)
+	}
+}
+
+// TestLargeLength 长度字段很大而数据很少时，不按长度字段分配空间
+func TestLargeLength(t *testing.T) {
+	header := make([]byte, HeaderSize)
+	binary.LittleEndian.PutUint32(header, MaxSize)
+	data := append(header, make([]byte, 100)...)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < 10; i++ {
+		if _, err := NewReader(bytes.NewReader(data)).Next(); err != io.ErrUnexpectedEOF {
+			t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 10<<20 {
+		t.Fatalf("分配了 %d 字节", n)
+	}
+
+	//跨越多个块的帧
+	big := (&Frame{Type: 1, Seq: 1, Data: bytes.Repeat([]byte("x"), 3*readChunk+7)}).Marshal()
+	f, err := NewReader(bytes.NewReader(big)).Next()
+	if err != nil || len(f.Data) != 3*readChunk+7 {
+		t.Fatalf("读取大帧失败: %v", err)
+	}
+	if _, err := NewReader(bytes.NewReader(big[:len(big)-1])).Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+	}
+}
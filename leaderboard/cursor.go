@@ -0,0 +1,70 @@
+package leaderboard
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+)
+
+/*
+分页游标
+游标记录上一页最后一条记录的 (分数, 提交序号, 玩家)，下一页从该位置之后继续。
+分数变化时不会因为排位整体移动而重复或遗漏未变化的玩家
+*/
+
+const cursorVersion = 1
+
+func encodeCursor(key scoreKey) string {
+	buf := make([]byte, 0, 17+len(key.player))
+	buf = append(buf, cursorVersion)
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(key.score))
+	buf = binary.BigEndian.AppendUint64(buf, key.seq)
+	buf = append(buf, key.player...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func decodeCursor(cursor string) (scoreKey, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(buf) < 17 || buf[0] != cursorVersion {
+		return scoreKey{}, cursorErr
+	}
+	return scoreKey{
+		score:  math.Float64frombits(binary.BigEndian.Uint64(buf[1:])),
+		seq:    binary.BigEndian.Uint64(buf[9:]),
+		player: string(buf[17:]),
+	}, nil
+}
+
+/*
+分页获取
+cursor 为空时从第一名开始，返回本页记录及下一页的游标，没有更多记录时游标为空
+*/
+func (lb *Leaderboard) Page(cursor string, limit int) ([]Entry, string, error) {
+	if limit < 1 {
+		return nil, "", limitErr
+	}
+	start := 1
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	if cursor != "" {
+		last, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		//上一页最后一条之后的第一条
+		data, rank := lb.sl.GetCeilingWithRankByKey(last)
+		if rank < 1 {
+			return []Entry{}, "", nil
+		}
+		if data.(scoreKey) == last {
+			rank++
+		}
+		start = rank
+	}
+	list := lb.entries(start, start+limit-1)
+	next := ""
+	if len(list) == limit && start+limit-1 < lb.sl.GetLength() {
+		next = encodeCursor(lb.sl.GetByRank(start + limit - 1).(scoreKey))
+	}
+	return list, next, nil
+}
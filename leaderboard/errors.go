@@ -0,0 +1,11 @@
+package leaderboard
+
+import "errors"
+
+var (
+	playerNotFoundErr = errors.New("player not found")
+	cursorErr         = errors.New("invalid cursor")
+	limitErr          = errors.New("limit must grater than 0")
+	submitPolicyErr   = errors.New("unknown submit policy")
+	rankModeErr       = errors.New("unknown rank mode")
+)
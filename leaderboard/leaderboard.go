@@ -0,0 +1,221 @@
+package leaderboard
+
+import (
+	"cmp"
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+// 排行榜中的一条记录
+type Entry struct {
+	Player string
+	Score  float64
+	Rank   int //按 RankMode 计算的排名
+}
+
+// 跳表中的key  分数好的在前，同分时先达到的在前
+type scoreKey struct {
+	score  float64
+	seq    uint64 //提交序号
+	player string
+}
+
+/*
+排行榜
+玩家按分数存储在 skiplist.SkipList 中，另用一个跳表记录不同分数及人数，用于密集排名。
+*/
+type Leaderboard struct {
+	mu            sync.RWMutex
+	sl            *skiplist.SkipList //scoreKey -> scoreKey
+	scores        *skiplist.SkipList //score -> 该分数的人数 (*int)
+	players       map[string]scoreKey
+	seq           uint64
+	policy        SubmitPolicy
+	mode          RankMode
+	lowerIsBetter bool
+}
+
+func New(options ...Option) (*Leaderboard, error) {
+	lb := &Leaderboard{players: map[string]scoreKey{}}
+	for k := range options {
+		if err := options[k](lb); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if lb.sl, err = skiplist.New(skiplist.CompareFunc(lb.compareKey), skiplist.WithAllowTheSameKey(false)); err != nil {
+		return nil, err
+	}
+	if lb.scores, err = skiplist.New(skiplist.CompareFunc(func(a, b interface{}) int {
+		return lb.compareScore(a.(float64), b.(float64))
+	}), skiplist.WithAllowTheSameKey(false)); err != nil {
+		lb.sl.Close()
+		return nil, err
+	}
+	return lb, nil
+}
+
+// 关闭排行榜  结束两个跳表的层数生成协程，不再使用时需要调用，关闭后仍可读写
+func (lb *Leaderboard) Close() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.sl.Close()
+	lb.scores.Close()
+}
+
+// 比较分数  好的在前
+func (lb *Leaderboard) compareScore(a, b float64) int {
+	if lb.lowerIsBetter {
+		return cmp.Compare(a, b)
+	}
+	return cmp.Compare(b, a)
+}
+
+func (lb *Leaderboard) compareKey(a, b interface{}) int {
+	ka, kb := a.(scoreKey), b.(scoreKey)
+	if c := lb.compareScore(ka.score, kb.score); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(ka.seq, kb.seq); c != 0 {
+		return c
+	}
+	return cmp.Compare(ka.player, kb.player)
+}
+
+/*
+提交分数
+按 SubmitPolicy 决定是否更新，返回是否更新了玩家的分数
+*/
+func (lb *Leaderboard) SubmitScore(player string, score float64) bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	old, exists := lb.players[player]
+	if exists {
+		if lb.policy == SubmitBest && lb.compareScore(score, old.score) >= 0 {
+			return false
+		}
+		if lb.policy == SubmitLatest && score == old.score {
+			return false
+		}
+		lb.remove(old)
+	}
+	lb.seq++
+	key := scoreKey{score: score, seq: lb.seq, player: player}
+	lb.sl.Insert(key, key)
+	lb.players[player] = key
+	if count, ok := lb.scores.GetRandByKey(score).(*int); ok {
+		*count++
+	} else {
+		n := 1
+		lb.scores.Insert(score, &n)
+	}
+	return true
+}
+
+// 移除玩家
+func (lb *Leaderboard) Remove(player string) bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	key, ok := lb.players[player]
+	if !ok {
+		return false
+	}
+	lb.remove(key)
+	delete(lb.players, player)
+	return true
+}
+
+func (lb *Leaderboard) remove(key scoreKey) {
+	lb.sl.DeleteByKey(key)
+	if count := lb.scores.GetRandByKey(key.score).(*int); *count > 1 {
+		*count--
+	} else {
+		lb.scores.DeleteByKey(key.score)
+	}
+}
+
+// 玩家数量
+func (lb *Leaderboard) Len() int {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.sl.GetLength()
+}
+
+// 玩家的分数
+func (lb *Leaderboard) Score(player string) (float64, bool) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	key, ok := lb.players[player]
+	return key.score, ok
+}
+
+// 玩家的排名
+func (lb *Leaderboard) GetRank(player string) (Entry, error) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	key, ok := lb.players[player]
+	if !ok {
+		return Entry{}, playerNotFoundErr
+	}
+	_, position := lb.sl.GetRandWithRankByKey(key)
+	return Entry{Player: player, Score: key.score, Rank: lb.rankOf(key.score, position)}, nil
+}
+
+// 前 n 名
+func (lb *Leaderboard) Top(n int) []Entry {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.entries(1, n)
+}
+
+// 玩家及其前后各 n 名
+func (lb *Leaderboard) Around(player string, n int) ([]Entry, error) {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	key, ok := lb.players[player]
+	if !ok {
+		return nil, playerNotFoundErr
+	}
+	_, position := lb.sl.GetRandWithRankByKey(key)
+	start := position - n
+	if start < 1 {
+		start = 1
+	}
+	return lb.entries(start, position+n), nil
+}
+
+// 按排位区间 [start, end] 获取记录并计算排名
+func (lb *Leaderboard) entries(start, end int) []Entry {
+	data := lb.sl.GetByRankRange(start, end)
+	list := make([]Entry, len(data))
+	for k := range data {
+		key := data[k].(scoreKey)
+		list[k] = Entry{Player: key.player, Score: key.score}
+		switch {
+		case k == 0:
+			list[k].Rank = lb.rankOf(key.score, start)
+		case key.score == list[k-1].Score && lb.mode != RankOrdinal:
+			list[k].Rank = list[k-1].Rank
+		case lb.mode == RankDense:
+			list[k].Rank = list[k-1].Rank + 1
+		default:
+			list[k].Rank = start + k
+		}
+	}
+	return list
+}
+
+// 按 RankMode 计算排名  position 为在跳表中的排位
+func (lb *Leaderboard) rankOf(score float64, position int) int {
+	switch lb.mode {
+	case RankCompetition:
+		//该分数第一个玩家的排位
+		_, rank := lb.sl.GetCeilingWithRankByKey(scoreKey{score: score})
+		return rank
+	case RankDense:
+		_, rank := lb.scores.GetRandWithRankByKey(score)
+		return rank
+	}
+	return position
+}
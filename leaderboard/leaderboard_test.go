@@ -0,0 +1,148 @@
+package leaderboard
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func ranks(list []Entry) string {
+	s := ""
+	for _, e := range list {
+		s += fmt.Sprintf("%s:%d ", e.Player, e.Rank)
+	}
+	return s
+}
+
+// TestRankMode 测试同分时的三种排名方式
+func TestRankMode(t *testing.T) {
+	cases := []struct {
+		mode RankMode
+		want string
+	}{
+		{RankOrdinal, "a:1 b:2 c:3 d:4 e:5 "},
+		{RankCompetition, "a:1 b:2 c:2 d:4 e:5 "},
+		{RankDense, "a:1 b:2 c:2 d:3 e:4 "},
+	}
+	for _, c := range cases {
+		lb, _ := New(WithRankMode(c.mode))
+		lb.SubmitScore("a", 100)
+		lb.SubmitScore("b", 90)
+		lb.SubmitScore("c", 90)
+		lb.SubmitScore("d", 80)
+		lb.SubmitScore("e", 70)
+		if got := ranks(lb.Top(10)); got != c.want {
+			t.Fatalf("mode %d: Top = %s want %s", c.mode, got, c.want)
+		}
+		//从中间开始的区间也要得到相同的排名
+		around, _ := lb.Around("d", 1)
+		if got, want := ranks(around), c.want[8:20]; got != want {
+			t.Fatalf("mode %d: Around = %s want %s", c.mode, got, want)
+		}
+		for _, e := range lb.Top(10) {
+			if r, _ := lb.GetRank(e.Player); r.Rank != e.Rank {
+				t.Fatalf("mode %d: GetRank(%s) = %d want %d", c.mode, e.Player, r.Rank, e.Rank)
+			}
+		}
+	}
+}
+
+// TestSubmitPolicy 测试保留最好成绩与最近成绩
+func TestSubmitPolicy(t *testing.T) {
+	best, _ := New()
+	best.SubmitScore("a", 10)
+	if best.SubmitScore("a", 5) || !best.SubmitScore("a", 20) {
+		t.Fatal("最好成绩策略错误")
+	}
+	if score, _ := best.Score("a"); score != 20 {
+		t.Fatalf("score = %v", score)
+	}
+
+	latest, _ := New(WithSubmitPolicy(SubmitLatest))
+	latest.SubmitScore("a", 10)
+	latest.SubmitScore("b", 8)
+	if !latest.SubmitScore("a", 5) {
+		t.Fatal("最近成绩策略应更新")
+	}
+	if top := latest.Top(2); top[0].Player != "b" || top[1].Score != 5 || latest.Len() != 2 {
+		t.Fatalf("Top = %v", top)
+	}
+
+	low, _ := New(WithLowerIsBetter(), WithRankMode(RankDense))
+	low.SubmitScore("a", 30)
+	low.SubmitScore("b", 10)
+	low.SubmitScore("a", 20)
+	if got := ranks(low.Top(5)); got != "b:1 a:2 " {
+		t.Fatalf("分数越低越好 Top = %s", got)
+	}
+	if !low.Remove("b") || low.Remove("b") {
+		t.Fatal("Remove 结果错误")
+	}
+	if r, _ := low.GetRank("a"); r.Rank != 1 {
+		t.Fatalf("移除后排名 = %d", r.Rank)
+	}
+	if _, err := low.GetRank("b"); err != playerNotFoundErr {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// TestPage 分页过程中分数变化不会导致未变化的玩家重复或遗漏
+func TestPage(t *testing.T) {
+	lb, _ := New()
+	for i := 0; i < 100; i++ {
+		lb.SubmitScore(fmt.Sprintf("p%03d", i), float64(i))
+	}
+	seen := map[string]int{}
+	cursor := ""
+	for page := 0; ; page++ {
+		list, next, err := lb.Page(cursor, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range list {
+			seen[e.Player]++
+		}
+		if page == 2 {
+			//已翻过的玩家分数上升到榜首，后面的玩家排位整体后移
+			lb.SubmitScore("p095", 1000)
+			lb.SubmitScore("p090", 1001)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	for i := 0; i < 100; i++ {
+		if player := fmt.Sprintf("p%03d", i); seen[player] != 1 {
+			t.Fatalf("%s 出现 %d 次", player, seen[player])
+		}
+	}
+	if _, _, err := lb.Page("bad cursor", 10); err != cursorErr {
+		t.Fatalf("err = %v", err)
+	}
+	if list, next, _ := lb.Page("", 200); len(list) != 100 || next != "" {
+		t.Fatalf("一页取完时不应返回游标: %d %q", len(list), next)
+	}
+}
+
+// TestClose 测试关闭后协程退出且仍可读写
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	list := []*Leaderboard{}
+	for i := 0; i < 20; i++ {
+		lb, _ := New()
+		lb.SubmitScore("a", float64(i))
+		list = append(list, lb)
+	}
+	for _, lb := range list {
+		lb.Close()
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	lb := list[0]
+	lb.SubmitScore("b", 10)
+	if got := ranks(lb.Top(2)); got != "b:1 a:2 " {
+		t.Fatalf("关闭后读写错误 %s", got)
+	}
+}
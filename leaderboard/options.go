@@ -0,0 +1,50 @@
+package leaderboard
+
+// 提交分数的策略
+type SubmitPolicy int
+
+const (
+	SubmitBest   SubmitPolicy = iota //保留最好成绩
+	SubmitLatest                     //保留最近一次成绩
+)
+
+// 同分时的排名方式
+type RankMode int
+
+const (
+	RankOrdinal     RankMode = iota //顺序排名 1234，同分按先达到该分数的在前
+	RankCompetition                 //竞赛排名 1224
+	RankDense                       //密集排名 1223
+)
+
+type Option func(*Leaderboard) error
+
+// 设置提交分数的策略，默认保留最好成绩
+func WithSubmitPolicy(policy SubmitPolicy) Option {
+	return func(lb *Leaderboard) error {
+		if policy != SubmitBest && policy != SubmitLatest {
+			return submitPolicyErr
+		}
+		lb.policy = policy
+		return nil
+	}
+}
+
+// 设置同分时的排名方式，默认顺序排名
+func WithRankMode(mode RankMode) Option {
+	return func(lb *Leaderboard) error {
+		if mode < RankOrdinal || mode > RankDense {
+			return rankModeErr
+		}
+		lb.mode = mode
+		return nil
+	}
+}
+
+// 设置分数越低越好 (如竞速用时)，默认分数越高越好
+func WithLowerIsBetter() Option {
+	return func(lb *Leaderboard) error {
+		lb.lowerIsBetter = true
+		return nil
+	}
+}
@@ -0,0 +1,14 @@
+package memtable
+
+import "errors"
+
+var (
+	immutableErr    = errors.New("memtable is immutable")
+	mutableErr      = errors.New("memtable must be frozen before flush")
+	duplicateSeqErr = errors.New("the same key and sequence number already exists")
+	seqRangeErr     = errors.New("sequence number is grater than MaxSeq")
+	keyOrderErr     = errors.New("entries must be added in internal key order")
+	writerClosedErr = errors.New("table writer is already finished")
+	corruptErr      = errors.New("table file is corrupted")
+	checksumErr     = errors.New("table block checksum mismatch")
+)
@@ -0,0 +1,60 @@
+package memtable
+
+import "bytes"
+
+/*
+	internal key = (user key, sequence number, kind)
+	排序: user key 升序 -> seq 降序 -> kind 降序
+	同一个 user key 的新版本排在前面，按快照读时定位到第一个 seq <= snapshot 的版本即可
+*/
+
+// 记录类型
+type Kind uint8
+
+const (
+	KindDelete Kind = 0 //删除标记
+	KindPut    Kind = 1 //写入
+
+	kindSeek = KindPut //查找时使用的类型，保证定位到同 seq 的所有类型之前
+)
+
+// 允许写入的最大序列号  落盘时 seq 与 kind 合并为一个 uint64，高 56 位为 seq
+const MaxSeq uint64 = 1<<56 - 1
+
+// 内部key
+type InternalKey struct {
+	UserKey []byte //用户key
+	Seq     uint64 //序列号  写入时不能大于 MaxSeq
+	Kind    Kind   //记录类型
+}
+
+// 一条记录
+type Entry struct {
+	Key   InternalKey
+	Value []byte //删除标记时为nil
+}
+
+// 比较内部key   -1 a<b  0 a==b  1 a>b
+func compareInternalKey(a, b InternalKey) int {
+	if c := bytes.Compare(a.UserKey, b.UserKey); c != 0 {
+		return c
+	}
+	if a.Seq > b.Seq {
+		return -1
+	} else if a.Seq < b.Seq {
+		return 1
+	}
+	if a.Kind > b.Kind {
+		return -1
+	} else if a.Kind < b.Kind {
+		return 1
+	}
+	return 0
+}
+
+// 内部key比较接口实现, 用于 skiplist.New
+type internalKeyCmp struct{}
+
+func (*internalKeyCmp) Compare(a, b interface{}) int {
+	return compareInternalKey(a.(InternalKey), b.(InternalKey))
+}
@@ -0,0 +1,179 @@
+package memtable
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+// 每条记录除 key/value 外的近似开销 (seq + kind + 结点指针等)
+const entryOverhead = 32
+
+// 内存表  以 skiplist.SkipList 存储内部key，冻结后可刷写为有序的表文件
+type MemTable struct {
+	mu        sync.RWMutex
+	sl        *skiplist.SkipList
+	size      int  //近似占用字节数
+	immutable bool //是否已冻结
+}
+
+func New() (*MemTable, error) {
+	sl, err := skiplist.New(&internalKeyCmp{}, skiplist.WithAllowTheSameKey(false))
+	if err != nil {
+		return nil, err
+	}
+	return &MemTable{sl: sl}, nil
+}
+
+// 写入 key/value, seq 由调用侧保证单调递增且不大于 MaxSeq
+func (m *MemTable) Put(seq uint64, key, value []byte) error {
+	return m.add(InternalKey{UserKey: key, Seq: seq, Kind: KindPut}, value)
+}
+
+// 写入删除标记
+func (m *MemTable) Delete(seq uint64, key []byte) error {
+	return m.add(InternalKey{UserKey: key, Seq: seq, Kind: KindDelete}, nil)
+}
+
+func (m *MemTable) add(ikey InternalKey, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.immutable {
+		return immutableErr
+	}
+	if ikey.Seq > MaxSeq {
+		return seqRangeErr
+	}
+	//拷贝一份，避免调用侧复用切片
+	ikey.UserKey = append([]byte(nil), ikey.UserKey...)
+	if value != nil {
+		value = append([]byte{}, value...)
+	}
+	entry := Entry{Key: ikey, Value: value}
+	if _, ok := m.sl.Insert(ikey, entry); !ok {
+		return duplicateSeqErr
+	}
+	m.size += len(ikey.UserKey) + len(value) + entryOverhead
+	return nil
+}
+
+/*
+按快照读取
+返回 seq <= snapshot 的最新版本，found 为 false 表示内存表中没有该key的可见版本。
+返回的记录类型为 KindDelete 时表示已删除，调用侧不应再去更旧的表中查找
+*/
+func (m *MemTable) Get(key []byte, snapshot uint64) (entry Entry, found bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, _ := m.sl.GetCeilingWithRankByKey(InternalKey{UserKey: key, Seq: snapshot, Kind: kindSeek})
+	if data == nil {
+		return Entry{}, false
+	}
+	entry = data.(Entry)
+	if string(entry.Key.UserKey) != string(key) {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// 冻结内存表，冻结后不再接受写入
+func (m *MemTable) Freeze() {
+	m.mu.Lock()
+	m.immutable = true
+	m.mu.Unlock()
+}
+
+// 关闭内存表  冻结并结束跳表的层数生成协程，不再使用时 (通常在 Flush 之后) 需要调用，关闭后仍可读取
+func (m *MemTable) Close() {
+	m.mu.Lock()
+	m.immutable = true
+	m.mu.Unlock()
+	m.sl.Close()
+}
+
+// 是否已冻结
+func (m *MemTable) Immutable() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.immutable
+}
+
+// 记录条数 (包含所有版本及删除标记)
+func (m *MemTable) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sl.GetLength()
+}
+
+// 近似占用字节数，可用于判断何时切换内存表
+func (m *MemTable) ApproximateSize() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.size
+}
+
+// 按内部key顺序遍历所有记录, fn 返回 false 时停止
+func (m *MemTable) Range(fn func(entry Entry) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	it := skiplist.NewIterator(m.sl)
+	for it.Next(0); it.Valid(); it.Next(0) {
+		if !fn(it.Data().(Entry)) {
+			return
+		}
+	}
+}
+
+/*
+将冻结的内存表刷写为表文件
+先写入临时文件并 fsync，再重命名为 path，避免留下写了一半的表文件
+*/
+func (m *MemTable) Flush(path string, blockSize int) (err error) {
+	if !m.Immutable() {
+		return mutableErr
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	w := NewTableWriter(f, blockSize)
+	m.Range(func(entry Entry) bool {
+		err = w.Add(entry)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = w.Finish(); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// 同步目录，保证重命名落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,187 @@
+package memtable
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSnapshotGet 测试按快照读取
+func TestSnapshotGet(t *testing.T) {
+	m, _ := New()
+	defer m.Close()
+	m.Put(1, []byte("a"), []byte("a1"))
+	m.Put(2, []byte("b"), []byte("b2"))
+	m.Put(3, []byte("a"), []byte("a3"))
+	m.Delete(4, []byte("a"))
+
+	cases := []struct {
+		key      string
+		snapshot uint64
+		found    bool
+		kind     Kind
+		value    string
+	}{
+		{"a", 0, false, 0, ""},
+		{"a", 1, true, KindPut, "a1"},
+		{"a", 2, true, KindPut, "a1"},
+		{"a", 3, true, KindPut, "a3"},
+		{"a", 10, true, KindDelete, ""},
+		{"b", 1, false, 0, ""},
+		{"b", 2, true, KindPut, "b2"},
+		{"c", 10, false, 0, ""},
+		{"", 10, false, 0, ""},
+	}
+	for _, c := range cases {
+		entry, found := m.Get([]byte(c.key), c.snapshot)
+		if found != c.found {
+			t.Fatalf("Get(%q, %d) found = %v, want %v", c.key, c.snapshot, found, c.found)
+		}
+		if found && (entry.Key.Kind != c.kind || string(entry.Value) != c.value) {
+			t.Fatalf("Get(%q, %d) = %v %q, want %v %q", c.key, c.snapshot, entry.Key.Kind, entry.Value, c.kind, c.value)
+		}
+	}
+}
+
+// TestDuplicateAndFreeze 测试重复seq及冻结后写入
+func TestDuplicateAndFreeze(t *testing.T) {
+	m, _ := New()
+	defer m.Close()
+	if err := m.Put(1, []byte("a"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put(1, []byte("a"), []byte("2")); err != duplicateSeqErr {
+		t.Fatalf("重复的 key+seq 应失败, got: %v", err)
+	}
+	if err := m.Put(MaxSeq+1, []byte("a"), []byte("3")); err != seqRangeErr {
+		t.Fatalf("seq 超过 MaxSeq 应失败, got: %v", err)
+	}
+	if err := m.Put(MaxSeq, []byte("a"), []byte("3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewTableWriter(io.Discard, 0).Add(Entry{Key: InternalKey{UserKey: []byte("a"), Seq: MaxSeq + 1, Kind: KindPut}}); err != seqRangeErr {
+		t.Fatalf("写表时 seq 超过 MaxSeq 应失败, got: %v", err)
+	}
+	if err := m.Flush(filepath.Join(t.TempDir(), "t.sst"), 0); err != mutableErr {
+		t.Fatalf("未冻结时刷写应失败, got: %v", err)
+	}
+	m.Freeze()
+	if err := m.Put(2, []byte("b"), []byte("2")); err != immutableErr {
+		t.Fatalf("冻结后写入应失败, got: %v", err)
+	}
+}
+
+// TestFlushAndRead 测试刷写表文件后读取与遍历
+func TestFlushAndRead(t *testing.T) {
+	m, _ := New()
+	defer m.Close()
+	var seq uint64
+	for i := 0; i < 500; i++ {
+		seq++
+		m.Put(seq, []byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("v%d", i)))
+	}
+	snapshot := seq
+	for i := 0; i < 500; i += 3 {
+		seq++
+		m.Put(seq, []byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("new%d", i)))
+	}
+	for i := 1; i < 500; i += 7 {
+		seq++
+		m.Delete(seq, []byte(fmt.Sprintf("key%04d", i)))
+	}
+	m.Freeze()
+
+	path := filepath.Join(t.TempDir(), "000001.sst")
+	if err := m.Flush(path, 256); err != nil {
+		t.Fatal(err)
+	}
+	//关闭后仍可读取，用于与表文件对比
+	m.Close()
+	table, err := OpenTable(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if len(table.index) < 2 {
+		t.Fatalf("应生成多个数据块, got: %d", len(table.index))
+	}
+
+	//表文件与内存表的读取结果一致
+	for i := 0; i < 510; i++ {
+		key := []byte(fmt.Sprintf("key%04d", i))
+		for _, snap := range []uint64{0, snapshot, seq} {
+			want, wantFound := m.Get(key, snap)
+			got, found, err := table.Get(key, snap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != wantFound || got.Key.Seq != want.Key.Seq || got.Key.Kind != want.Key.Kind || string(got.Value) != string(want.Value) {
+				t.Fatalf("Get(%s, %d) = %v %v, want %v %v", key, snap, got, found, want, wantFound)
+			}
+		}
+	}
+
+	//遍历顺序与内存表一致
+	it := table.NewIterator()
+	n := 0
+	m.Range(func(want Entry) bool {
+		if !it.Next() {
+			t.Fatalf("迭代器提前结束: %v", it.Err())
+		}
+		if compareInternalKey(it.Entry().Key, want.Key) != 0 {
+			t.Fatalf("第 %d 条记录 = %v, want %v", n, it.Entry().Key, want.Key)
+		}
+		n++
+		return true
+	})
+	if it.Next() || n != m.Len() {
+		t.Fatalf("遍历条数不一致 %d != %d", n, m.Len())
+	}
+
+	//Seek 后顺序遍历
+	it = table.NewIterator()
+	it.Seek([]byte("key0250"), snapshot)
+	if !it.Next() || string(it.Entry().Key.UserKey) != "key0250" || string(it.Entry().Value) != "v250" {
+		t.Fatalf("Seek 结果错误: %v", it.Entry())
+	}
+}
+
+// TestCorruptTable 长度及偏移字段接近 2^64 时返回 corruptErr，不会因相加溢出而越界
+func TestCorruptTable(t *testing.T) {
+	data := binary.AppendUvarint(nil, math.MaxUint64-3)
+	data = append(data, make([]byte, 10)...)
+	if _, _, err := decodeKey(data); !errors.Is(err, corruptErr) {
+		t.Fatalf("want corruptErr, got %v", err)
+	}
+
+	m, _ := New()
+	defer m.Close()
+	for i := 0; i < 100; i++ {
+		m.Put(uint64(i+1), []byte(fmt.Sprintf("key%04d", i)), []byte("v"))
+	}
+	m.Freeze()
+	path := filepath.Join(t.TempDir(), "000001.sst")
+	if err := m.Flush(path, 256); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//offset+size+crcSize+footerSize 溢出后恰好等于文件大小
+	footer := file[len(file)-footerSize:]
+	offset := uint64(math.MaxUint64 - 100)
+	binary.LittleEndian.PutUint64(footer[0:], offset)
+	binary.LittleEndian.PutUint64(footer[8:], uint64(len(file))-offset-crcSize-footerSize)
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenTable(path); !errors.Is(err, corruptErr) {
+		t.Fatalf("want corruptErr, got %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package memtable
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+)
+
+// 索引项
+type blockHandle struct {
+	lastKey InternalKey //块内最大的key
+	offset  uint64
+	size    uint64
+}
+
+// 表文件读取器
+type Table struct {
+	f     *os.File
+	index []blockHandle
+}
+
+// 打开表文件并加载索引
+func OpenTable(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{f: f}
+	if err = t.loadIndex(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Table) loadIndex() error {
+	stat, err := t.f.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() < footerSize {
+		return corruptErr
+	}
+	footer := make([]byte, footerSize)
+	if _, err = t.f.ReadAt(footer, stat.Size()-footerSize); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint64(footer[16:]) != tableMagic {
+		return corruptErr
+	}
+	offset := binary.LittleEndian.Uint64(footer[0:])
+	size := binary.LittleEndian.Uint64(footer[8:])
+	//逐项与文件大小比较，避免相加溢出
+	fileSize := uint64(stat.Size())
+	if offset > fileSize || size > fileSize-offset || fileSize-offset-size != crcSize+footerSize {
+		return corruptErr
+	}
+	data, err := t.readBlock(offset, size)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		var h blockHandle
+		var n int
+		if h.lastKey, data, err = decodeKey(data); err != nil {
+			return err
+		}
+		if h.offset, n = binary.Uvarint(data); n <= 0 {
+			return corruptErr
+		}
+		data = data[n:]
+		if h.size, n = binary.Uvarint(data); n <= 0 {
+			return corruptErr
+		}
+		data = data[n:]
+		//数据块需要位于索引块之前
+		if h.offset > offset || h.size > offset-h.offset || offset-h.offset-h.size < crcSize {
+			return corruptErr
+		}
+		t.index = append(t.index, h)
+	}
+	return nil
+}
+
+// 读取块并校验crc
+func (t *Table) readBlock(offset, size uint64) ([]byte, error) {
+	buf := make([]byte, size+crcSize)
+	if _, err := t.f.ReadAt(buf, int64(offset)); err != nil {
+		if err == io.EOF {
+			return nil, corruptErr
+		}
+		return nil, err
+	}
+	data := buf[:size]
+	if crc32.ChecksumIEEE(data) != binary.LittleEndian.Uint32(buf[size:]) {
+		return nil, checksumErr
+	}
+	return data, nil
+}
+
+// 关闭文件
+func (t *Table) Close() error {
+	return t.f.Close()
+}
+
+/*
+按快照读取，语义与 MemTable.Get 相同
+先在索引中二分找到第一个最大key不小于查找key的数据块，再在块内顺序查找
+*/
+func (t *Table) Get(key []byte, snapshot uint64) (Entry, bool, error) {
+	it := t.NewIterator()
+	it.Seek(key, snapshot)
+	if !it.Next() {
+		return Entry{}, false, it.Err()
+	}
+	entry := it.Entry()
+	if string(entry.Key.UserKey) != string(key) {
+		return Entry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+// 表文件迭代器，按内部key升序输出所有记录
+type TableIterator struct {
+	t     *Table
+	block int    //下一个要加载的块
+	data  []byte //当前块剩余未读的数据
+	seek  *InternalKey
+	entry Entry
+	err   error
+}
+
+// 创建迭代器，默认从第一条记录开始
+func (t *Table) NewIterator() *TableIterator {
+	return &TableIterator{t: t}
+}
+
+// 定位到第一个 >= (key, snapshot) 的记录, 之后调用 Next 获取
+func (it *TableIterator) Seek(key []byte, snapshot uint64) {
+	target := InternalKey{UserKey: key, Seq: snapshot, Kind: kindSeek}
+	it.block = sort.Search(len(it.t.index), func(i int) bool {
+		return compareInternalKey(it.t.index[i].lastKey, target) >= 0
+	})
+	it.data = nil
+	it.err = nil
+	it.seek = &target
+}
+
+// 移动到下一条记录，没有更多记录或出错时返回 false
+func (it *TableIterator) Next() bool {
+	for it.err == nil {
+		if len(it.data) == 0 {
+			if it.block >= len(it.t.index) {
+				return false
+			}
+			h := it.t.index[it.block]
+			it.block++
+			if it.data, it.err = it.t.readBlock(h.offset, h.size); it.err != nil {
+				return false
+			}
+			continue
+		}
+		var entry Entry
+		if entry, it.data, it.err = decodeEntry(it.data); it.err != nil {
+			return false
+		}
+		if it.seek != nil {
+			if compareInternalKey(entry.Key, *it.seek) < 0 {
+				continue
+			}
+			it.seek = nil
+		}
+		it.entry = entry
+		return true
+	}
+	return false
+}
+
+// 当前记录
+func (it *TableIterator) Entry() Entry {
+	return it.entry
+}
+
+// 迭代过程中的错误
+func (it *TableIterator) Err() error {
+	return it.err
+}
+
+// 解码内部key
+func decodeKey(data []byte) (InternalKey, []byte, error) {
+	l, n := binary.Uvarint(data)
+	if n <= 0 || l > uint64(len(data)-n) || uint64(len(data)-n)-l < 8 {
+		return InternalKey{}, nil, corruptErr
+	}
+	data = data[n:]
+	key := InternalKey{UserKey: data[:l:l]}
+	trailer := binary.LittleEndian.Uint64(data[l:])
+	key.Seq = trailer >> 8
+	key.Kind = Kind(trailer & 0xff)
+	return key, data[l+8:], nil
+}
+
+// 解码一条记录
+func decodeEntry(data []byte) (Entry, []byte, error) {
+	key, data, err := decodeKey(data)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	l, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < l {
+		return Entry{}, nil, corruptErr
+	}
+	data = data[n:]
+	entry := Entry{Key: key}
+	if key.Kind == KindPut {
+		entry.Value = data[:l:l]
+	}
+	return entry, data[l:], nil
+}
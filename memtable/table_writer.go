@@ -0,0 +1,139 @@
+package memtable
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+/*
+表文件格式
+	[data block 1][crc]...[data block n][crc][index block][crc][footer]
+
+	data block : 若干条记录  uvarint(len(userKey)) userKey | uint64(seq<<8|kind) | uvarint(len(value)) value
+	index block: 每个数据块一条  uvarint(len(lastUserKey)) lastUserKey | uint64(seq<<8|kind) | uvarint(offset) | uvarint(size)
+	crc        : 4 字节 crc32(IEEE)，校验其前面的块内容
+	footer     : uint64(index offset) | uint64(index size) | uint64(magic)  固定 24 字节
+*/
+
+const (
+	defaultBlockSize = 4 << 10 //默认数据块大小
+	tableMagic       = 0x6473736b69706c73
+	footerSize       = 24
+	crcSize          = 4
+)
+
+// 表文件写入器，记录需按内部key升序添加
+type TableWriter struct {
+	w        io.Writer
+	offset   uint64      //已写入的字节数
+	block    []byte      //当前数据块
+	index    []byte      //索引块
+	lastKey  InternalKey //上一条记录的key
+	count    int         //已写入的记录数
+	size     int         //数据块大小阈值
+	finished bool
+	err      error
+}
+
+func NewTableWriter(w io.Writer, blockSize int) *TableWriter {
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+	return &TableWriter{
+		w:    w,
+		size: blockSize,
+	}
+}
+
+// 添加一条记录
+func (tw *TableWriter) Add(entry Entry) error {
+	if tw.err != nil {
+		return tw.err
+	}
+	if tw.finished {
+		return writerClosedErr
+	}
+	if entry.Key.Seq > MaxSeq {
+		return seqRangeErr
+	}
+	if tw.count > 0 && compareInternalKey(tw.lastKey, entry.Key) >= 0 {
+		return keyOrderErr
+	}
+	tw.block = appendEntry(tw.block, entry)
+	tw.lastKey = entry.Key
+	tw.count++
+	if len(tw.block) >= tw.size {
+		return tw.flushBlock()
+	}
+	return nil
+}
+
+// 写入剩余数据块、索引块及footer
+func (tw *TableWriter) Finish() error {
+	if tw.err != nil {
+		return tw.err
+	}
+	if tw.finished {
+		return writerClosedErr
+	}
+	tw.finished = true
+	if len(tw.block) > 0 {
+		if err := tw.flushBlock(); err != nil {
+			return err
+		}
+	}
+	indexOffset := tw.offset
+	if err := tw.writeBlock(tw.index); err != nil {
+		return err
+	}
+	footer := make([]byte, footerSize)
+	binary.LittleEndian.PutUint64(footer[0:], indexOffset)
+	binary.LittleEndian.PutUint64(footer[8:], uint64(len(tw.index)))
+	binary.LittleEndian.PutUint64(footer[16:], tableMagic)
+	return tw.write(footer)
+}
+
+// 写出当前数据块并记录索引
+func (tw *TableWriter) flushBlock() error {
+	offset := tw.offset
+	if err := tw.writeBlock(tw.block); err != nil {
+		return err
+	}
+	tw.index = appendKey(tw.index, tw.lastKey)
+	tw.index = binary.AppendUvarint(tw.index, offset)
+	tw.index = binary.AppendUvarint(tw.index, uint64(len(tw.block)))
+	tw.block = tw.block[:0]
+	return nil
+}
+
+// 写出块及其crc
+func (tw *TableWriter) writeBlock(block []byte) error {
+	if err := tw.write(block); err != nil {
+		return err
+	}
+	return tw.write(binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(block)))
+}
+
+func (tw *TableWriter) write(p []byte) error {
+	n, err := tw.w.Write(p)
+	tw.offset += uint64(n)
+	if err != nil {
+		tw.err = err
+	}
+	return err
+}
+
+// 编码内部key
+func appendKey(dst []byte, key InternalKey) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(key.UserKey)))
+	dst = append(dst, key.UserKey...)
+	return binary.LittleEndian.AppendUint64(dst, key.Seq<<8|uint64(key.Kind))
+}
+
+// 编码一条记录
+func appendEntry(dst []byte, entry Entry) []byte {
+	dst = appendKey(dst, entry.Key)
+	dst = binary.AppendUvarint(dst, uint64(len(entry.Value)))
+	return append(dst, entry.Value...)
+}
@@ -0,0 +1,16 @@
+package mmapskiplist
+
+import "errors"
+
+var (
+	closedErr      = errors.New("skiplist file is closed")
+	magicErr       = errors.New("not a mmap skiplist file")
+	versionErr     = errors.New("unsupported mmap skiplist file version")
+	headerErr      = errors.New("mmap skiplist header checksum mismatch")
+	corruptErr     = errors.New("mmap skiplist file is corrupted")
+	journalErr     = errors.New("mmap skiplist journal overflow")
+	levelErr       = errors.New("level must grater than 1 and not grater than 64")
+	probabilityErr = errors.New("probability must grater than 0 and less than 1")
+	compareErr     = errors.New("compare func is nil")
+	unsupportedErr = errors.New("mmap is not supported on this platform")
+)
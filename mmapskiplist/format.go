@@ -0,0 +1,128 @@
+package mmapskiplist
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+/*
+文件格式 (小端序)
+	[0, 4096)          文件头: magic[8] | uint32(version) | uint32(maxLevel) | uint64(length) | uint64(level)
+	                           | uint64(dataEnd) | uint64(tail) | uint32(crc32 of 前48字节)
+	[4096, 12288)      日志区: uint32(写入数) | uint32(crc32) | 文件头镜像[48] | 写入数 × (uint64(offset) | uint64(value))
+	[12288, ...)       头结点，之后为按追加顺序存放的结点及溢出区数据
+
+	结点: uint32(height) | uint32(keyLen) | uint64(valLen) | uint64(valOff) | uint64(prev)
+	      | height × (uint64(next) | uint64(span)) | key
+	      数据存放在 valOff 处，插入时紧跟在key之后，更新时追加到文件末尾的溢出区
+	指针均为文件内偏移，0表示空
+
+修改已有结点的写入先完整写入日志区，再作用到结点并更新文件头，最后清空日志区; 新结点及溢出数据写在 dataEnd 之后，
+提交前不会被引用。打开时日志区有效则重放，从而保证崩溃后结构完整
+*/
+
+const (
+	magic         = "DSMMAPSL"
+	version       = 1
+	headerSize    = 4096
+	headerLen     = 48 //文件头有效字节数 (不含crc)
+	journalOffset = headerSize
+	journalSize   = 8192
+	headOffset    = journalOffset + journalSize
+	nodeFixedSize = 32 //结点固定部分大小
+	levelSize     = 16 //每层大小
+	initialSize   = 1 << 20
+)
+
+// 结点字段偏移
+const (
+	fieldHeight = 0
+	fieldKeyLen = 4
+	fieldValLen = 8
+	fieldValOff = 16
+	fieldPrev   = 24
+)
+
+// 文件头
+type header struct {
+	maxLevel uint32
+	length   uint64
+	level    uint64 //当前最大层数 (从0开始)
+	dataEnd  uint64 //已使用空间的末尾
+	tail     uint64 //尾结点
+}
+
+func (h *header) marshal(b []byte) {
+	copy(b[0:8], magic)
+	binary.LittleEndian.PutUint32(b[8:], version)
+	binary.LittleEndian.PutUint32(b[12:], h.maxLevel)
+	binary.LittleEndian.PutUint64(b[16:], h.length)
+	binary.LittleEndian.PutUint64(b[24:], h.level)
+	binary.LittleEndian.PutUint64(b[32:], h.dataEnd)
+	binary.LittleEndian.PutUint64(b[40:], h.tail)
+	binary.LittleEndian.PutUint32(b[headerLen:], crc32.ChecksumIEEE(b[:headerLen]))
+}
+
+func (h *header) unmarshal(b []byte) error {
+	if string(b[0:8]) != magic {
+		return magicErr
+	}
+	if binary.LittleEndian.Uint32(b[8:]) != version {
+		return versionErr
+	}
+	if crc32.ChecksumIEEE(b[:headerLen]) != binary.LittleEndian.Uint32(b[headerLen:]) {
+		return headerErr
+	}
+	h.maxLevel = binary.LittleEndian.Uint32(b[12:])
+	h.length = binary.LittleEndian.Uint64(b[16:])
+	h.level = binary.LittleEndian.Uint64(b[24:])
+	h.dataEnd = binary.LittleEndian.Uint64(b[32:])
+	h.tail = binary.LittleEndian.Uint64(b[40:])
+	if h.maxLevel < 1 || h.maxLevel > maxMaxLevel || h.level >= uint64(h.maxLevel) || h.dataEnd < headOffset {
+		return corruptErr
+	}
+	return nil
+}
+
+// 一次对已有数据的写入
+type write struct {
+	off, val uint64
+}
+
+// 日志区最多容纳的写入数
+const maxJournalWrites = (journalSize - 8 - headerLen - 4) / 16
+
+// 编码日志区
+func marshalJournal(b []byte, h *header, writes []write) {
+	body := b[8:]
+	h.marshal(body)
+	p := body[headerLen+4:]
+	for k, w := range writes {
+		binary.LittleEndian.PutUint64(p[k*16:], w.off)
+		binary.LittleEndian.PutUint64(p[k*16+8:], w.val)
+	}
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(writes)))
+	binary.LittleEndian.PutUint32(b[4:], crc32.ChecksumIEEE(body[:headerLen+4+len(writes)*16]))
+}
+
+// 解码日志区  日志区为空或不完整时返回false
+func unmarshalJournal(b []byte) (*header, []write, bool) {
+	count := binary.LittleEndian.Uint32(b[0:])
+	if count == 0 || count > maxJournalWrites {
+		return nil, nil, false
+	}
+	body := b[8:]
+	if crc32.ChecksumIEEE(body[:headerLen+4+int(count)*16]) != binary.LittleEndian.Uint32(b[4:]) {
+		return nil, nil, false
+	}
+	h := &header{}
+	if h.unmarshal(body) != nil {
+		return nil, nil, false
+	}
+	writes := make([]write, count)
+	p := body[headerLen+4:]
+	for k := range writes {
+		writes[k] = write{off: binary.LittleEndian.Uint64(p[k*16:]), val: binary.LittleEndian.Uint64(p[k*16+8:])}
+	}
+	return h, writes, true
+}
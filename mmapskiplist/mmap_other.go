@@ -0,0 +1,13 @@
+//go:build !unix
+
+package mmapskiplist
+
+import "os"
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, unsupportedErr
+}
+
+func munmap(b []byte) error {
+	return unsupportedErr
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package mmapskiplist
+
+import (
+	"os"
+	"syscall"
+)
+
+// 以读写共享方式映射文件
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
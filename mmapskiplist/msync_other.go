@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || openbsd || dragonfly)
+
+package mmapskiplist
+
+// 没有 msync 系统调用的平台依赖 fsync 写回映射的内容
+func msync(b []byte) error {
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin || freebsd || openbsd || dragonfly
+
+package mmapskiplist
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// 将映射的内容同步写回文件
+func msync(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	_, _, e := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), syscall.MS_SYNC)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package mmapskiplist
+
+const (
+	defaultMaxLevel    = 32   //默认最大层数
+	maxMaxLevel        = 64   //最大层数上限，受日志区大小限制
+	defaultProbability = 0.25 //默认层数生成概率
+)
+
+type Option func(*SkipList) error
+
+// 设置最大层数  只在创建文件时生效，重新打开时使用文件中记录的层数
+func WithMaxLevel(level int) Option {
+	return func(sl *SkipList) error {
+		if level < 1 || level > maxMaxLevel {
+			return levelErr
+		}
+		sl.maxLevel = level
+		return nil
+	}
+}
+
+// 设置层数生成概率
+func WithProbability(probability float64) Option {
+	return func(sl *SkipList) error {
+		if probability <= 0 || probability >= 1 {
+			return probabilityErr
+		}
+		sl.probability = probability
+		return nil
+	}
+}
+
+// 设置key的比较函数，默认为 bytes.Compare  重新打开时需要使用相同的比较函数
+func WithCompare(compare func(a, b []byte) int) Option {
+	return func(sl *SkipList) error {
+		if compare == nil {
+			return compareErr
+		}
+		sl.compare = compare
+		return nil
+	}
+}
+
+// 设置允许相同的key  默认允许，为false时插入相同key会失败
+func WithAllowTheSameKey(allow bool) Option {
+	return func(sl *SkipList) error {
+		sl.allowSameKey = allow
+		return nil
+	}
+}
+
+// 设置每次写入后是否 fsync  默认开启; 关闭后进程崩溃仍可恢复，但掉电时可能丢失最近的写入
+func WithSync(sync bool) Option {
+	return func(sl *SkipList) error {
+		sl.sync = sync
+		return nil
+	}
+}
@@ -0,0 +1,702 @@
+package mmapskiplist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"time"
+)
+
+/*
+基于内存映射文件的跳表
+结点存放在映射到内存的文件中，以文件内偏移代替指针，数据量可以超过内存，由操作系统按需换入换出。
+key与数据为任意长度的字节切片，插入时与结点存放在一起，更新时数据追加到溢出区; 删除及更新不回收空间。
+提供 skiplist.SkipList 接口的一个子集: Insert、Get、GetWithRank、GetCeiling、GetByRank、GetByRankRange、Range、
+UpdateByKey、DeleteByKey、DeleteBatchByKey、DeleteByRank; 没有 UpdateByRank、UpdateBatchByKey、GetAllByKey、GetFirst、GetTail。
+返回的切片均为复制，不引用映射的内存。
+不是并发安全的
+*/
+type SkipList struct {
+	f            *os.File
+	data         []byte //映射的文件内容
+	hdr          header //已提交的文件头
+	cur          header //当前操作中的文件头，操作结束后与hdr一致
+	writes       []write
+	undo         []write //提交时被覆盖的原值，结点写入落盘失败时恢复
+	staged       map[uint64]int //已暂存写入的偏移 -> writes下标
+	compare      func(a, b []byte) int
+	maxLevel     int
+	probability  float64
+	allowSameKey bool
+	sync         bool
+	fsync        func() error //刷盘文件，测试中替换以模拟失败
+	rd           *rand.Rand
+	closed       bool
+}
+
+// 一条数据
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
+/*
+打开或创建文件
+文件不存在时按参数创建; 存在时校验文件头，日志区中有未完成的写入时先重放
+*/
+func Open(path string, options ...Option) (*SkipList, error) {
+	sl := &SkipList{
+		staged:       map[uint64]int{},
+		compare:      bytes.Compare,
+		maxLevel:     defaultMaxLevel,
+		probability:  defaultProbability,
+		allowSameKey: true,
+		sync:         true,
+		rd:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for k := range options {
+		if err := options[k](sl); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	sl.f = f
+	sl.fsync = f.Sync
+	if err = sl.load(); err != nil {
+		if sl.data != nil {
+			munmap(sl.data)
+		}
+		f.Close()
+		return nil, err
+	}
+	return sl, nil
+}
+
+// 加载或初始化文件
+func (sl *SkipList) load() error {
+	info, err := sl.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return sl.create()
+	}
+	if info.Size() < headOffset {
+		return corruptErr
+	}
+	if sl.data, err = mmap(sl.f, int(info.Size())); err != nil {
+		return err
+	}
+	if h, writes, ok := unmarshalJournal(sl.data[journalOffset:]); ok {
+		//上次提交在作用到结点的过程中中断，重放日志
+		if h.dataEnd > uint64(len(sl.data)) {
+			return corruptErr
+		}
+		sl.applyJournal(h, writes)
+		if err = sl.flush(); err != nil {
+			return err
+		}
+		sl.clearJournal()
+		if err = sl.flush(); err != nil {
+			return err
+		}
+	}
+	if err = sl.hdr.unmarshal(sl.data); err != nil {
+		return err
+	}
+	if sl.hdr.dataEnd > uint64(len(sl.data)) {
+		return corruptErr
+	}
+	sl.cur = sl.hdr
+	sl.maxLevel = int(sl.hdr.maxLevel)
+	return nil
+}
+
+// 创建新文件  写入文件头及头结点
+func (sl *SkipList) create() error {
+	if err := sl.f.Truncate(initialSize); err != nil {
+		return err
+	}
+	var err error
+	if sl.data, err = mmap(sl.f, initialSize); err != nil {
+		return err
+	}
+	sl.hdr = header{
+		maxLevel: uint32(sl.maxLevel),
+		dataEnd:  headOffset + nodeFixedSize + uint64(sl.maxLevel)*levelSize,
+	}
+	binary.LittleEndian.PutUint32(sl.data[headOffset+fieldHeight:], uint32(sl.maxLevel))
+	sl.hdr.marshal(sl.data)
+	sl.cur = sl.hdr
+	return sl.flush()
+}
+
+// 将日志中的写入及文件头作用到文件
+func (sl *SkipList) applyJournal(h *header, writes []write) {
+	for _, w := range writes {
+		binary.LittleEndian.PutUint64(sl.data[w.off:], w.val)
+	}
+	h.marshal(sl.data)
+}
+
+// 清空日志区  需要在日志中的写入落盘之后执行，否则掉电时可能丢失已作用到结点的写入
+func (sl *SkipList) clearJournal() {
+	binary.LittleEndian.PutUint32(sl.data[journalOffset:], 0)
+}
+
+// 刷盘  先 msync 映射的内容，再 fsync 文件
+func (sl *SkipList) flush() error {
+	if err := msync(sl.data); err != nil {
+		return err
+	}
+	return sl.fsync()
+}
+
+// 关闭或重新映射失败后映射的内存不可访问
+func (sl *SkipList) check() error {
+	if sl.closed || sl.data == nil {
+		return closedErr
+	}
+	return nil
+}
+
+// 开始一次修改
+func (sl *SkipList) begin() error {
+	if err := sl.check(); err != nil {
+		return err
+	}
+	sl.cur = sl.hdr
+	sl.writes = sl.writes[:0]
+	clear(sl.staged)
+	return nil
+}
+
+/*
+放弃当前修改  dataEnd 之后已写入的内容不会被引用
+日志区可能已经写入了当前修改，清空后重新打开时不会重放; 刷盘失败时只能尽力而为
+*/
+func (sl *SkipList) rollback() {
+	sl.cur = sl.hdr
+	sl.writes = sl.writes[:0]
+	clear(sl.staged)
+	if sl.data != nil {
+		sl.clearJournal()
+		if sl.sync {
+			sl.flush()
+		}
+	}
+}
+
+/*
+提交当前修改
+先写入日志区，再作用到已有结点并写入文件头，最后清空日志区。
+开启 sync 时写入日志区、作用到结点、清空日志区之后分别刷盘，保证日志区被清空时结点写入已经落盘。
+结点写入落盘之前失败时恢复原值并清空日志区，返回错误的修改不会在重新打开时被重放
+*/
+func (sl *SkipList) commit() error {
+	if len(sl.writes) > maxJournalWrites {
+		sl.rollback()
+		return journalErr
+	}
+	journal := len(sl.writes) > 0
+	if journal {
+		marshalJournal(sl.data[journalOffset:], &sl.cur, sl.writes)
+		if sl.sync {
+			if err := sl.flush(); err != nil {
+				sl.rollback()
+				return err
+			}
+		}
+	}
+	sl.undo = sl.undo[:0]
+	for _, w := range sl.writes {
+		sl.undo = append(sl.undo, write{off: w.off, val: binary.LittleEndian.Uint64(sl.data[w.off:])})
+	}
+	sl.applyJournal(&sl.cur, sl.writes)
+	if sl.sync {
+		if err := sl.flush(); err != nil {
+			sl.applyJournal(&sl.hdr, sl.undo)
+			sl.rollback()
+			return err
+		}
+	}
+	sl.hdr = sl.cur
+	sl.writes = sl.writes[:0]
+	clear(sl.staged)
+	if journal {
+		//结点写入已落盘，修改已经提交; 清空日志区没有落盘时重新打开会重放同样的写入，结果不变
+		sl.clearJournal()
+		if sl.sync {
+			sl.flush()
+		}
+	}
+	return nil
+}
+
+// 读取uint64  优先读取当前修改中暂存的值
+func (sl *SkipList) u64(off uint64) uint64 {
+	if len(sl.writes) > 0 {
+		if k, ok := sl.staged[off]; ok {
+			return sl.writes[k].val
+		}
+	}
+	return binary.LittleEndian.Uint64(sl.data[off:])
+}
+
+// 写入uint64  已提交区域的写入先暂存，提交时经日志区写入; 新分配区域直接写入
+func (sl *SkipList) put(off, val uint64) {
+	if off >= sl.hdr.dataEnd {
+		binary.LittleEndian.PutUint64(sl.data[off:], val)
+		return
+	}
+	if k, ok := sl.staged[off]; ok {
+		sl.writes[k].val = val
+		return
+	}
+	sl.staged[off] = len(sl.writes)
+	sl.writes = append(sl.writes, write{off: off, val: val})
+}
+
+// 在文件末尾分配空间 (8字节对齐)，空间不足时扩大文件并重新映射
+func (sl *SkipList) alloc(size int) (uint64, error) {
+	off := (sl.cur.dataEnd + 7) &^ 7
+	end := off + uint64(size)
+	if end > uint64(len(sl.data)) {
+		newSize := uint64(len(sl.data)) * 2
+		for newSize < end {
+			newSize *= 2
+		}
+		if err := sl.f.Truncate(int64(newSize)); err != nil {
+			return 0, err
+		}
+		if err := munmap(sl.data); err != nil {
+			return 0, err
+		}
+		data, err := mmap(sl.f, int(newSize))
+		if err != nil {
+			sl.data = nil
+			sl.closed = true
+			return 0, err
+		}
+		sl.data = data
+	}
+	sl.cur.dataEnd = end
+	return off, nil
+}
+
+// 结点字段
+func (sl *SkipList) height(node uint64) int {
+	return int(binary.LittleEndian.Uint32(sl.data[node+fieldHeight:]))
+}
+
+func (sl *SkipList) next(node uint64, level int) uint64 {
+	return sl.u64(node + nodeFixedSize + uint64(level)*levelSize)
+}
+
+func (sl *SkipList) span(node uint64, level int) uint64 {
+	return sl.u64(node + nodeFixedSize + uint64(level)*levelSize + 8)
+}
+
+func (sl *SkipList) setNext(node uint64, level int, next uint64) {
+	sl.put(node+nodeFixedSize+uint64(level)*levelSize, next)
+}
+
+func (sl *SkipList) setSpan(node uint64, level int, span uint64) {
+	sl.put(node+nodeFixedSize+uint64(level)*levelSize+8, span)
+}
+
+// 结点的key  引用映射的内存，不能跨越可能扩容的操作保存
+func (sl *SkipList) key(node uint64) []byte {
+	start := node + nodeFixedSize + uint64(sl.height(node))*levelSize
+	return sl.data[start : start+uint64(binary.LittleEndian.Uint32(sl.data[node+fieldKeyLen:]))]
+}
+
+// 结点的数据  引用映射的内存
+func (sl *SkipList) value(node uint64) []byte {
+	off := sl.u64(node + fieldValOff)
+	return sl.data[off : off+sl.u64(node+fieldValLen)]
+}
+
+// 生成层数
+func (sl *SkipList) randomLevel() int {
+	level := 1
+	for level < sl.maxLevel && sl.rd.Float64() < sl.probability {
+		level++
+	}
+	return level
+}
+
+// 查找路径  prev[level] 为第level层的前置结点，rank[level] 为其rank
+type searchPath struct {
+	prev []uint64
+	rank []uint64
+}
+
+func (sl *SkipList) newSearchPath() *searchPath {
+	path := &searchPath{prev: make([]uint64, sl.maxLevel), rank: make([]uint64, sl.maxLevel)}
+	for level := range path.prev {
+		path.prev[level] = headOffset
+	}
+	return path
+}
+
+// 查找key的前置结点  inclusive 为 true 时为最后一个小于等于key的结点，否则为最后一个小于key的结点
+func (sl *SkipList) seekPath(path *searchPath, key []byte, inclusive bool) {
+	top := int(sl.cur.level)
+	for level := top; level >= 0; level-- {
+		pre, rank := path.prev[level], path.rank[level]
+		if level < top && path.rank[level+1] > rank {
+			pre, rank = path.prev[level+1], path.rank[level+1]
+		}
+		for next := sl.next(pre, level); next != 0; next = sl.next(pre, level) {
+			if c := sl.compare(sl.key(next), key); c > 0 || (c == 0 && !inclusive) {
+				break
+			}
+			rank += sl.span(pre, level)
+			pre = next
+		}
+		path.prev[level], path.rank[level] = pre, rank
+	}
+}
+
+// 查找rank的前置结点 (每层最后一个rank小于rk的结点)
+func (sl *SkipList) seekRankPath(path *searchPath, rk uint64) {
+	top := int(sl.cur.level)
+	for level := top; level >= 0; level-- {
+		pre, rank := path.prev[level], path.rank[level]
+		if level < top && path.rank[level+1] > rank {
+			pre, rank = path.prev[level+1], path.rank[level+1]
+		}
+		for sl.next(pre, level) != 0 && rank+sl.span(pre, level) < rk {
+			rank += sl.span(pre, level)
+			pre = sl.next(pre, level)
+		}
+		path.prev[level], path.rank[level] = pre, rank
+	}
+}
+
+// 将结点插入到路径的第0层前置结点之后，返回结点的rank
+func (sl *SkipList) linkNode(path *searchPath, node uint64, height int) uint64 {
+	for level := int(sl.cur.level) + 1; level < height; level++ {
+		path.prev[level], path.rank[level] = headOffset, 0
+	}
+	if sl.cur.length == 0 || uint64(height-1) > sl.cur.level {
+		sl.cur.level = uint64(height - 1)
+	}
+	sl.cur.length++
+	prev0, rank0 := path.prev[0], path.rank[0]
+	for level := 0; level < height; level++ {
+		pre := path.prev[level]
+		next := sl.next(pre, level)
+		sl.setNext(node, level, next)
+		span := uint64(0)
+		if next != 0 {
+			span = sl.span(pre, level) - (rank0 - path.rank[level])
+		}
+		sl.setSpan(node, level, span)
+		sl.setNext(pre, level, node)
+		sl.setSpan(pre, level, rank0-path.rank[level]+1)
+	}
+	for level := height; level <= int(sl.cur.level); level++ {
+		if sl.next(path.prev[level], level) != 0 {
+			sl.setSpan(path.prev[level], level, sl.span(path.prev[level], level)+1)
+		}
+	}
+	if prev0 != headOffset {
+		sl.put(node+fieldPrev, prev0)
+	}
+	if next := sl.next(node, 0); next != 0 {
+		sl.put(next+fieldPrev, node)
+	} else {
+		sl.cur.tail = node
+	}
+	return rank0 + 1
+}
+
+// 删除路径第0层前置结点的下一个结点
+func (sl *SkipList) unlinkNext(path *searchPath) {
+	node := sl.next(path.prev[0], 0)
+	height := sl.height(node)
+	for level := 0; level <= int(sl.cur.level); level++ {
+		pre := path.prev[level]
+		if level < height {
+			next := sl.next(node, level)
+			sl.setNext(pre, level, next)
+			span := uint64(0)
+			if next != 0 {
+				span = sl.span(pre, level) + sl.span(node, level) - 1
+			}
+			sl.setSpan(pre, level, span)
+		} else if sl.next(pre, level) != 0 {
+			sl.setSpan(pre, level, sl.span(pre, level)-1)
+		}
+	}
+	prev := sl.u64(node + fieldPrev)
+	if next := sl.next(node, 0); next != 0 {
+		sl.put(next+fieldPrev, prev)
+	} else {
+		sl.cur.tail = prev
+	}
+	sl.cur.length--
+	for sl.cur.level > 0 && sl.next(headOffset, int(sl.cur.level)) == 0 {
+		sl.cur.level--
+	}
+}
+
+// 复制切片，不引用映射的内存
+func clone(b []byte) []byte {
+	return append([]byte{}, b...)
+}
+
+// 结点数量
+func (sl *SkipList) GetLength() int {
+	return int(sl.hdr.length)
+}
+
+/*
+插入数据
+不允许相同key时，重复的key插入会返回false; 返回当前排名和插入结果，写入文件失败时返回错误
+*/
+func (sl *SkipList) Insert(key, value []byte) (int, bool, error) {
+	if err := sl.begin(); err != nil {
+		return 0, false, err
+	}
+	path := sl.newSearchPath()
+	sl.seekPath(path, key, true)
+	if !sl.allowSameKey && path.prev[0] != headOffset && sl.compare(sl.key(path.prev[0]), key) == 0 {
+		return 0, false, nil
+	}
+	node, height, err := sl.newNode(key, value)
+	if err != nil {
+		sl.rollback()
+		return 0, false, err
+	}
+	rank := sl.linkNode(path, node, height)
+	if err = sl.commit(); err != nil {
+		return 0, false, err
+	}
+	return int(rank), true, nil
+}
+
+// 在文件末尾创建结点  新结点在 dataEnd 之后，各字段直接写入
+func (sl *SkipList) newNode(key, value []byte) (uint64, int, error) {
+	height := sl.randomLevel()
+	keyOff := nodeFixedSize + uint64(height)*levelSize
+	node, err := sl.alloc(int(keyOff) + len(key) + len(value))
+	if err != nil {
+		return 0, 0, err
+	}
+	clear(sl.data[node : node+keyOff])
+	binary.LittleEndian.PutUint32(sl.data[node+fieldHeight:], uint32(height))
+	binary.LittleEndian.PutUint32(sl.data[node+fieldKeyLen:], uint32(len(key)))
+	binary.LittleEndian.PutUint64(sl.data[node+fieldValLen:], uint64(len(value)))
+	binary.LittleEndian.PutUint64(sl.data[node+fieldValOff:], node+keyOff+uint64(len(key)))
+	copy(sl.data[node+keyOff:], key)
+	copy(sl.data[node+keyOff+uint64(len(key)):], value)
+	return node, height, nil
+}
+
+// 查找和key相等的第一个结点及其rank，不存在时返回0
+func (sl *SkipList) searchFirst(key []byte) (uint64, uint64) {
+	path := sl.newSearchPath()
+	sl.seekPath(path, key, false)
+	if node := sl.next(path.prev[0], 0); node != 0 && sl.compare(sl.key(node), key) == 0 {
+		return node, path.rank[0] + 1
+	}
+	return 0, 0
+}
+
+// 获取和key相等的第一个数据  关闭后返回 closedErr
+func (sl *SkipList) Get(key []byte) ([]byte, bool, error) {
+	if err := sl.check(); err != nil {
+		return nil, false, err
+	}
+	if node, _ := sl.searchFirst(key); node != 0 {
+		return clone(sl.value(node)), true, nil
+	}
+	return nil, false, nil
+}
+
+// 获取和key相等的第一个数据及其排名  不存在时排名为-1，关闭后返回 closedErr
+func (sl *SkipList) GetWithRank(key []byte) ([]byte, int, error) {
+	if err := sl.check(); err != nil {
+		return nil, -1, err
+	}
+	if node, rank := sl.searchFirst(key); node != 0 {
+		return clone(sl.value(node)), int(rank), nil
+	}
+	return nil, -1, nil
+}
+
+// 获取大于等于key的第一个数据及其排名  不存在时排名为-1，关闭后返回 closedErr
+func (sl *SkipList) GetCeiling(key []byte) (Entry, int, error) {
+	if err := sl.check(); err != nil {
+		return Entry{}, -1, err
+	}
+	path := sl.newSearchPath()
+	sl.seekPath(path, key, false)
+	if node := sl.next(path.prev[0], 0); node != 0 {
+		return Entry{Key: clone(sl.key(node)), Value: clone(sl.value(node))}, int(path.rank[0] + 1), nil
+	}
+	return Entry{}, -1, nil
+}
+
+// 获取指定排名的数据  关闭后返回 closedErr
+func (sl *SkipList) GetByRank(rk int) (Entry, bool, error) {
+	list, err := sl.GetByRankRange(rk, rk)
+	if err != nil || len(list) == 0 {
+		return Entry{}, false, err
+	}
+	return list[0], true, nil
+}
+
+// 获取指定排名区间的数据  关闭后返回 closedErr
+func (sl *SkipList) GetByRankRange(start, end int) ([]Entry, error) {
+	if err := sl.check(); err != nil {
+		return nil, err
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > sl.GetLength() {
+		end = sl.GetLength()
+	}
+	list := []Entry{}
+	if start > end {
+		return list, nil
+	}
+	path := sl.newSearchPath()
+	sl.seekRankPath(path, uint64(start))
+	for node := sl.next(path.prev[0], 0); node != 0 && start <= end; node, start = sl.next(node, 0), start+1 {
+		list = append(list, Entry{Key: clone(sl.key(node)), Value: clone(sl.value(node))})
+	}
+	return list, nil
+}
+
+// 从第一个大于等于from的结点开始按顺序遍历  from为nil时从头开始，fn返回false时停止，fn中不能修改跳表; 关闭后返回 closedErr
+func (sl *SkipList) Range(from []byte, fn func(key, value []byte) bool) error {
+	if err := sl.check(); err != nil {
+		return err
+	}
+	node := sl.next(headOffset, 0)
+	if from != nil {
+		path := sl.newSearchPath()
+		sl.seekPath(path, from, false)
+		node = sl.next(path.prev[0], 0)
+	}
+	for ; node != 0; node = sl.next(node, 0) {
+		if !fn(clone(sl.key(node)), clone(sl.value(node))) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// 更新和key相同的数据  当只有一个相同key的结点时能更新成功，新数据写入溢出区
+func (sl *SkipList) UpdateByKey(key, value []byte) (bool, error) {
+	if err := sl.begin(); err != nil {
+		return false, err
+	}
+	node, _ := sl.searchFirst(key)
+	if node == 0 {
+		return false, nil
+	}
+	if next := sl.next(node, 0); next != 0 && sl.compare(sl.key(next), key) == 0 {
+		return false, nil
+	}
+	off, err := sl.alloc(len(value))
+	if err != nil {
+		sl.rollback()
+		return false, err
+	}
+	copy(sl.data[off:], value)
+	sl.put(node+fieldValLen, uint64(len(value)))
+	sl.put(node+fieldValOff, off)
+	return true, sl.commit()
+}
+
+// 删除和key相同的数据  当只有一个相同key的结点时能删除成功
+func (sl *SkipList) DeleteByKey(key []byte) (bool, error) {
+	if err := sl.begin(); err != nil {
+		return false, err
+	}
+	path := sl.newSearchPath()
+	sl.seekPath(path, key, false)
+	node := sl.next(path.prev[0], 0)
+	if node == 0 || sl.compare(sl.key(node), key) != 0 {
+		return false, nil
+	}
+	if next := sl.next(node, 0); next != 0 && sl.compare(sl.key(next), key) == 0 {
+		return false, nil
+	}
+	sl.unlinkNext(path)
+	return true, sl.commit()
+}
+
+// 删除所有和key相同的数据  每个结点单独提交
+func (sl *SkipList) DeleteBatchByKey(key []byte) (bool, error) {
+	deleted := false
+	for {
+		if err := sl.begin(); err != nil {
+			return deleted, err
+		}
+		path := sl.newSearchPath()
+		sl.seekPath(path, key, false)
+		node := sl.next(path.prev[0], 0)
+		if node == 0 || sl.compare(sl.key(node), key) != 0 {
+			return deleted, nil
+		}
+		sl.unlinkNext(path)
+		if err := sl.commit(); err != nil {
+			return deleted, err
+		}
+		deleted = true
+	}
+}
+
+// 删除指定排名的结点
+func (sl *SkipList) DeleteByRank(rank int) (bool, error) {
+	if err := sl.begin(); err != nil {
+		return false, err
+	}
+	if rank < 1 || rank > sl.GetLength() {
+		return false, nil
+	}
+	path := sl.newSearchPath()
+	sl.seekRankPath(path, uint64(rank))
+	sl.unlinkNext(path)
+	return true, sl.commit()
+}
+
+// 文件已使用的字节数 (含已删除结点及旧数据占用的空间)
+func (sl *SkipList) Size() int64 {
+	return int64(sl.hdr.dataEnd)
+}
+
+// 将映射的内容刷到磁盘
+func (sl *SkipList) Sync() error {
+	if err := sl.check(); err != nil {
+		return err
+	}
+	return sl.flush()
+}
+
+// 刷盘并关闭文件
+func (sl *SkipList) Close() error {
+	if sl.closed {
+		return closedErr
+	}
+	sl.closed = true
+	err := sl.flush()
+	if e := munmap(sl.data); err == nil {
+		err = e
+	}
+	if e := sl.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,291 @@
+//go:build unix
+
+package mmapskiplist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+func openTest(t *testing.T, path string, options ...Option) *SkipList {
+	t.Helper()
+	sl, err := Open(path, append([]Option{WithSync(false)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sl
+}
+
+// 与内存跳表对比全部数据
+func assertSame(t *testing.T, sl *SkipList, want *skiplist.SkipList) {
+	t.Helper()
+	if sl.GetLength() != want.GetLength() {
+		t.Fatalf("长度 %d want %d", sl.GetLength(), want.GetLength())
+	}
+	got, err := sl.GetByRankRange(1, sl.GetLength())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := want.GetByRankRange(1, want.GetLength())
+	for i := range exp {
+		if string(got[i].Value) != exp[i].(string) {
+			t.Fatalf("第 %d 个数据 %s want %s", i+1, got[i].Value, exp[i])
+		}
+	}
+	n := 0
+	err = sl.Range(nil, func(key, value []byte) bool {
+		n++
+		return true
+	})
+	if err != nil || n != sl.GetLength() {
+		t.Fatalf("遍历数量 %d want %d", n, sl.GetLength())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list")
+	if _, err := Open(path, WithMaxLevel(65)); err != levelErr {
+		t.Fatalf("want levelErr, got %v", err)
+	}
+	if _, err := Open(path, WithProbability(1)); err != probabilityErr {
+		t.Fatalf("want probabilityErr, got %v", err)
+	}
+	if _, err := Open(path, WithCompare(nil)); err != compareErr {
+		t.Fatalf("want compareErr, got %v", err)
+	}
+	os.WriteFile(path, bytes.Repeat([]byte{1}, headOffset), 0644)
+	if _, err := Open(path); err != magicErr {
+		t.Fatalf("want magicErr, got %v", err)
+	}
+	sl := openTest(t, filepath.Join(t.TempDir(), "unique"), WithAllowTheSameKey(false))
+	defer sl.Close()
+	if _, ok, _ := sl.Insert([]byte("a"), nil); !ok {
+		t.Fatal("插入失败")
+	}
+	if _, ok, _ := sl.Insert([]byte("a"), nil); ok {
+		t.Fatal("不允许重复key")
+	}
+}
+
+// TestRandom 随机操作与内存跳表对比，并在中途重新打开
+func TestRandom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list")
+	sl := openTest(t, path, WithMaxLevel(12))
+	want, _ := skiplist.New(skiplist.Bytes())
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		key := []byte(fmt.Sprintf("key-%04d", r.Intn(3000)))
+		value := fmt.Sprintf("value-%d-%s", i, bytes.Repeat([]byte{'x'}, r.Intn(64)))
+		var err error
+		switch op := r.Intn(10); {
+		case op < 6:
+			rk, ok, e := sl.Insert(key, []byte(value))
+			wrk, wok := want.Insert(key, value)
+			if rk != wrk || ok != wok {
+				t.Fatalf("插入 %s = %d,%v want %d,%v", key, rk, ok, wrk, wok)
+			}
+			err = e
+		case op < 7:
+			ok, e := sl.UpdateByKey(key, []byte(value))
+			if ok != want.UpdateByKey(key, value) {
+				t.Fatalf("更新 %s 结果不一致", key)
+			}
+			err = e
+		case op < 8:
+			ok, e := sl.DeleteByKey(key)
+			if ok != want.DeleteByKey(key) {
+				t.Fatalf("删除 %s 结果不一致", key)
+			}
+			err = e
+		case op < 9:
+			ok, e := sl.DeleteBatchByKey(key)
+			if ok != want.DeleteBatchByKey(key) {
+				t.Fatalf("批量删除 %s 结果不一致", key)
+			}
+			err = e
+		default:
+			rk := r.Intn(want.GetLength() + 2)
+			ok, e := sl.DeleteByRank(rk)
+			if ok != want.DeleteByRank(rk) {
+				t.Fatalf("删除 rank %d 结果不一致", rk)
+			}
+			err = e
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i%5000 == 4999 {
+			assertSame(t, sl, want)
+			if err = sl.Close(); err != nil {
+				t.Fatal(err)
+			}
+			sl = openTest(t, path)
+			if sl.maxLevel != 12 {
+				t.Fatalf("重新打开后最大层数 %d want 12", sl.maxLevel)
+			}
+		}
+	}
+	assertSame(t, sl, want)
+	//查询
+	key := []byte("key-1000")
+	value, rank, _ := sl.GetWithRank(key)
+	wvalue, wrank := want.GetFirstWithRankByKey(key)
+	if rank != wrank || (rank > 0 && string(value) != wvalue.(string)) {
+		t.Fatalf("GetWithRank = %s,%d want %v,%d", value, rank, wvalue, wrank)
+	}
+	entry, rank, _ := sl.GetCeiling([]byte("key-1000a"))
+	wvalue, wrank = want.GetCeilingWithRankByKey([]byte("key-1000a"))
+	if rank != wrank || string(entry.Value) != wvalue.(string) {
+		t.Fatalf("GetCeiling = %s,%d want %v,%d", entry.Value, rank, wvalue, wrank)
+	}
+	if sl.Size() <= initialSize {
+		t.Fatalf("文件没有扩容 %d", sl.Size())
+	}
+	sl.Close()
+	if _, _, err := sl.Insert(key, key); err != closedErr {
+		t.Fatalf("want closedErr, got %v", err)
+	}
+	//关闭后映射的内存已解除，读接口同样返回 closedErr
+	if _, _, err := sl.Get(key); err != closedErr {
+		t.Fatalf("Get want closedErr, got %v", err)
+	}
+	if _, _, err := sl.GetWithRank(key); err != closedErr {
+		t.Fatalf("GetWithRank want closedErr, got %v", err)
+	}
+	if _, _, err := sl.GetCeiling(key); err != closedErr {
+		t.Fatalf("GetCeiling want closedErr, got %v", err)
+	}
+	if _, _, err := sl.GetByRank(1); err != closedErr {
+		t.Fatalf("GetByRank want closedErr, got %v", err)
+	}
+	if _, err := sl.GetByRankRange(1, 10); err != closedErr {
+		t.Fatalf("GetByRankRange want closedErr, got %v", err)
+	}
+	if err := sl.Range(nil, func(key, value []byte) bool { return true }); err != closedErr {
+		t.Fatalf("Range want closedErr, got %v", err)
+	}
+}
+
+// TestSyncFailure 提交时刷盘失败返回错误，修改被撤销，重新打开时不会重放
+func TestSyncFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list")
+	sl := openTest(t, path, WithSync(true))
+	for i := 0; i < 100; i++ {
+		sl.Insert([]byte(fmt.Sprintf("%03d", i*2)), []byte("v"))
+	}
+	fail := errors.New("sync failed")
+	//第1次刷盘为日志区，第2次为结点写入
+	for n, key := range []string{"051", "053"} {
+		calls := 0
+		sl.fsync = func() error {
+			if calls++; calls == n+1 {
+				return fail
+			}
+			return nil
+		}
+		if _, _, err := sl.Insert([]byte(key), []byte("new")); err != fail {
+			t.Fatalf("第 %d 次刷盘失败 want fail, got %v", n+1, err)
+		}
+		if _, ok, _ := sl.Get([]byte(key)); ok || sl.GetLength() != 100 {
+			t.Fatalf("失败的插入仍然可见, 长度 %d", sl.GetLength())
+		}
+		checkList(t, sl, 100)
+	}
+	sl.fsync = sl.f.Sync
+	munmap(sl.data)
+	sl.f.Close()
+	sl = openTest(t, path)
+	for _, key := range []string{"051", "053"} {
+		if _, ok, _ := sl.Get([]byte(key)); ok {
+			t.Fatalf("失败的插入 %s 被重放", key)
+		}
+	}
+	checkList(t, sl, 100)
+	sl.Close()
+}
+
+// 校验结点数量及第0层的顺序
+func checkList(t *testing.T, sl *SkipList, length int) {
+	t.Helper()
+	list, err := sl.GetByRankRange(1, sl.GetLength())
+	if err != nil || len(list) != length || sl.GetLength() != length {
+		t.Fatalf("长度 %d/%d want %d, %v", len(list), sl.GetLength(), length, err)
+	}
+	for i := 1; i < len(list); i++ {
+		if bytes.Compare(list[i-1].Key, list[i].Key) > 0 {
+			t.Fatalf("第 %d 个key %s 小于前一个 %s", i+1, list[i].Key, list[i-1].Key)
+		}
+	}
+}
+
+// 模拟提交过程中崩溃: 日志区已写入，applied 为 false 时没有作用到结点，为 true 时已作用到结点但没有清空日志区
+func crashAfterJournal(t *testing.T, sl *SkipList, key, value []byte, applied bool) {
+	t.Helper()
+	sl.begin()
+	path := sl.newSearchPath()
+	sl.seekPath(path, key, true)
+	node, height, _ := sl.newNode(key, value)
+	sl.linkNode(path, node, height)
+	marshalJournal(sl.data[journalOffset:], &sl.cur, sl.writes)
+	if applied {
+		sl.applyJournal(&sl.cur, sl.writes)
+	}
+	munmap(sl.data)
+	sl.f.Close()
+}
+
+// TestRecover 日志区完整时重放，不完整时丢弃，文件头损坏时报错
+func TestRecover(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "list")
+	sl := openTest(t, path)
+	for i := 0; i < 100; i++ {
+		sl.Insert([]byte(fmt.Sprintf("%03d", i*2)), []byte("v"))
+	}
+	crashAfterJournal(t, sl, []byte("051"), []byte("new"), false)
+	sl = openTest(t, path)
+	if v, rank, _ := sl.GetWithRank([]byte("051")); string(v) != "new" || rank != 27 || sl.GetLength() != 101 {
+		t.Fatalf("重放后 %s,%d 长度 %d", v, rank, sl.GetLength())
+	}
+
+	//已作用到结点但日志区没有清空，重放是幂等的
+	crashAfterJournal(t, sl, []byte("001"), []byte("new"), true)
+	sl = openTest(t, path)
+	if v, rank, _ := sl.GetWithRank([]byte("001")); string(v) != "new" || rank != 2 || sl.GetLength() != 102 {
+		t.Fatalf("重放后 %s,%d 长度 %d", v, rank, sl.GetLength())
+	}
+	if n := binary.LittleEndian.Uint32(sl.data[journalOffset:]); n != 0 {
+		t.Fatalf("重放后日志区没有清空 %d", n)
+	}
+	sl.DeleteByKey([]byte("001"))
+
+	//日志区写了一半，丢弃
+	crashAfterJournal(t, sl, []byte("053"), []byte("new"), false)
+	data, _ := os.ReadFile(path)
+	data[journalOffset+20]++
+	os.WriteFile(path, data, 0644)
+	sl = openTest(t, path)
+	if _, ok, _ := sl.Get([]byte("053")); ok || sl.GetLength() != 101 {
+		t.Fatalf("不完整的日志不应当被重放, 长度 %d", sl.GetLength())
+	}
+	sl.Insert([]byte("053"), []byte("again"))
+	if v, rank, _ := sl.GetWithRank([]byte("053")); string(v) != "again" || rank != 29 {
+		t.Fatalf("插入后 %s,%d", v, rank)
+	}
+	sl.Close()
+
+	data, _ = os.ReadFile(path)
+	data[20]++
+	os.WriteFile(path, data, 0644)
+	if _, err := Open(path); !errors.Is(err, headerErr) {
+		t.Fatalf("want headerErr, got %v", err)
+	}
+}
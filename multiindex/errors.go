@@ -0,0 +1,11 @@
+package multiindex
+
+import "errors"
+
+var (
+	indexExistsErr     = errors.New("index already exists")
+	indexNotFoundErr   = errors.New("index not found")
+	compareErr         = errors.New("compare is nil")
+	recordNotFoundErr  = errors.New("record not found")
+	uniqueViolationErr = errors.New("unique constraint violation")
+)
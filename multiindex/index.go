@@ -0,0 +1,84 @@
+package multiindex
+
+/*
+索引查询视图
+按索引的顺序查询记录，查询条件 probe 是与记录同类型的值，只需要填充索引比较时用到的字段
+*/
+type Index struct {
+	c   *Collection
+	idx *index
+}
+
+// 索引名
+func (ix *Index) Name() string {
+	return ix.idx.name
+}
+
+// 是否为唯一索引
+func (ix *Index) Unique() bool {
+	return ix.idx.unique
+}
+
+// 和probe相等的第一条记录
+func (ix *Index) First(probe interface{}) (Record, bool) {
+	r, rank := ix.idx.first(probe)
+	return r, rank > 0
+}
+
+// 和probe相等的所有记录，按插入顺序
+func (ix *Index) Find(probe interface{}) []Record {
+	list := []Record{}
+	_, rank := ix.idx.first(probe)
+	if rank < 0 {
+		return list
+	}
+	ix.Range(rank, func(r Record) bool {
+		if ix.idx.compareAble.Compare(r.Value, probe) != 0 {
+			return false
+		}
+		list = append(list, r)
+		return true
+	})
+	return list
+}
+
+// 记录在索引中的排名  记录不存在时返回-1
+func (ix *Index) Rank(id uint64) int {
+	value, ok := ix.c.records[id]
+	if !ok {
+		return -1
+	}
+	_, rank := ix.idx.sl.GetFirstWithRankByKey(entry{value: value, id: id})
+	return rank
+}
+
+// 获取指定排名的记录
+func (ix *Index) GetByRank(rank int) (Record, bool) {
+	data := ix.idx.sl.GetByRank(rank)
+	if data == nil {
+		return Record{}, false
+	}
+	return data.(Record), true
+}
+
+// 获取指定排名区间的记录
+func (ix *Index) GetByRankRange(start, end int) []Record {
+	data := ix.idx.sl.GetByRankRange(start, end)
+	list := make([]Record, len(data))
+	for k := range data {
+		list[k] = data[k].(Record)
+	}
+	return list
+}
+
+// 从指定排名开始按索引顺序遍历  fn 返回false时停止，fn 中不能修改集合
+func (ix *Index) Range(rank int, fn func(r Record) bool) {
+	const batch = 64
+	for start := max(rank, 1); start <= ix.idx.sl.GetLength(); start += batch {
+		for _, data := range ix.idx.sl.GetByRankRange(start, start+batch-1) {
+			if !fn(data.(Record)) {
+				return
+			}
+		}
+	}
+}
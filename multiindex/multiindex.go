@@ -0,0 +1,202 @@
+package multiindex
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+// 一条记录
+type Record struct {
+	ID    uint64 //插入时分配，从1开始
+	Value interface{}
+}
+
+// 索引中的key  先按索引的比较接口比较记录，相同时按ID排序，以便定位到具体的记录
+type entry struct {
+	value interface{}
+	id    uint64 //0 用于查找，排在所有相同记录之前
+}
+
+// 索引
+type index struct {
+	name        string
+	compareAble skiplist.CompareAble
+	unique      bool
+	sl          *skiplist.SkipList //entry -> Record
+}
+
+func newIndex(name string, compareAble skiplist.CompareAble, unique bool) (*index, error) {
+	idx := &index{name: name, compareAble: compareAble, unique: unique}
+	sl, err := skiplist.New(skiplist.CompareFunc(func(a, b interface{}) int {
+		ea, eb := a.(entry), b.(entry)
+		if c := compareAble.Compare(ea.value, eb.value); c != 0 {
+			return c
+		}
+		return cmp.Compare(ea.id, eb.id)
+	}), skiplist.WithAllowTheSameKey(false))
+	if err != nil {
+		return nil, err
+	}
+	idx.sl = sl
+	return idx, nil
+}
+
+// 和value相等的第一条记录  不存在时rank为-1
+func (idx *index) first(value interface{}) (Record, int) {
+	data, rank := idx.sl.GetCeilingWithRankByKey(entry{value: value})
+	if rank < 0 {
+		return Record{}, -1
+	}
+	r := data.(Record)
+	if idx.compareAble.Compare(r.Value, value) != 0 {
+		return Record{}, -1
+	}
+	return r, rank
+}
+
+// value是否与ID不为id的记录冲突
+func (idx *index) conflicts(value interface{}, id uint64) bool {
+	if !idx.unique {
+		return false
+	}
+	r, rank := idx.first(value)
+	if rank < 0 {
+		return false
+	}
+	if r.ID != id {
+		return true
+	}
+	//与自身相等时继续检查下一条
+	next := idx.sl.GetByRank(rank + 1)
+	return next != nil && idx.compareAble.Compare(next.(Record).Value, value) == 0
+}
+
+/*
+多索引集合
+同一组记录按多个命名索引同时排序，每个索引使用自己的比较接口，可以设置为唯一索引。
+Insert/Delete/Update 先校验所有唯一索引，校验通过后再修改全部索引，违反唯一约束时返回错误且不做任何修改。
+与 SkipList 相同，Collection 不是并发安全的
+*/
+type Collection struct {
+	indexes []*index
+	byName  map[string]*index
+	records map[uint64]interface{}
+	nextID  uint64
+}
+
+func New() *Collection {
+	return &Collection{byName: map[string]*index{}, records: map[uint64]interface{}{}}
+}
+
+// 关闭集合  结束每个索引跳表的层数生成协程，不再使用时需要调用，关闭后仍可读写及添加索引
+func (c *Collection) Close() {
+	for _, idx := range c.indexes {
+		idx.sl.Close()
+	}
+}
+
+/*
+添加索引
+已有记录时会为其建立索引，唯一索引下已有记录重复时返回错误且不添加索引
+*/
+func (c *Collection) AddIndex(name string, compareAble skiplist.CompareAble, unique bool) error {
+	if compareAble == nil {
+		return compareErr
+	}
+	if _, ok := c.byName[name]; ok {
+		return fmt.Errorf("multiindex: index %q: %w", name, indexExistsErr)
+	}
+	idx, err := newIndex(name, compareAble, unique)
+	if err != nil {
+		return err
+	}
+	for id, value := range c.records {
+		if idx.conflicts(value, id) {
+			idx.sl.Close()
+			return fmt.Errorf("multiindex: index %q: %w", name, uniqueViolationErr)
+		}
+		idx.sl.Insert(entry{value: value, id: id}, Record{ID: id, Value: value})
+	}
+	c.indexes = append(c.indexes, idx)
+	c.byName[name] = idx
+	return nil
+}
+
+// 校验value是否违反唯一约束  id为被更新的记录，插入时为0
+func (c *Collection) check(value interface{}, id uint64) error {
+	for _, idx := range c.indexes {
+		if idx.conflicts(value, id) {
+			return fmt.Errorf("multiindex: index %q: %w", idx.name, uniqueViolationErr)
+		}
+	}
+	return nil
+}
+
+// 插入记录，返回分配的ID
+func (c *Collection) Insert(value interface{}) (uint64, error) {
+	if err := c.check(value, 0); err != nil {
+		return 0, err
+	}
+	c.nextID++
+	id := c.nextID
+	c.records[id] = value
+	for _, idx := range c.indexes {
+		idx.sl.Insert(entry{value: value, id: id}, Record{ID: id, Value: value})
+	}
+	return id, nil
+}
+
+// 删除记录，返回记录是否存在
+func (c *Collection) Delete(id uint64) bool {
+	value, ok := c.records[id]
+	if !ok {
+		return false
+	}
+	for _, idx := range c.indexes {
+		idx.sl.DeleteByKey(entry{value: value, id: id})
+	}
+	delete(c.records, id)
+	return true
+}
+
+/*
+更新记录
+新记录违反唯一约束时返回错误且不做任何修改; 排序字段变化时记录会在各索引中移动到新位置
+*/
+func (c *Collection) Update(id uint64, value interface{}) error {
+	old, ok := c.records[id]
+	if !ok {
+		return fmt.Errorf("multiindex: record %d: %w", id, recordNotFoundErr)
+	}
+	if err := c.check(value, id); err != nil {
+		return err
+	}
+	for _, idx := range c.indexes {
+		idx.sl.DeleteByKey(entry{value: old, id: id})
+		idx.sl.Insert(entry{value: value, id: id}, Record{ID: id, Value: value})
+	}
+	c.records[id] = value
+	return nil
+}
+
+// 获取记录
+func (c *Collection) Get(id uint64) (interface{}, bool) {
+	value, ok := c.records[id]
+	return value, ok
+}
+
+// 记录数量
+func (c *Collection) Len() int {
+	return len(c.records)
+}
+
+// 获取命名索引的查询视图
+func (c *Collection) Index(name string) (*Index, error) {
+	idx, ok := c.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("multiindex: index %q: %w", name, indexNotFoundErr)
+	}
+	return &Index{c: c, idx: idx}, nil
+}
@@ -0,0 +1,203 @@
+package multiindex
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+type order struct {
+	No    string
+	User  string
+	Price float64
+	Time  int64
+}
+
+func newOrders(t *testing.T) *Collection {
+	c := New()
+	indexes := []struct {
+		name   string
+		cmp    skiplist.CompareAble
+		unique bool
+	}{
+		{"no", skiplist.By(func(o order) string { return o.No }, skiplist.Ordered[string]()), true},
+		{"price", skiplist.By(func(o order) float64 { return o.Price }, skiplist.Ordered[float64]()), false},
+		{"time", skiplist.By(func(o order) int64 { return o.Time }, skiplist.Ordered[int64]()), false},
+		{"user", skiplist.By(func(o order) string { return o.User }, skiplist.Ordered[string]()), false},
+	}
+	for _, idx := range indexes {
+		if err := c.AddIndex(idx.name, idx.cmp, idx.unique); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func nos(list []Record) string {
+	s := ""
+	for _, r := range list {
+		s += r.Value.(order).No + " "
+	}
+	return s
+}
+
+func TestCollection(t *testing.T) {
+	c := newOrders(t)
+	defer c.Close()
+	if err := c.AddIndex("no", skiplist.Ordered[string](), false); !errors.Is(err, indexExistsErr) {
+		t.Fatalf("want indexExistsErr, got %v", err)
+	}
+	if _, err := c.Index("missing"); !errors.Is(err, indexNotFoundErr) {
+		t.Fatalf("want indexNotFoundErr, got %v", err)
+	}
+	c.Insert(order{"a", "u1", 30, 3})
+	idB, _ := c.Insert(order{"b", "u2", 10, 1})
+	c.Insert(order{"c", "u1", 20, 2})
+	c.Insert(order{"d", "u1", 10, 4})
+	//违反唯一约束时不做任何修改
+	if _, err := c.Insert(order{"a", "u3", 1, 1}); !errors.Is(err, uniqueViolationErr) {
+		t.Fatalf("want uniqueViolationErr, got %v", err)
+	}
+	if c.Len() != 4 {
+		t.Fatalf("Len = %d", c.Len())
+	}
+	price, _ := c.Index("price")
+	if got := nos(price.GetByRankRange(1, 10)); got != "b d c a " {
+		t.Fatalf("price order %s", got)
+	}
+	timeIdx, _ := c.Index("time")
+	if got := nos(timeIdx.GetByRankRange(1, 10)); got != "b c a d " {
+		t.Fatalf("time order %s", got)
+	}
+	user, _ := c.Index("user")
+	if got := nos(user.Find(order{User: "u1"})); got != "a c d " {
+		t.Fatalf("user u1 %s", got)
+	}
+	if got := nos(price.Find(order{Price: 10})); got != "b d " {
+		t.Fatalf("price 10 %s", got)
+	}
+	if r, ok := price.First(order{Price: 20}); !ok || r.Value.(order).No != "c" {
+		t.Fatalf("First price 20 = %v, %v", r, ok)
+	}
+	if _, ok := price.First(order{Price: 15}); ok {
+		t.Fatal("price 15 should not exist")
+	}
+
+	//更新排序字段后移动位置
+	if err := c.Update(idB, order{"b", "u2", 40, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if got := nos(price.GetByRankRange(1, 10)); got != "d c a b " {
+		t.Fatalf("price order after update %s", got)
+	}
+	if price.Rank(idB) != 4 || timeIdx.Rank(idB) != 4 {
+		t.Fatalf("rank after update price %d time %d", price.Rank(idB), timeIdx.Rank(idB))
+	}
+	//更新为与其他记录冲突的唯一key
+	if err := c.Update(idB, order{"a", "u2", 40, 5}); !errors.Is(err, uniqueViolationErr) {
+		t.Fatalf("want uniqueViolationErr, got %v", err)
+	}
+	if v, _ := c.Get(idB); v.(order).No != "b" {
+		t.Fatalf("update should not apply, got %v", v)
+	}
+	if err := c.Update(100, order{}); !errors.Is(err, recordNotFoundErr) {
+		t.Fatalf("want recordNotFoundErr, got %v", err)
+	}
+
+	if !c.Delete(idB) || c.Delete(idB) {
+		t.Fatal("Delete result mismatch")
+	}
+	for _, name := range []string{"no", "price", "time", "user"} {
+		ix, _ := c.Index(name)
+		if len(ix.GetByRankRange(1, 10)) != 3 || ix.Rank(idB) != -1 {
+			t.Fatalf("index %s not updated", name)
+		}
+	}
+	//已有重复记录时不能添加唯一索引
+	if err := c.AddIndex("user_unique", skiplist.By(func(o order) string { return o.User }, skiplist.Ordered[string]()), true); !errors.Is(err, uniqueViolationErr) {
+		t.Fatalf("want uniqueViolationErr, got %v", err)
+	}
+	if err := c.AddIndex("price_unique", skiplist.By(func(o order) float64 { return o.Price }, skiplist.Ordered[float64]()), true); err != nil {
+		t.Fatal(err)
+	}
+	ix, _ := c.Index("price_unique")
+	if !ix.Unique() || nos(ix.GetByRankRange(1, 10)) != "d c a " {
+		t.Fatalf("new index %s", nos(ix.GetByRankRange(1, 10)))
+	}
+}
+
+// TestRandom 随机操作后各索引都与按记录排序的结果一致
+func TestRandom(t *testing.T) {
+	c := newOrders(t)
+	r := rand.New(rand.NewSource(1))
+	ids := []uint64{}
+	for i := 0; i < 3000; i++ {
+		o := order{No: string(rune('a'+r.Intn(26))) + string(rune('a'+r.Intn(26))), User: string(rune('a' + r.Intn(5))), Price: float64(r.Intn(50)), Time: int64(r.Intn(100))}
+		switch {
+		case len(ids) > 0 && r.Intn(4) == 0:
+			k := r.Intn(len(ids))
+			c.Delete(ids[k])
+			ids = append(ids[:k], ids[k+1:]...)
+		case len(ids) > 0 && r.Intn(3) == 0:
+			c.Update(ids[r.Intn(len(ids))], o)
+		default:
+			if id, err := c.Insert(o); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	price, _ := c.Index("price")
+	list := price.GetByRankRange(1, c.Len())
+	if len(list) != c.Len() || len(ids) != c.Len() {
+		t.Fatalf("长度不一致 %d %d %d", len(list), len(ids), c.Len())
+	}
+	if !sort.SliceIsSorted(list, func(i, j int) bool {
+		a, b := list[i].Value.(order), list[j].Value.(order)
+		return a.Price < b.Price || a.Price == b.Price && list[i].ID < list[j].ID
+	}) {
+		t.Fatal("price 索引顺序错误")
+	}
+	no, _ := c.Index("no")
+	seen := map[string]bool{}
+	for _, rec := range no.GetByRankRange(1, c.Len()) {
+		if seen[rec.Value.(order).No] {
+			t.Fatalf("唯一索引重复 %s", rec.Value.(order).No)
+		}
+		seen[rec.Value.(order).No] = true
+		if v, _ := c.Get(rec.ID); v != rec.Value {
+			t.Fatalf("索引中的记录 %v 与集合不一致 %v", rec.Value, v)
+		}
+	}
+}
+
+// TestClose 测试关闭后各索引的协程退出且仍可读写
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	list := []*Collection{}
+	for i := 0; i < 10; i++ {
+		c := newOrders(t)
+		c.Insert(order{"a", "u1", 1, 1})
+		c.Insert(order{"b", "u1", 2, 2})
+		//添加失败的唯一索引也不会留下协程
+		if err := c.AddIndex("user_unique", skiplist.By(func(o order) string { return o.User }, skiplist.Ordered[string]()), true); !errors.Is(err, uniqueViolationErr) {
+			t.Fatalf("want uniqueViolationErr, got %v", err)
+		}
+		list = append(list, c)
+	}
+	for _, c := range list {
+		c.Close()
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	c := list[0]
+	c.Insert(order{"c", "u2", 0, 3})
+	price, _ := c.Index("price")
+	if got := nos(price.GetByRankRange(1, 10)); got != "c a b " {
+		t.Fatalf("关闭后 price order %s", got)
+	}
+}
@@ -0,0 +1,16 @@
+package replication
+
+import "errors"
+
+var (
+	closedErr       = errors.New("replication is closed")
+	codecErr        = errors.New("codec is nil")
+	logSizeErr      = errors.New("log size must grater than 0")
+	snapshotSizeErr = errors.New("snapshot size must grater than 0")
+	listErr         = errors.New("skiplist is nil")
+	frameErr        = errors.New("replication frame is corrupted")
+	handshakeErr    = errors.New("unexpected replication handshake")
+	gapErr          = errors.New("replication sequence gap")
+	divergedErr     = errors.New("follower diverged from leader")
+	snapshotErr     = errors.New("snapshot entries out of range")
+)
@@ -0,0 +1,162 @@
+package replication
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+	"github.com/yytany/ds/wal"
+)
+
+/*
+从节点
+连接主节点后接收快照及增量变更并作用到本地跳表。每个变更的序号必须是上一个序号加一，否则认为出现了缺口;
+插入后的排名与主节点不一致时认为数据已经分叉。出现错误后重新 Run 会从最后应用的序号继续，
+主节点无法提供时会重新全量同步
+*/
+type Follower struct {
+	mu     sync.RWMutex
+	sl     *skiplist.SkipList
+	codec  wal.Codec
+	max    int    //快照条目数上限
+	seq    uint64 //最后应用的变更序号
+	synced bool   //是否完成过全量同步
+	loads  int    //全量同步次数
+}
+
+// 创建从节点  sl 应为新建的空跳表，比较接口与允许重复key的设置需要与主节点一致
+func NewFollower(sl *skiplist.SkipList, options ...Option) (*Follower, error) {
+	if sl == nil {
+		return nil, listErr
+	}
+	c, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Follower{sl: sl, codec: c.codec, max: c.snapshotSize}, nil
+}
+
+// 最后应用的变更序号
+func (f *Follower) Seq() uint64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.seq
+}
+
+// 读取跳表  fn 执行期间不会应用新的变更
+func (f *Follower) View(fn func(sl *skiplist.SkipList)) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	fn(f.sl)
+}
+
+// 从主节点同步，直到连接断开或出现错误  返回时关闭连接
+func (f *Follower) Run(conn net.Conn) error {
+	defer conn.Close()
+	f.mu.RLock()
+	hello := &message{typ: msgHello}
+	if f.synced {
+		hello.seq = f.seq + 1
+	}
+	f.mu.RUnlock()
+	if _, err := conn.Write(hello.marshal()); err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	for {
+		m, err := readMessage(r)
+		if err != nil {
+			return err
+		}
+		if m.typ == msgSnapshot {
+			err = f.loadSnapshot(r, m)
+		} else {
+			err = f.apply(m)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 加载快照  先解码全部条目，再替换跳表中的数据; 条目数超过上限时返回错误，按实际读到的条目扩容
+func (f *Follower) loadSnapshot(r *bufio.Reader, head *message) error {
+	if head.rank < 0 || head.rank > f.max {
+		return snapshotErr
+	}
+	keys := make([]interface{}, 0, min(head.rank, 1024))
+	datas := make([]interface{}, 0, min(head.rank, 1024))
+	for k := 0; k < head.rank; k++ {
+		m, err := readMessage(r)
+		if err != nil {
+			return err
+		}
+		if m.typ != msgEntry {
+			return frameErr
+		}
+		key, err := f.codec.Decode(m.key)
+		if err != nil {
+			return err
+		}
+		data, err := f.codec.Decode(m.data)
+		if err != nil {
+			return err
+		}
+		keys, datas = append(keys, key), append(datas, data)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.sl.GetLength() > 0 {
+		f.sl.DeleteByRank(f.sl.GetLength())
+	}
+	var hint skiplist.Hint
+	for k := range keys {
+		if _, ok := f.sl.InsertAfterHint(&hint, keys[k], datas[k]); !ok {
+			f.synced = false
+			return divergedErr
+		}
+	}
+	f.seq, f.synced = head.seq, true
+	f.loads++
+	return nil
+}
+
+// 应用一个增量变更
+func (f *Follower) apply(m *message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.synced || m.seq != f.seq+1 {
+		return gapErr
+	}
+	var key, data interface{}
+	var err error
+	if key, err = f.codec.Decode(m.key); err != nil {
+		return err
+	}
+	if m.typ == msgInsert || m.typ == msgUpdate {
+		if data, err = f.codec.Decode(m.data); err != nil {
+			return err
+		}
+	}
+	ok := false
+	switch m.typ {
+	case msgInsert:
+		var rank int
+		rank, ok = f.sl.Insert(key, data)
+		ok = ok && rank == m.rank
+	case msgDelete:
+		ok = f.sl.DeleteByRank(m.rank)
+	case msgUpdate:
+		ok = f.sl.UpdateByRank(m.rank, data)
+	default:
+		return frameErr
+	}
+	if !ok {
+		//数据已经分叉，下次连接时重新全量同步
+		f.synced = false
+		return divergedErr
+	}
+	f.seq = m.seq
+	return nil
+}
@@ -0,0 +1,315 @@
+package replication
+
+import (
+	"bufio"
+	"net"
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+	"github.com/yytany/ds/wal"
+)
+
+const maxBatch = 1024 //每次发送的最大变更数
+
+/*
+主节点
+通过观察者记录跳表的每一次变更，按单调递增的序号保存在内存中的变更日志里，并推送给从节点。
+从节点首次连接或落后超过日志保留范围时发送快照，之后发送增量变更。
+对跳表的修改需要通过 Update 执行，以保证快照与序号一致; 在 Update 之外的修改同样会被记录，
+但调用侧需要自行保证不与 Update、View 及快照并发
+*/
+type Leader struct {
+	mu       sync.Mutex //保护跳表及连接
+	sl       *skiplist.SkipList
+	codec    wal.Codec
+	logSize  int
+	logMu    sync.Mutex    //保护变更日志  加锁顺序为先 mu 后 logMu
+	events   []*message    //保留的变更，events[i].seq == first+i
+	seq      uint64        //最后一个变更的序号
+	wake     chan struct{} //有新变更时关闭并替换，用于唤醒发送协程
+	updating bool          //Update 执行中，由 Update 结束时统一唤醒
+	err      error         //编码失败后变更日志不再完整，停止复制
+	observer int
+	conns    map[net.Conn]struct{}
+	closed   bool
+	done     chan struct{}
+}
+
+// 创建主节点  sl 中已有的数据会在从节点首次连接时通过快照同步
+func NewLeader(sl *skiplist.SkipList, options ...Option) (*Leader, error) {
+	if sl == nil {
+		return nil, listErr
+	}
+	c, err := newConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	l := &Leader{
+		sl:      sl,
+		codec:   c.codec,
+		logSize: c.logSize,
+		wake:    make(chan struct{}),
+		conns:   map[net.Conn]struct{}{},
+		done:    make(chan struct{}),
+	}
+	l.observer = sl.AddObserver(skiplist.Observer{
+		OnInsert: func(key, data interface{}, rank int) {
+			l.record(msgInsert, rank, key, data, true)
+		},
+		OnDelete: func(key, data interface{}, rank int) {
+			l.record(msgDelete, rank, key, nil, false)
+		},
+		OnUpdate: func(key, oldData, data interface{}, rank int) {
+			l.record(msgUpdate, rank, key, data, true)
+		},
+	})
+	return l, nil
+}
+
+// 记录一次变更  由观察者回调，可能在 Update 之外
+func (l *Leader) record(typ msgType, rank int, key, data interface{}, withData bool) {
+	l.logMu.Lock()
+	defer l.logMu.Unlock()
+	if l.err != nil {
+		return
+	}
+	m := &message{typ: typ, seq: l.seq + 1, rank: rank}
+	if m.key, l.err = l.codec.Encode(key); l.err != nil {
+		return
+	}
+	if withData {
+		if m.data, l.err = l.codec.Encode(data); l.err != nil {
+			return
+		}
+	}
+	l.seq = m.seq
+	l.events = append(l.events, m)
+	if len(l.events) > l.logSize {
+		//整体搬移，避免底层数组无限增长
+		n := copy(l.events, l.events[len(l.events)-l.logSize:])
+		for k := n; k < len(l.events); k++ {
+			l.events[k] = nil
+		}
+		l.events = l.events[:n]
+	}
+	if !l.updating {
+		l.notify()
+	}
+}
+
+// 唤醒发送协程  需要持有 logMu
+func (l *Leader) notify() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+/*
+修改跳表
+fn 中对跳表的所有修改都会被记录并复制到从节点; 返回编码错误，发生错误后停止复制
+*/
+func (l *Leader) Update(fn func(sl *skiplist.SkipList)) (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return closedErr
+	}
+	l.logMu.Lock()
+	if l.err != nil {
+		l.logMu.Unlock()
+		return l.err
+	}
+	seq := l.seq
+	l.updating = true
+	l.logMu.Unlock()
+	//fn 发生 panic 时同样恢复通知，并把已记录的变更发送给从节点
+	defer func() {
+		l.logMu.Lock()
+		defer l.logMu.Unlock()
+		l.updating = false
+		if l.seq != seq {
+			l.notify()
+		}
+		if err == nil {
+			err = l.err
+		}
+	}()
+	fn(l.sl)
+	return nil
+}
+
+// 读取跳表  fn 执行期间不会有修改
+func (l *Leader) View(fn func(sl *skiplist.SkipList)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fn(l.sl)
+}
+
+// 最后一个变更的序号
+func (l *Leader) Seq() uint64 {
+	l.logMu.Lock()
+	defer l.logMu.Unlock()
+	return l.seq
+}
+
+// 第一个保留的变更序号  需要持有 logMu
+func (l *Leader) first() uint64 {
+	return l.seq - uint64(len(l.events)) + 1
+}
+
+// 接受连接并为每个从节点启动发送协程，监听关闭或 Close 后返回
+func (l *Leader) ServeListener(ln net.Listener) error {
+	go func() {
+		<-l.done
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-l.done:
+				return closedErr
+			default:
+				return err
+			}
+		}
+		go l.Serve(conn)
+	}
+}
+
+/*
+向一个从节点复制
+读取从节点的握手后，按需发送快照，然后持续发送增量变更直到连接断开或 Close; 返回时关闭连接
+*/
+func (l *Leader) Serve(conn net.Conn) error {
+	defer conn.Close()
+	if !l.register(conn) {
+		return closedErr
+	}
+	defer l.unregister(conn)
+	hello, err := readMessage(bufio.NewReader(conn))
+	if err != nil {
+		return err
+	}
+	if hello.typ != msgHello {
+		return handshakeErr
+	}
+	w := bufio.NewWriter(conn)
+	next, err := l.handshake(w, hello.seq)
+	if err != nil {
+		return err
+	}
+	for {
+		l.logMu.Lock()
+		if l.err != nil {
+			err = l.err
+			l.logMu.Unlock()
+			return err
+		}
+		if next < l.first() {
+			//发送速度跟不上变更，需要的记录已被淘汰，在当前连接上重新发送快照
+			l.logMu.Unlock()
+			if next, err = l.handshake(w, 0); err != nil {
+				return err
+			}
+			continue
+		}
+		//复制出来在锁外发送，日志在发送期间可能被整体搬移
+		start := next - l.first()
+		batch := append([]*message(nil), l.events[start:min(int(start)+maxBatch, len(l.events))]...)
+		wake := l.wake
+		l.logMu.Unlock()
+		for _, m := range batch {
+			if _, err = w.Write(m.marshal()); err != nil {
+				return err
+			}
+		}
+		if err = w.Flush(); err != nil {
+			return err
+		}
+		next += uint64(len(batch))
+		if len(batch) > 0 {
+			continue
+		}
+		select {
+		case <-wake:
+		case <-l.done:
+			return closedErr
+		}
+	}
+}
+
+/*
+处理握手  从节点需要的序号仍在日志中时直接增量同步，否则发送快照
+返回之后需要发送的第一个序号
+*/
+func (l *Leader) handshake(w *bufio.Writer, want uint64) (uint64, error) {
+	l.mu.Lock()
+	l.logMu.Lock()
+	if err := l.err; err != nil {
+		l.logMu.Unlock()
+		l.mu.Unlock()
+		return 0, err
+	}
+	if want != 0 && want >= l.first() && want <= l.seq+1 {
+		l.logMu.Unlock()
+		l.mu.Unlock()
+		return want, nil
+	}
+	seq := l.seq
+	l.logMu.Unlock()
+	//快照在锁内编码，发送在锁外进行，不阻塞写入
+	snapshot := []*message{{typ: msgSnapshot, seq: seq, rank: l.sl.GetLength()}}
+	it := skiplist.NewIterator(l.sl)
+	for it.Next(0); it.Valid(); it.Next(0) {
+		m := &message{typ: msgEntry}
+		var err error
+		if m.key, err = l.codec.Encode(it.Key()); err == nil {
+			m.data, err = l.codec.Encode(it.Data())
+		}
+		if err != nil {
+			l.mu.Unlock()
+			return 0, err
+		}
+		snapshot = append(snapshot, m)
+	}
+	next := seq + 1
+	l.mu.Unlock()
+	for _, m := range snapshot {
+		if _, err := w.Write(m.marshal()); err != nil {
+			return 0, err
+		}
+	}
+	return next, w.Flush()
+}
+
+func (l *Leader) register(conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	return true
+}
+
+func (l *Leader) unregister(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+}
+
+// 停止复制并断开所有从节点  跳表不再被观察，之后的修改不会被记录
+func (l *Leader) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return closedErr
+	}
+	l.closed = true
+	close(l.done)
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.sl.RemoveObserver(l.observer)
+	return nil
+}
@@ -0,0 +1,49 @@
+package replication
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/yytany/ds/internal/frame"
+)
+
+// 帧格式见 internal/frame，帧类型为 msgType
+
+// 消息类型
+type msgType byte
+
+const (
+	msgHello    msgType = iota + 1 //从节点握手  seq 为需要的下一个序号，0表示需要全量同步
+	msgSnapshot                    //全量同步开始  seq 为快照对应的序号，rank 为之后的条目数
+	msgEntry                       //快照条目
+	msgInsert                      //插入事件
+	msgDelete                      //删除事件
+	msgUpdate                      //更新事件
+)
+
+// 一条消息  key/data 为编码后的字节
+type message struct {
+	typ  msgType
+	seq  uint64
+	rank int
+	key  []byte
+	data []byte
+}
+
+// 编码消息(含头部)
+func (m *message) marshal() []byte {
+	f := frame.Frame{Type: byte(m.typ), Seq: m.seq, Rank: m.rank, Key: m.key, Data: m.data}
+	return f.Marshal()
+}
+
+// 读取一条消息
+func readMessage(r *bufio.Reader) (*message, error) {
+	f, err := frame.NewReader(r).Next()
+	if err != nil {
+		if errors.Is(err, frame.CorruptedErr) {
+			err = frameErr
+		}
+		return nil, err
+	}
+	return &message{typ: msgType(f.Type), seq: f.Seq, rank: f.Rank, key: f.Key, data: f.Data}, nil
+}
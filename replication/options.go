@@ -0,0 +1,60 @@
+package replication
+
+import "github.com/yytany/ds/wal"
+
+const (
+	defaultLogSize      = 1 << 16 //默认保留的变更记录数
+	defaultSnapshotSize = 1 << 24 //默认快照条目数上限
+)
+
+// 主从共用的参数
+type config struct {
+	codec        wal.Codec
+	logSize      int
+	snapshotSize int
+}
+
+type Option func(*config) error
+
+func newConfig(options []Option) (*config, error) {
+	c := &config{codec: wal.GobCodec{}, logSize: defaultLogSize, snapshotSize: defaultSnapshotSize}
+	for k := range options {
+		if err := options[k](c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// 设置 key/data 的序列化方式，默认为 wal.GobCodec，主从需要一致
+func WithCodec(codec wal.Codec) Option {
+	return func(c *config) error {
+		if codec == nil {
+			return codecErr
+		}
+		c.codec = codec
+		return nil
+	}
+}
+
+// 设置主节点内存中保留的变更记录数  从节点落后超过该数量时需要重新全量同步
+func WithLogSize(size int) Option {
+	return func(c *config) error {
+		if size < 1 {
+			return logSizeErr
+		}
+		c.logSize = size
+		return nil
+	}
+}
+
+// 设置从节点接受的快照条目数上限  快照头部声明的条目数超过上限时返回错误，避免按损坏或恶意的头部分配内存
+func WithSnapshotSize(size int) Option {
+	return func(c *config) error {
+		if size < 1 {
+			return snapshotSizeErr
+		}
+		c.snapshotSize = size
+		return nil
+	}
+}
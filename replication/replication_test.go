@@ -0,0 +1,290 @@
+package replication
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yytany/ds/skiplist"
+	"github.com/yytany/ds/wal"
+)
+
+func init() {
+	gob.Register(skiplist.CmpInstanceInt(0))
+}
+
+func newList(t *testing.T) *skiplist.SkipList {
+	var cmp *skiplist.CmpInstanceInt
+	sl, err := skiplist.New(cmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sl
+}
+
+// 按顺序导出跳表内容
+func dump(sl *skiplist.SkipList) []interface{} {
+	return sl.GetByRankRange(1, sl.GetLength())
+}
+
+// 在主节点上执行一组随机变更
+func mutate(t *testing.T, l *Leader, r *rand.Rand, count int) {
+	for i := 0; i < count; i++ {
+		err := l.Update(func(sl *skiplist.SkipList) {
+			key := skiplist.CmpInstanceInt(r.Intn(100))
+			switch r.Intn(6) {
+			case 0:
+				sl.DeleteBatchByKey(key)
+			case 1:
+				sl.UpdateBatchByKey(key, r.Int())
+			case 2:
+				sl.DeleteByRank(r.Intn(sl.GetLength() + 1))
+			default:
+				sl.Insert(key, r.Int())
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// 等待从节点追上主节点，并比较两边的数据
+func waitConverge(t *testing.T, l *Leader, f *Follower) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for f.Seq() != l.Seq() {
+		if time.Now().After(deadline) {
+			t.Fatalf("从节点没有追上: leader %d follower %d", l.Seq(), f.Seq())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var want, got []interface{}
+	l.View(func(sl *skiplist.SkipList) { want = dump(sl) })
+	f.View(func(sl *skiplist.SkipList) { got = dump(sl) })
+	if len(got) != len(want) {
+		t.Fatalf("长度不一致 got %d want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("第 %d 个数据不一致 got %v want %v", i+1, got[i], want[i])
+		}
+	}
+}
+
+// 连接到主节点并在后台运行从节点，返回运行结果通道
+func runFollower(t *testing.T, f *Follower, addr string) (net.Conn, chan error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- f.Run(conn) }()
+	return conn, done
+}
+
+func TestOptions(t *testing.T) {
+	if _, err := NewLeader(nil); err != listErr {
+		t.Fatalf("want listErr, got %v", err)
+	}
+	if _, err := NewLeader(newList(t), WithLogSize(0)); err != logSizeErr {
+		t.Fatalf("want logSizeErr, got %v", err)
+	}
+	if _, err := NewFollower(newList(t), WithCodec(nil)); err != codecErr {
+		t.Fatalf("want codecErr, got %v", err)
+	}
+}
+
+// TestLoopback 通过本地 TCP 复制: 快照同步、增量同步、断线后增量追赶、落后过多时重新全量同步
+func TestLoopback(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	leaderList := newList(t)
+	//复制开始前已有的数据通过快照同步
+	for i := 0; i < 200; i++ {
+		leaderList.Insert(skiplist.CmpInstanceInt(r.Intn(100)), i)
+	}
+	l, err := NewLeader(leaderList, WithLogSize(2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.ServeListener(ln)
+	defer l.Close()
+
+	f, _ := NewFollower(newList(t))
+	mutate(t, l, r, 100)
+	conn, done := runFollower(t, f, ln.Addr().String())
+	waitConverge(t, l, f)
+	mutate(t, l, r, 1000)
+	waitConverge(t, l, f)
+
+	//断线期间的变更仍在日志中，重连后增量追赶
+	conn.Close()
+	<-done
+	mutate(t, l, r, 300)
+	loads := f.loads
+	conn, done = runFollower(t, f, ln.Addr().String())
+	waitConverge(t, l, f)
+	if f.loads != loads {
+		t.Fatalf("重连后应当增量追赶, 全量同步 %d 次", f.loads)
+	}
+
+	//落后超过日志保留数时重新全量同步
+	conn.Close()
+	<-done
+	mutate(t, l, r, 3000)
+	conn, done = runFollower(t, f, ln.Addr().String())
+	waitConverge(t, l, f)
+	if f.loads != loads+1 {
+		t.Fatalf("落后过多时应当全量同步, 全量同步 %d 次", f.loads)
+	}
+
+	//主节点关闭后从节点返回
+	l.Close()
+	if err := <-done; err == nil {
+		t.Fatal("主节点关闭后从节点应当返回错误")
+	}
+	conn.Close()
+}
+
+// TestOutsideUpdate 在 Update 之外修改跳表时变更同样被记录并唤醒发送协程，与发送协程之间没有数据竞争
+func TestOutsideUpdate(t *testing.T) {
+	sl := newList(t)
+	l, _ := NewLeader(sl)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.ServeListener(ln)
+	defer l.Close()
+	f, _ := NewFollower(newList(t))
+	conn, done := runFollower(t, f, ln.Addr().String())
+	//快照发送完成后跳表只被当前协程修改
+	mutate(t, l, rand.New(rand.NewSource(1)), 1)
+	waitConverge(t, l, f)
+	for i := 0; i < 500; i++ {
+		sl.Insert(skiplist.CmpInstanceInt(i%50), i)
+	}
+	waitConverge(t, l, f)
+	conn.Close()
+	<-done
+}
+
+// TestUpdatePanic Update 中 fn 发生 panic 后，之后的变更仍然会复制到从节点
+func TestUpdatePanic(t *testing.T) {
+	sl := newList(t)
+	l, _ := NewLeader(sl)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go l.ServeListener(ln)
+	defer l.Close()
+	f, _ := NewFollower(newList(t))
+	conn, done := runFollower(t, f, ln.Addr().String())
+	mutate(t, l, rand.New(rand.NewSource(1)), 1)
+	waitConverge(t, l, f)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("want panic")
+			}
+		}()
+		l.Update(func(sl *skiplist.SkipList) {
+			sl.Insert(skiplist.CmpInstanceInt(1000), 1000)
+			panic("boom")
+		})
+	}()
+	waitConverge(t, l, f)
+	//Update 之外的修改不再被当作 Update 中的修改而延迟通知
+	for i := 0; i < 50; i++ {
+		sl.Insert(skiplist.CmpInstanceInt(i), i)
+	}
+	waitConverge(t, l, f)
+	mutate(t, l, rand.New(rand.NewSource(2)), 50)
+	waitConverge(t, l, f)
+	conn.Close()
+	<-done
+}
+
+// TestGap 序号不连续时从节点返回 gapErr
+func TestGap(t *testing.T) {
+	codec := wal.GobCodec{}
+	key, _ := codec.Encode(skiplist.CmpInstanceInt(1))
+	data, _ := codec.Encode(1)
+	leaderSide, followerSide := net.Pipe()
+	f, _ := NewFollower(newList(t))
+	done := make(chan error, 1)
+	go func() { done <- f.Run(followerSide) }()
+
+	hello, err := readMessage(bufio.NewReader(leaderSide))
+	if err != nil || hello.typ != msgHello || hello.seq != 0 {
+		t.Fatalf("握手错误 %+v %v", hello, err)
+	}
+	for _, m := range []*message{
+		{typ: msgSnapshot, seq: 10},
+		{typ: msgInsert, seq: 11, rank: 1, key: key, data: data},
+		{typ: msgInsert, seq: 13, rank: 2, key: key, data: data},
+	} {
+		leaderSide.Write(m.marshal())
+	}
+	if err := <-done; !errors.Is(err, gapErr) {
+		t.Fatalf("want gapErr, got %v", err)
+	}
+	if f.Seq() != 11 {
+		t.Fatalf("Seq = %d want 11", f.Seq())
+	}
+	leaderSide.Close()
+}
+
+// TestDiverged 插入后的排名与主节点不一致时从节点返回 divergedErr 并在下次连接时全量同步
+func TestDiverged(t *testing.T) {
+	codec := wal.GobCodec{}
+	key, _ := codec.Encode(skiplist.CmpInstanceInt(1))
+	data, _ := codec.Encode(1)
+	leaderSide, followerSide := net.Pipe()
+	f, _ := NewFollower(newList(t))
+	done := make(chan error, 1)
+	go func() { done <- f.Run(followerSide) }()
+	readMessage(bufio.NewReader(leaderSide))
+	for _, m := range []*message{
+		{typ: msgSnapshot, seq: 1},
+		{typ: msgInsert, seq: 2, rank: 5, key: key, data: data},
+	} {
+		leaderSide.Write(m.marshal())
+	}
+	if err := <-done; !errors.Is(err, divergedErr) {
+		t.Fatalf("want divergedErr, got %v", err)
+	}
+	if f.synced {
+		t.Fatal("分叉后需要重新全量同步")
+	}
+	leaderSide.Close()
+}
+
+// TestSnapshotRange 快照头部声明的条目数超过上限时从节点返回 snapshotErr，不按头部分配内存
+func TestSnapshotRange(t *testing.T) {
+	for _, rank := range []int{3, 1 << 40} {
+		leaderSide, followerSide := net.Pipe()
+		f, _ := NewFollower(newList(t), WithSnapshotSize(2))
+		done := make(chan error, 1)
+		go func() { done <- f.Run(followerSide) }()
+		readMessage(bufio.NewReader(leaderSide))
+		m := &message{typ: msgSnapshot, seq: 1, rank: rank}
+		leaderSide.Write(m.marshal())
+		if err := <-done; !errors.Is(err, snapshotErr) {
+			t.Fatalf("rank %d want snapshotErr, got %v", rank, err)
+		}
+		leaderSide.Close()
+	}
+	if _, err := NewFollower(newList(t), WithSnapshotSize(0)); !errors.Is(err, snapshotSizeErr) {
+		t.Fatalf("want snapshotSizeErr, got %v", err)
+	}
+}
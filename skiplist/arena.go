@@ -0,0 +1,93 @@
+package skiplist
+
+const defaultArenaSlabSize = 4096 //默认每块分配的结点数
+
+/*
+结点分配器
+结点与层数组从成块的连续内存中切分，减少小对象数量及GC扫描开销; 删除的结点按层数组容量放入空闲链表，
+插入时优先复用容量相同的结点，其次复用容量更大的结点 (确定性跳表中被降低层高或交换了层数组的结点)。
+开启后被删除的结点会被复用，迭代器、结点句柄等在删除之后不能继续使用
+*/
+type arena struct {
+	slabSize int
+	nodes    []skipListNode  //当前块中未分配的结点
+	levels   []levelNode     //当前块中未分配的层
+	free     []*skipListNode //按层数组容量的空闲链表，通过prev串联
+	stats    arenaStats
+}
+
+// 分配器统计
+type arenaStats struct {
+	slabs  int //已分配的块数
+	reused int //复用的结点数
+	freed  int //当前空闲的结点数
+}
+
+func newArena(slabSize int) *arena {
+	return &arena{slabSize: slabSize}
+}
+
+// 分配层高为height的结点  复用容量更大的结点时只使用前height层
+func (a *arena) alloc(height int) *skipListNode {
+	for size := height; size < len(a.free); size++ {
+		if node := a.free[size]; node != nil {
+			a.free[size] = node.prev
+			node.prev = nil
+			node.level = node.level[:height]
+			a.stats.reused++
+			a.stats.freed--
+			return node
+		}
+	}
+	if len(a.nodes) == 0 {
+		a.nodes = make([]skipListNode, a.slabSize)
+		a.stats.slabs++
+	}
+	if len(a.levels) < height {
+		//层高期望值为 1/(1-p)，按每个结点两层分配
+		size := a.slabSize * 2
+		if size < height {
+			size = height
+		}
+		a.levels = make([]levelNode, size)
+	}
+	node := &a.nodes[0]
+	a.nodes = a.nodes[1:]
+	node.level = a.levels[:height:height]
+	a.levels = a.levels[height:]
+	return node
+}
+
+// 回收结点  清除结点中的引用，避免被删除的数据无法被GC回收
+func (a *arena) release(node *skipListNode) {
+	size := cap(node.level)
+	for size >= len(a.free) {
+		a.free = append(a.free, nil)
+	}
+	node.key, node.data = nil, nil
+	node.level = node.level[:size]
+	clear(node.level)
+	node.prev = a.free[size]
+	a.free[size] = node
+	a.stats.freed++
+}
+
+// 回收已从跳表中删除的结点  未开启分配器时不做处理
+func (sl *SkipList) freeNode(node *skipListNode) {
+	if sl.arena != nil {
+		sl.arena.release(node)
+	}
+}
+
+// 获取复用的查找路径  只能用于不保留路径的单次操作
+func (sl *SkipList) scratchPath() *searchPath {
+	if sl.path == nil {
+		sl.path = sl.newSearchPath()
+		return sl.path
+	}
+	//查找只会使用当前最大层数以下的层，更高的层在插入时重置
+	for level := 0; level <= sl.currentMaxLevel; level++ {
+		sl.path.prev[level], sl.path.rank[level] = sl.head, 0
+	}
+	return sl.path
+}
@@ -0,0 +1,128 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test_Arena 开启分配器后结果与普通跳表一致，删除的结点被复用
+func Test_Arena(t *testing.T) {
+	var cmp *CmpInstanceInt
+	if _, err := New(cmp, WithArena(-1)); err != slabSizeErr {
+		t.Fatalf("want slabSizeErr, got %v", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	sl, _ := New(cmp, WithArena(64))
+	want, _ := New(cmp)
+	for i := 0; i < 20000; i++ {
+		key := CmpInstanceInt(r.Intn(1000))
+		switch r.Intn(4) {
+		case 0:
+			if sl.DeleteBatchByKey(key) != want.DeleteBatchByKey(key) {
+				t.Fatalf("删除 %d 结果不一致", key)
+			}
+		case 1:
+			rk := r.Intn(want.GetLength() + 1)
+			if sl.DeleteByRank(rk) != want.DeleteByRank(rk) {
+				t.Fatalf("删除 rank %d 结果不一致", rk)
+			}
+		default:
+			rk, _ := sl.Insert(key, i)
+			if wrk, _ := want.Insert(key, i); rk != wrk {
+				t.Fatalf("插入 %d rank %d want %d", key, rk, wrk)
+			}
+		}
+	}
+	checkStructure(t, sl)
+	if got, exp := sl.GetByRankRange(1, sl.GetLength()), want.GetByRankRange(1, want.GetLength()); !equalSlice(got, exp) {
+		t.Fatal("结果不一致")
+	}
+	s := sl.Stats()
+	if !s.Arena || s.ArenaReused == 0 || s.ArenaSlabs == 0 {
+		t.Fatalf("分配器统计错误 %+v", s)
+	}
+	//块数应接近结点峰值/块大小，而不是插入总数/块大小
+	if s.ArenaSlabs*64 > 2*(sl.GetLength()+s.ArenaFree) {
+		t.Fatalf("结点没有被复用: slabs %d length %d free %d", s.ArenaSlabs, sl.GetLength(), s.ArenaFree)
+	}
+}
+
+// Test_ArenaRelease 回收的结点不再引用数据
+func Test_ArenaRelease(t *testing.T) {
+	var cmp *CmpInstanceInt
+	m, _ := NewOrderedMap(cmp, WithArena(8), WithLevelCacheSize(2, 1, 1))
+	m.Set(CmpInstanceInt(1), "a")
+	node := m.sl.head.level[0].next
+	if v, ok := m.Delete(CmpInstanceInt(1)); !ok || v != "a" {
+		t.Fatalf("Delete = %v, %v", v, ok)
+	}
+	if node.key != nil || node.data != nil || node.level[0].next != nil {
+		t.Fatal("回收的结点仍然引用数据")
+	}
+	m.Set(CmpInstanceInt(2), "b")
+	if m.sl.head.level[0].next != node {
+		t.Fatal("相同层高的结点应当被复用")
+	}
+}
+
+// Test_ArenaDeterministic 确定性跳表只分配层高为1的结点，层数组容量更大的空闲结点同样被复用
+func Test_ArenaDeterministic(t *testing.T) {
+	a := newArena(4)
+	tall := a.alloc(3)
+	a.release(tall)
+	if node := a.alloc(1); node != tall || len(node.level) != 1 || a.stats.freed != 0 {
+		t.Fatal("层高为1的结点应当复用容量更大的空闲结点")
+	}
+	if node := a.alloc(2); node == tall {
+		t.Fatal("已分配的结点不能被再次复用")
+	}
+	a.release(tall)
+	if node := a.alloc(3); node != tall || len(node.level) != 3 {
+		t.Fatal("按原层高复用时应当恢复全部层")
+	}
+
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(3))
+	sl, _ := New(cmp, WithDeterministic(true), WithArena(64))
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 1000; i++ {
+			sl.Insert(CmpInstanceInt(i), i)
+		}
+		checkGaps(t, sl)
+		for sl.GetLength() > 0 {
+			sl.DeleteByRank(r.Intn(sl.GetLength()) + 1)
+		}
+	}
+	s := sl.Stats()
+	if s.ArenaReused != 4000 || s.ArenaFree != 1000 {
+		t.Fatalf("结点没有被全部复用: reused %d free %d", s.ArenaReused, s.ArenaFree)
+	}
+}
+
+// 插入后删除再插入，对比分配次数
+func Benchmark_Alloc(b *testing.B) {
+	var cmp *CmpInstanceInt
+	for _, c := range []struct {
+		name    string
+		options []Option
+	}{
+		{"heap", nil},
+		{"arena", []Option{WithArena(0)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			sl, _ := New(cmp, c.options...)
+			r := rand.New(rand.NewSource(1))
+			const size = 1 << 14
+			for i := 0; i < size; i++ {
+				sl.Insert(CmpInstanceInt(r.Intn(size*4)), i)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := CmpInstanceInt(r.Intn(size * 4))
+				sl.Insert(key, nil)
+				sl.DeleteByRank(r.Intn(sl.GetLength()) + 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package skiplist
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	batchKeyExistsErr   = errors.New("key already exists")
+	batchKeyNotFoundErr = errors.New("key not found")
+)
+
+// 批量操作类型
+type batchOpType int
+
+const (
+	batchInsert batchOpType = iota + 1
+	batchDelete
+	batchUpdate
+)
+
+type batchOp struct {
+	typ   batchOpType
+	key   interface{}
+	data  interface{}
+	index int //添加顺序
+}
+
+/*
+批量操作
+记录 Insert/Delete/Update，通过 SkipList.Apply 一次性全部生效或全部不生效
+*/
+type Batch struct {
+	ops []batchOp
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// 插入数据  不允许重复key时，key已存在会导致整个批量操作失败
+func (b *Batch) Insert(key, data interface{}) {
+	b.ops = append(b.ops, batchOp{typ: batchInsert, key: key, data: data, index: len(b.ops)})
+}
+
+// 删除所有和key相同的数据  key不存在会导致整个批量操作失败
+func (b *Batch) Delete(key interface{}) {
+	b.ops = append(b.ops, batchOp{typ: batchDelete, key: key, index: len(b.ops)})
+}
+
+// 更新所有和key相同的数据  key不存在会导致整个批量操作失败
+func (b *Batch) Update(key, data interface{}) {
+	b.ops = append(b.ops, batchOp{typ: batchUpdate, key: key, data: data, index: len(b.ops)})
+}
+
+// 操作数量
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// 清空操作，便于复用
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// 回滚记录
+type undoOp struct {
+	typ   batchOpType
+	key   interface{}
+	nodes []*skipListNode //插入、删除的结点
+	datas []interface{}   //更新前的数据
+}
+
+/*
+原子地执行批量操作
+操作按key排序后执行 (相同key的操作保持添加顺序)，相邻操作复用查找路径，不必每次从头结点开始查找。
+执行前先校验: 不允许重复key时插入已存在的key、删除或更新不存在的key都会返回错误且不做任何修改;
+执行中出现异常 (如权重函数 panic) 时回滚已执行的操作。
+与其他方法相同，Apply 不是并发安全的，需要并发读写时由调用侧加锁。
+变更通知先缓存，全部成功之后按执行顺序发送; 失败时不发送任何通知
+*/
+func (sl *SkipList) Apply(b *Batch) error {
+	if b == nil || len(b.ops) == 0 {
+		return nil
+	}
+	events, err := sl.applyBatch(b)
+	for k := range events {
+		sl.dispatch(events[k])
+	}
+	return err
+}
+
+// 执行批量操作，返回成功时缓存的变更通知
+func (sl *SkipList) applyBatch(b *Batch) (events []Event, err error) {
+	ops := make([]batchOp, len(b.ops))
+	copy(ops, b.ops)
+	sort.SliceStable(ops, func(i, j int) bool {
+		return sl.compare(ops[i].key, ops[j].key) < 0
+	})
+	if err = sl.validateBatch(ops); err != nil {
+		return nil, err
+	}
+
+	undo := make([]undoOp, 0, len(ops))
+	sl.deferEvents = true
+	defer func() {
+		if r := recover(); r != nil {
+			sl.rollback(undo)
+			err = fmt.Errorf("skiplist: apply batch: %v", r)
+		}
+		if err == nil {
+			events = sl.pending
+		}
+		sl.deferEvents, sl.pending = false, nil
+	}()
+	path := sl.newSearchPath()
+	for k := range ops {
+		op := &ops[k]
+		//path 为最后一个小于key的路径，各操作都从这里开始
+		sl.seekPath(path, op.key, false)
+		//先记录回滚信息再修改，保证修改过程中出现异常时也能回滚
+		switch op.typ {
+		case batchInsert:
+			insertPath := path.clone()
+			sl.seekPath(insertPath, op.key, true)
+			node := sl.nodeGenerate(op.key, op.data)
+			//linkNode 在修改结构之前计算权重，出现异常时结点不会被插入，插入后再记录
+			sl.linkNode(insertPath, node)
+			undo = append(undo, undoOp{typ: batchInsert, key: op.key, nodes: []*skipListNode{node}})
+		case batchDelete:
+			undo = append(undo, undoOp{typ: batchDelete, key: op.key})
+			u := &undo[len(undo)-1]
+			for next := path.prev[0].level[0].next; next != nil && sl.equals(next.key, op.key); next = path.prev[0].level[0].next {
+				u.nodes = append(u.nodes, next)
+				sl.unlinkNext(path)
+			}
+		case batchUpdate:
+			undo = append(undo, undoOp{typ: batchUpdate, key: op.key})
+			u := &undo[len(undo)-1]
+			for node := path.prev[0].level[0].next; node != nil && sl.equals(node.key, op.key); node = node.level[0].next {
+				u.nodes = append(u.nodes, node)
+				u.datas = append(u.datas, node.data)
+				sl.updateByNode(node, op.data)
+			}
+		}
+	}
+	//全部成功后才回收删除的结点，回滚时需要原样插回
+	for _, u := range undo {
+		if u.typ == batchDelete {
+			for _, node := range u.nodes {
+				sl.freeNode(node)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// 按key分组模拟执行，校验操作是否都能成功
+func (sl *SkipList) validateBatch(ops []batchOp) error {
+	path := sl.newSearchPath()
+	for start := 0; start < len(ops); {
+		key := ops[start].key
+		sl.seekPath(path, key, false)
+		count := 0 //当前相同key的结点数
+		for node := path.prev[0].level[0].next; node != nil && sl.equals(node.key, key); node = node.level[0].next {
+			count++
+		}
+		end := start
+		for ; end < len(ops) && sl.equals(ops[end].key, key); end++ {
+			switch ops[end].typ {
+			case batchInsert:
+				if !sl.allowSameKey && count > 0 {
+					return fmt.Errorf("skiplist: batch op %d: %w", ops[end].index, batchKeyExistsErr)
+				}
+				count++
+			case batchDelete:
+				if count == 0 {
+					return fmt.Errorf("skiplist: batch op %d: %w", ops[end].index, batchKeyNotFoundErr)
+				}
+				count = 0
+			case batchUpdate:
+				if count == 0 {
+					return fmt.Errorf("skiplist: batch op %d: %w", ops[end].index, batchKeyNotFoundErr)
+				}
+			}
+		}
+		start = end
+	}
+	return nil
+}
+
+// 逆序撤销已执行的操作  删除的结点原样重新插入，保证结点及相同key的顺序不变
+func (sl *SkipList) rollback(undo []undoOp) {
+	for k := len(undo) - 1; k >= 0; k-- {
+		u := undo[k]
+		switch u.typ {
+		case batchInsert:
+			path := sl.newSearchPath()
+			sl.seekRankPath(path, sl.rankOfNode(u.nodes[0]))
+			sl.freeNode(sl.unlinkNext(path))
+		case batchDelete:
+			path := sl.newSearchPath()
+			sl.seekPath(path, u.key, true)
+			for _, node := range u.nodes {
+				sl.linkNode(path, node)
+			}
+		case batchUpdate:
+			for i := len(u.nodes) - 1; i >= 0; i-- {
+				sl.updateByNode(u.nodes[i], u.datas[i])
+			}
+		}
+	}
+}
+
+// 复制查找路径
+func (path *searchPath) clone() *searchPath {
+	c := &searchPath{
+		prev: make([]*skipListNode, len(path.prev)),
+		rank: make([]int, len(path.rank)),
+	}
+	copy(c.prev, path.prev)
+	copy(c.rank, path.rank)
+	return c
+}
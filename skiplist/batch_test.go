@@ -0,0 +1,185 @@
+package skiplist
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// Test_BatchApply 批量执行的结果与逐条执行 (按key排序后) 一致
+func Test_BatchApply(t *testing.T) {
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		sl, _ := New(cmp)
+		want, _ := New(cmp)
+		for i := 0; i < 200; i++ {
+			key := CmpInstanceInt(r.Intn(100))
+			sl.Insert(key, i)
+			want.Insert(key, i)
+		}
+		b := NewBatch()
+		exists := map[CmpInstanceInt]bool{}
+		for i := 0; i < 100; i++ {
+			exists[CmpInstanceInt(i)] = want.GetRandByKey(CmpInstanceInt(i)) != nil
+		}
+		var ops []batchOp
+		for i := 0; i < 60; i++ {
+			key := CmpInstanceInt(r.Intn(100))
+			switch {
+			case !exists[key] || r.Intn(3) == 0:
+				b.Insert(key, 1000+i)
+				ops = append(ops, batchOp{typ: batchInsert, key: key, data: 1000 + i})
+				exists[key] = true
+			case r.Intn(2) == 0:
+				b.Delete(key)
+				ops = append(ops, batchOp{typ: batchDelete, key: key})
+				exists[key] = false
+			default:
+				b.Update(key, 2000+i)
+				ops = append(ops, batchOp{typ: batchUpdate, key: key, data: 2000 + i})
+			}
+		}
+		//同一个key的操作保持顺序，不同key的操作互不影响，逐条执行即可得到期望结果
+		for _, op := range ops {
+			switch op.typ {
+			case batchInsert:
+				want.Insert(op.key, op.data)
+			case batchDelete:
+				want.DeleteBatchByKey(op.key)
+			case batchUpdate:
+				want.UpdateBatchByKey(op.key, op.data)
+			}
+		}
+		if err := sl.Apply(b); err != nil {
+			t.Fatal(err)
+		}
+		checkStructure(t, sl)
+		if got, exp := sl.GetByRankRange(1, sl.GetLength()), want.GetByRankRange(1, want.GetLength()); !equalSlice(got, exp) {
+			t.Fatalf("round %d 结果不一致\n got: %v\nwant: %v", round, got, exp)
+		}
+	}
+}
+
+// Test_BatchValidate 校验失败时不做任何修改
+func Test_BatchValidate(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, WithAllowTheSameKey(false))
+	sl.Insert(CmpInstanceInt(1), 1)
+	sl.Insert(CmpInstanceInt(2), 2)
+
+	cases := []struct {
+		build func(b *Batch)
+		err   error
+	}{
+		{func(b *Batch) { b.Insert(CmpInstanceInt(3), 3); b.Insert(CmpInstanceInt(1), 11) }, batchKeyExistsErr},
+		{func(b *Batch) { b.Insert(CmpInstanceInt(3), 3); b.Insert(CmpInstanceInt(3), 33) }, batchKeyExistsErr},
+		{func(b *Batch) { b.Delete(CmpInstanceInt(1)); b.Update(CmpInstanceInt(1), 11) }, batchKeyNotFoundErr},
+		{func(b *Batch) { b.Insert(CmpInstanceInt(0), 0); b.Delete(CmpInstanceInt(5)) }, batchKeyNotFoundErr},
+	}
+	for k, c := range cases {
+		b := NewBatch()
+		c.build(b)
+		if err := sl.Apply(b); !errors.Is(err, c.err) {
+			t.Fatalf("case %d: err = %v, want %v", k, err, c.err)
+		}
+		if got := sl.GetByRankRange(1, 10); !equalSlice(got, []interface{}{1, 2}) {
+			t.Fatalf("case %d: 校验失败后数据被修改: %v", k, got)
+		}
+	}
+
+	//删除后重新插入是允许的
+	b := NewBatch()
+	b.Delete(CmpInstanceInt(1))
+	b.Insert(CmpInstanceInt(1), 111)
+	b.Update(CmpInstanceInt(2), 22)
+	if err := sl.Apply(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := sl.GetByRankRange(1, 10); !equalSlice(got, []interface{}{111, 22}) {
+		t.Fatalf("结果错误: %v", got)
+	}
+}
+
+// Test_BatchRollback 执行中出现异常时回滚，且不发送变更通知
+func Test_BatchRollback(t *testing.T) {
+	var cmp *CmpInstanceInt
+	weight := func(data interface{}) float64 {
+		if data == "boom" {
+			panic("weight failed")
+		}
+		return 1
+	}
+	for _, boom := range []string{"insert", "update"} {
+		sl, _ := New(cmp, WithWeight(weight))
+		for i := 0; i < 50; i++ {
+			sl.Insert(CmpInstanceInt(i%10), i)
+		}
+		before := sl.GetByRankRange(1, sl.GetLength())
+		events := 0
+		sl.AddObserver(Observer{
+			OnInsert: func(key, data interface{}, rank int) { events++ },
+			OnDelete: func(key, data interface{}, rank int) { events++ },
+			OnUpdate: func(key, oldData, data interface{}, rank int) { events++ },
+		})
+		b := NewBatch()
+		b.Delete(CmpInstanceInt(3))
+		b.Update(CmpInstanceInt(5), "x")
+		b.Insert(CmpInstanceInt(4), "y")
+		b.Delete(CmpInstanceInt(7))
+		b.Insert(CmpInstanceInt(7), "z")
+		if boom == "insert" {
+			b.Insert(CmpInstanceInt(8), "boom")
+		} else {
+			b.Update(CmpInstanceInt(8), "boom")
+		}
+		b.Delete(CmpInstanceInt(9))
+		if err := sl.Apply(b); err == nil {
+			t.Fatal("应返回错误")
+		}
+		checkStructure(t, sl)
+		checkWeights(t, sl)
+		if after := sl.GetByRankRange(1, sl.GetLength()); !equalSlice(after, before) {
+			t.Fatalf("%s: 回滚后数据不一致\n got: %v\nwant: %v", boom, after, before)
+		}
+		if events != 0 {
+			t.Fatalf("%s: 失败时不应发送变更通知, got: %d", boom, events)
+		}
+	}
+}
+
+// Test_BatchNotify 全部成功后才发送变更通知，通知中可以读取跳表
+func Test_BatchNotify(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	for i := 0; i < 10; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	var types []EventType
+	sl.AddObserver(Observer{OnInsert: func(key, data interface{}, rank int) {
+		if sl.GetLength() != 10 {
+			t.Errorf("通知时应已全部生效, length: %d", sl.GetLength())
+		}
+		types = append(types, EventInsert)
+	}, OnDelete: func(key, data interface{}, rank int) {
+		types = append(types, EventDelete)
+	}})
+	b := NewBatch()
+	b.Delete(CmpInstanceInt(2))
+	b.Insert(CmpInstanceInt(20), 20)
+	b.Delete(CmpInstanceInt(5))
+	b.Insert(CmpInstanceInt(50), 50)
+	if err := sl.Apply(b); err != nil {
+		t.Fatal(err)
+	}
+	//按key顺序执行: 删除2、删除5、插入20、插入50
+	want := []EventType{EventDelete, EventDelete, EventInsert, EventInsert}
+	if len(types) != len(want) {
+		t.Fatalf("通知数量错误: %v", types)
+	}
+	for k := range want {
+		if types[k] != want[k] {
+			t.Fatalf("通知顺序错误: %v", types)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package skiplist
+
+import (
+	"bytes"
+	"cmp"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+比较器组合
+以下函数均返回 CompareAble，可直接传给 New，例如 CmpInstanceStruct 的比较逻辑可以写成:
+
+	Then(
+		By(func(s Order) float64 { return s.price }, Ordered[float64]()),
+		Reverse(By(func(s Order) int64 { return s.createTime }, Ordered[int64]())),
+	)
+
+跳表内部按 -1/0/1 判断大小，组合器会把结果统一为这三个值
+*/
+
+// 函数形式的比较接口
+type CompareFunc func(a, b interface{}) int
+
+func (f CompareFunc) Compare(a, b interface{}) int {
+	return f(a, b)
+}
+
+// 统一为 -1/0/1
+func sign(c int) int {
+	if c < 0 {
+		return -1
+	} else if c > 0 {
+		return 1
+	}
+	return 0
+}
+
+// 可排序类型的升序比较  key 的类型必须为 T
+func Ordered[T cmp.Ordered]() CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return cmp.Compare(a.(T), b.(T))
+	})
+}
+
+// 反转比较结果，升序变为降序
+func Reverse(c CompareAble) CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return -sign(c.Compare(a, b))
+	})
+}
+
+// 依次比较，前一个比较相等时才使用下一个
+func Then(cs ...CompareAble) CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		for k := range cs {
+			if r := sign(cs[k].Compare(a, b)); r != 0 {
+				return r
+			}
+		}
+		return 0
+	})
+}
+
+// 先通过 extract 取出字段，再使用 c 比较字段
+func By[T, K any](extract func(T) K, c CompareAble) CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return sign(c.Compare(extract(a.(T)), extract(b.(T))))
+	})
+}
+
+// nil 排在最前面，两个都不为 nil 时使用 c 比较
+func NilsFirst(c CompareAble) CompareAble {
+	return nils(c, -1)
+}
+
+// nil 排在最后面，两个都不为 nil 时使用 c 比较
+func NilsLast(c CompareAble) CompareAble {
+	return nils(c, 1)
+}
+
+func nils(c CompareAble, nilOrder int) CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		aNil, bNil := isNil(a), isNil(b)
+		if aNil && bNil {
+			return 0
+		} else if aNil {
+			return nilOrder
+		} else if bNil {
+			return -nilOrder
+		}
+		return sign(c.Compare(a, b))
+	})
+}
+
+// 是否为 nil  (包含值为 nil 的指针、切片、map 等)
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// 字节切片按字典序比较  key 的类型必须为 []byte
+func Bytes() CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return bytes.Compare(a.([]byte), b.([]byte))
+	})
+}
+
+// 字符串忽略大小写比较  key 的类型必须为 string
+func StringFold() CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return compareFold(a.(string), b.(string))
+	})
+}
+
+func compareFold(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra, rb = unicode.ToLower(ra), unicode.ToLower(rb); ra != rb {
+			return cmp.Compare(ra, rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+/*
+字符串自然序比较  连续的数字按数值大小比较，例如 "item2" < "item10"
+数值相等时前导零少的排前面; key 的类型必须为 string
+*/
+func StringNatural() CompareAble {
+	return CompareFunc(func(a, b interface{}) int {
+		return compareNatural(a.(string), b.(string))
+	})
+}
+
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digitPrefix(a), digitPrefix(b)
+			ta, tb := trimZero(da), trimZero(db)
+			//去掉前导零后，位数多的数值大，位数相同时按字典序
+			if r := cmp.Compare(len(ta), len(tb)); r != 0 {
+				return r
+			}
+			if r := cmp.Compare(ta, tb); r != 0 {
+				return r
+			}
+			if r := cmp.Compare(len(da), len(db)); r != 0 {
+				return r
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return cmp.Compare(a[0], b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+func trimZero(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package skiplist
+
+import (
+	"sort"
+	"testing"
+)
+
+type order struct {
+	price      float64
+	createTime int64
+	user       *string
+}
+
+// Test_ComparatorCombine 组合出与 CmpInstanceStruct 相同的比较逻辑
+func Test_ComparatorCombine(t *testing.T) {
+	combined := Then(
+		By(func(s CmpInstanceStruct) float64 { return s.price }, Ordered[float64]()),
+		By(func(s CmpInstanceStruct) int64 { return s.createTime }, Ordered[int64]()),
+	)
+	manual := &CmpInstanceStruct{}
+	values := []CmpInstanceStruct{{1, 1}, {1, 2}, {2, 1}, {0.5, 9}, {2, 1}}
+	for _, a := range values {
+		for _, b := range values {
+			if combined.Compare(a, b) != manual.Compare(a, b) {
+				t.Fatalf("Compare(%v, %v) 结果不一致", a, b)
+			}
+		}
+	}
+
+	//价格升序、时间降序
+	sl, _ := New(Then(
+		By(func(o order) float64 { return o.price }, Ordered[float64]()),
+		Reverse(By(func(o order) int64 { return o.createTime }, Ordered[int64]())),
+	))
+	sl.Insert(order{price: 2, createTime: 1}, "a")
+	sl.Insert(order{price: 1, createTime: 1}, "b")
+	sl.Insert(order{price: 1, createTime: 3}, "c")
+	sl.Insert(order{price: 2, createTime: 5}, "d")
+	if got := sl.GetByRankRange(1, 4); !equalSlice(got, []interface{}{"c", "b", "d", "a"}) {
+		t.Fatalf("排序结果错误: %v", got)
+	}
+}
+
+// Test_ComparatorNils 测试 nil 的排序位置
+func Test_ComparatorNils(t *testing.T) {
+	name := func(s string) *string { return &s }
+	byUser := func(o order) *string { return o.user }
+	deref := CompareFunc(func(a, b interface{}) int {
+		return Ordered[string]().Compare(*a.(*string), *b.(*string))
+	})
+	first := By(byUser, NilsFirst(deref))
+	last := By(byUser, NilsLast(deref))
+	a, b, n := order{user: name("a")}, order{user: name("b")}, order{}
+	cases := []struct {
+		c    CompareAble
+		x, y order
+		want int
+	}{
+		{first, n, a, -1}, {first, a, n, 1}, {first, n, n, 0}, {first, a, b, -1},
+		{last, n, a, 1}, {last, a, n, -1}, {last, n, n, 0}, {last, b, a, 1},
+	}
+	for k, c := range cases {
+		if got := c.c.Compare(c.x, c.y); got != c.want {
+			t.Fatalf("case %d: got %d want %d", k, got, c.want)
+		}
+	}
+	if NilsFirst(Ordered[int]()).Compare(nil, 1) != -1 {
+		t.Fatal("interface nil 应排在前面")
+	}
+}
+
+// Test_ComparatorString 测试字符串与字节切片比较
+func Test_ComparatorString(t *testing.T) {
+	sortBy := func(c CompareAble, values []string) []string {
+		sort.SliceStable(values, func(i, j int) bool { return c.Compare(values[i], values[j]) < 0 })
+		return values
+	}
+	natural := sortBy(StringNatural(), []string{"item10", "item2", "item02", "item1", "item", "a10b2", "a10b10", "a9"})
+	want := []string{"a9", "a10b2", "a10b10", "item", "item1", "item2", "item02", "item10"}
+	for k := range want {
+		if natural[k] != want[k] {
+			t.Fatalf("自然序结果错误: %v", natural)
+		}
+	}
+
+	fold := StringFold()
+	if fold.Compare("Hello", "hello") != 0 || fold.Compare("ABC", "abd") != -1 || fold.Compare("Straße", "STRASSE") != 1 || fold.Compare("ab", "A") != 1 {
+		t.Fatal("忽略大小写比较错误")
+	}
+	//忽略大小写相等时再按原字符串比较
+	collate := Then(StringFold(), Ordered[string]())
+	if got := sortBy(collate, []string{"b", "a", "B", "A"}); got[0] != "A" || got[1] != "a" || got[2] != "B" {
+		t.Fatalf("排序结果错误: %v", got)
+	}
+
+	by := Bytes()
+	if by.Compare([]byte("ab"), []byte("abc")) != -1 || by.Compare([]byte{0xff}, []byte{0x01, 0x02}) != 1 || by.Compare([]byte{}, []byte(nil)) != 0 {
+		t.Fatal("字节切片比较错误")
+	}
+}
+
+func equalSlice(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package skiplist
+
+/*
+确定性跳表 (1-2-3 skip list, Munro, Papadakis, Sedgewick 1992)
+新结点的层高总是1，插入、删除后自底向上调整结点层高，维持:
+第h层每个结点 (包括头结点) 与它在第h层的下一个结点 (或表尾) 之间，恰有1~3个最高层为第h-1层的结点，称为间隔。
+每层结点数不超过下一层的一半，层数不超过 log2(n)+1，查找时每层最多前进3个结点，
+查找、插入、删除在最坏情况下都是 O(log n)。
+插入使间隔超过3个结点时，提升其中第3个结点将间隔一分为二; 删除使间隔为空时，降低相邻的一个结点与之合并，
+合并后超过3个结点时再拆分 (相当于从相邻间隔借一个结点)。拆分与合并可能逐层向上传递
+*/
+
+// 重置查找路径到头结点
+func (sl *SkipList) resetPath(path *searchPath) {
+	for level := range path.prev {
+		path.prev[level], path.rank[level] = sl.head, 0
+	}
+}
+
+// 将最高层为第h-1层的结点提升到第h层，插入到第h层的前置结点prev之后  dist 为两者的rank差
+func (sl *SkipList) raise(node, prev *skipListNode, h, dist int) {
+	node.level = append(node.level, levelNode{next: prev.level[h].next})
+	if node.level[h].next != nil {
+		node.level[h].span = prev.level[h].span - dist
+	}
+	prev.level[h] = levelNode{next: node, span: dist}
+	if sl.weigher != nil {
+		sl.weights[node] = append(sl.weights[node], 0)
+		sl.sumWeight(prev, h)
+		sl.sumWeight(node, h)
+	}
+	if h > sl.currentMaxLevel {
+		sl.currentMaxLevel = h
+	}
+}
+
+// 将最高层为第h层的结点降低一层  prev 为其在第h层的前置结点
+func (sl *SkipList) lower(node, prev *skipListNode, h int) {
+	next := node.level[h]
+	prev.level[h].next = next.next
+	prev.level[h].span += next.span
+	if next.next == nil {
+		prev.level[h].span = 0
+	}
+	node.level[h] = levelNode{}
+	node.level = node.level[:h]
+	if sl.weigher != nil {
+		sl.weights[node] = sl.weights[node][:h]
+		sl.sumWeight(prev, h)
+	}
+}
+
+// 第h层结点owner之后的间隔超过3个结点时，将第3个结点提升到第h层，返回是否提升
+func (sl *SkipList) splitGap(owner *skipListNode, h int) bool {
+	if h >= sl.constMaxLevel {
+		return false
+	}
+	end := owner.level[h].next
+	node, dist := owner, 0
+	var third *skipListNode
+	thirdDist := 0
+	for count := 1; count <= 4; count++ {
+		dist += node.level[h-1].span
+		if node = node.level[h-1].next; node == nil || node == end {
+			return false
+		}
+		if count == 3 {
+			third, thirdDist = node, dist
+		}
+	}
+	sl.raise(third, owner, h, thirdDist)
+	return true
+}
+
+// 插入层高为1的结点后自底向上拆分超过3个结点的间隔，并重新计算查找路径 (rank 为新结点的rank)
+func (sl *SkipList) balanceInsert(path *searchPath, rank int) {
+	top := sl.currentMaxLevel
+	for h := 1; h < sl.constMaxLevel; h++ {
+		owner := sl.head
+		if h <= top {
+			owner = path.prev[h]
+		}
+		if !sl.splitGap(owner, h) {
+			break
+		}
+	}
+	sl.resetPath(path)
+	sl.seekRankPath(path, rank+1)
+}
+
+/*
+删除路径第0层前置结点的下一个结点  (确定性跳表)
+被删除结点层高大于1时，先把它的各层转交给第0层的下一个结点 (维持间隔时该结点层高为1)，
+再按层高为1的结点摘除，然后自底向上合并为空的间隔，最后重新计算查找路径
+*/
+func (sl *SkipList) unlinkBalanced(path *searchPath) *skipListNode {
+	node := path.prev[0].level[0].next
+	rank0 := path.rank[0]
+	height := len(node.level)
+	var heir *skipListNode
+	if next := node.level[0].next; height > 1 && next != nil && len(next.level) == 1 {
+		heir = next
+		own := heir.level[0]
+		heir.level, node.level = node.level, heir.level
+		node.level[0] = heir.level[0]
+		heir.level[0] = own
+		if sl.weigher != nil {
+			hw, nw := sl.weights[heir], sl.weights[node]
+			hw[0], nw[0] = nw[0], hw[0]
+			sl.weights[heir], sl.weights[node] = nw, hw
+		}
+		for h := 1; h < height; h++ {
+			path.prev[h].level[h] = levelNode{next: heir, span: path.prev[h].level[h].span + 1}
+			if heir.level[h].next != nil {
+				heir.level[h].span--
+			}
+			if sl.weigher != nil {
+				sl.sumWeight(heir, h)
+			}
+		}
+	}
+	sl.unlink(path)
+	if heir != nil {
+		for h := 1; h < height; h++ {
+			path.prev[h] = heir
+		}
+	}
+	top := sl.currentMaxLevel
+	for h := 1; h <= top; h++ {
+		c := path.prev[h]
+		if c.level[h-1].next != c.level[h].next {
+			break
+		}
+		parent := sl.head
+		if h < top {
+			parent = path.prev[h+1]
+		}
+		if rs := c.level[h].next; rs != nil && len(rs.level) == h+1 {
+			//右侧相邻结点降低一层，其间隔并入c的间隔
+			sl.lower(rs, c, h)
+			if sl.splitGap(c, h) {
+				break
+			}
+		} else if c != sl.head && len(c.level) == h+1 {
+			//c 降低一层，并入左侧相邻结点的间隔
+			ls := parent
+			for ls.level[h].next != c {
+				ls = ls.level[h].next
+			}
+			sl.lower(c, ls, h)
+			if sl.splitGap(ls, h) {
+				break
+			}
+		} else {
+			break
+		}
+	}
+	sl.updateCurrentMaxLevel(top)
+	sl.resetPath(path)
+	sl.seekRankPath(path, rank0+1)
+	return node
+}
@@ -0,0 +1,168 @@
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// 校验确定性跳表的间隔: 每层每个结点之后都有1~3个最高层为下一层的结点，层数不超过 log2(n)+1
+func checkGaps(t *testing.T, sl *SkipList) {
+	t.Helper()
+	checkStructure(t, sl)
+	if sl.length == 0 {
+		return
+	}
+	for h := 1; h <= sl.currentMaxLevel+1; h++ {
+		for owner := sl.head; owner != nil; owner = owner.level[h].next {
+			end := owner.level[h].next
+			gap := 0
+			for node := owner.level[h-1].next; node != end; node = node.level[h-1].next {
+				if len(node.level) != h {
+					t.Fatalf("第 %d 层间隔中的结点层高为 %d", h, len(node.level))
+				}
+				gap++
+			}
+			if gap > 3 || (h <= sl.currentMaxLevel && gap < 1) {
+				t.Fatalf("第 %d 层的间隔为 %d 个结点", h, gap)
+			}
+			if h > sl.currentMaxLevel {
+				break
+			}
+		}
+	}
+	if bound := math.Log2(float64(sl.length)) + 1; float64(sl.currentMaxLevel+1) > bound {
+		t.Fatalf("%d 个结点的层数 %d 超过 %.1f", sl.length, sl.currentMaxLevel+1, bound)
+	}
+}
+
+func Test_Deterministic(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, WithDeterministic(true))
+	//升序插入是随机层数的最坏情况之一
+	for i := 0; i < 1000; i++ {
+		if rank, _ := sl.Insert(CmpInstanceInt(i), i); rank != i+1 {
+			t.Fatalf("插入 %d 的排位 %d", i, rank)
+		}
+	}
+	checkGaps(t, sl)
+	for i := 999; i >= 0; i -= 2 {
+		sl.DeleteByKey(CmpInstanceInt(i))
+	}
+	checkGaps(t, sl)
+	for sl.GetLength() > 0 {
+		sl.DeleteByRank(1)
+		checkGaps(t, sl)
+	}
+	if sl.currentMaxLevel != 0 {
+		t.Fatalf("清空后层数 %d", sl.currentMaxLevel)
+	}
+
+	//WithMaxLevel 不限制确定性跳表的层数，最高层的间隔仍然不超过3个结点
+	sl, _ = New(cmp, WithMaxLevel(2), WithDeterministic(true))
+	for i := 0; i < 1000; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	checkGaps(t, sl)
+	if sl.currentMaxLevel < 2 {
+		t.Fatalf("1000 个结点的层数 %d", sl.currentMaxLevel+1)
+	}
+}
+
+// Test_DeterministicRandom 随机插入、删除，与有序切片的结果对比并校验间隔
+func Test_DeterministicRandom(t *testing.T) {
+	type entry struct {
+		key  int
+		data int
+	}
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(5))
+	for _, options := range [][]Option{
+		{WithDeterministic(true)},
+		{WithDeterministic(true), WithArena(16), WithMaxLevel(6)},
+		{WithDeterministic(true), WithFingerSearch(true)},
+	} {
+		sl, _ := New(cmp, options...)
+		var want []entry
+		bounds := func(key int) (int, int) {
+			lo := sort.Search(len(want), func(j int) bool { return want[j].key >= key })
+			hi := sort.Search(len(want), func(j int) bool { return want[j].key > key })
+			return lo, hi
+		}
+		for i := 0; i < 6000; i++ {
+			key := r.Intn(300)
+			switch r.Intn(7) {
+			case 0, 1, 2:
+				rank, _ := sl.Insert(CmpInstanceInt(key), i)
+				_, pos := bounds(key)
+				want = append(want[:pos], append([]entry{{key, i}}, want[pos:]...)...)
+				if rank != pos+1 {
+					t.Fatalf("插入 %d 的排位 %d != %d", key, rank, pos+1)
+				}
+			case 3:
+				lo, hi := bounds(key)
+				if sl.DeleteBatchByKey(CmpInstanceInt(key)) != (hi > lo) {
+					t.Fatalf("DeleteBatchByKey(%d) 结果错误", key)
+				}
+				want = append(want[:lo], want[hi:]...)
+			case 4:
+				if len(want) > 0 {
+					rank := r.Intn(len(want)) + 1
+					sl.DeleteByRank(rank)
+					want = append(want[:rank-1], want[rank:]...)
+				}
+			case 5:
+				b := NewBatch()
+				b.Insert(CmpInstanceInt(key), i)
+				b.Insert(CmpInstanceInt(key+1), -i)
+				if err := sl.Apply(b); err != nil {
+					t.Fatal(err)
+				}
+				_, pos := bounds(key)
+				want = append(want[:pos], append([]entry{{key, i}}, want[pos:]...)...)
+				_, pos = bounds(key + 1)
+				want = append(want[:pos], append([]entry{{key + 1, -i}}, want[pos:]...)...)
+			case 6:
+				if lo, hi := bounds(key); hi > lo {
+					b := NewBatch()
+					b.Delete(CmpInstanceInt(key))
+					if err := sl.Apply(b); err != nil {
+						t.Fatal(err)
+					}
+					want = append(want[:lo], want[hi:]...)
+				}
+			}
+			if i%50 == 0 {
+				checkGaps(t, sl)
+				got := sl.GetByRankRange(1, sl.GetLength())
+				if len(got) != len(want) {
+					t.Fatalf("长度 %d != %d", len(got), len(want))
+				}
+				for k := range want {
+					if got[k] != want[k].data {
+						t.Fatalf("排位 %d 的数据 %v != %v", k+1, got[k], want[k].data)
+					}
+				}
+			}
+		}
+	}
+}
+
+func Benchmark_DeterministicInsert(b *testing.B) {
+	var cmp *CmpInstanceInt
+	for _, bc := range []struct {
+		name    string
+		options []Option
+	}{
+		{"random", nil},
+		{"deterministic", []Option{WithDeterministic(true)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			sl, _ := New(cmp, bc.options...)
+			for i := 0; i < b.N; i++ {
+				sl.Insert(CmpInstanceInt(i), i)
+			}
+		})
+	}
+}
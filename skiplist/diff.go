@@ -0,0 +1,157 @@
+package skiplist
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var diffStaleErr = errors.New("diff does not match the list")
+
+// 差异类型
+type DiffKind int
+
+const (
+	DiffOnlyInA DiffKind = iota + 1 //只在A中
+	DiffOnlyInB                     //只在B中
+	DiffChanged                     //key相同数据不同
+)
+
+/*
+一条差异
+相同key的多个结点按位置一一对应，Index 为结点在相同key中的位置 (从0开始): DiffChanged、DiffOnlyInA 为在A中的位置，
+DiffOnlyInB 为在B中的位置
+*/
+type DiffEntry struct {
+	Kind  DiffKind
+	Key   interface{}
+	Index int
+	A, B  interface{} //A、B 中的数据，不存在时为nil
+}
+
+/*
+比较两个跳表
+同时遍历两个跳表的第0层，按key顺序返回差异，O(n+m)。两个跳表需要使用相同的排序方式，使用A的比较接口比较key;
+equal 为nil时使用 reflect.DeepEqual 比较数据
+*/
+func Diff(a, b *SkipList, equal func(x, y interface{}) bool) []DiffEntry {
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+	diff := []DiffEntry{}
+	na, nb := a.head.level[0].next, b.head.level[0].next
+	for na != nil || nb != nil {
+		c := 0
+		switch {
+		case na == nil:
+			c = 1
+		case nb == nil:
+			c = -1
+		default:
+			c = a.compare(na.key, nb.key)
+		}
+		if c < 0 {
+			diff = append(diff, DiffEntry{Kind: DiffOnlyInA, Key: na.key, Index: 0, A: na.data})
+			na = na.level[0].next
+			for ; na != nil && a.equals(na.key, na.prev.key); na = na.level[0].next {
+				diff = append(diff, DiffEntry{Kind: DiffOnlyInA, Key: na.key, Index: diff[len(diff)-1].Index + 1, A: na.data})
+			}
+			continue
+		}
+		if c > 0 {
+			diff = append(diff, DiffEntry{Kind: DiffOnlyInB, Key: nb.key, Index: 0, B: nb.data})
+			nb = nb.level[0].next
+			for ; nb != nil && a.equals(nb.key, nb.prev.key); nb = nb.level[0].next {
+				diff = append(diff, DiffEntry{Kind: DiffOnlyInB, Key: nb.key, Index: diff[len(diff)-1].Index + 1, B: nb.data})
+			}
+			continue
+		}
+		//相同key的结点按位置对应
+		key, index := na.key, 0
+		for ; na != nil && nb != nil && a.equals(na.key, key) && a.equals(nb.key, key); index++ {
+			if !equal(na.data, nb.data) {
+				diff = append(diff, DiffEntry{Kind: DiffChanged, Key: na.key, Index: index, A: na.data, B: nb.data})
+			}
+			na, nb = na.level[0].next, nb.level[0].next
+		}
+		for ia := index; na != nil && a.equals(na.key, key); ia, na = ia+1, na.level[0].next {
+			diff = append(diff, DiffEntry{Kind: DiffOnlyInA, Key: na.key, Index: ia, A: na.data})
+		}
+		for ib := index; nb != nil && a.equals(nb.key, key); ib, nb = ib+1, nb.level[0].next {
+			diff = append(diff, DiffEntry{Kind: DiffOnlyInB, Key: nb.key, Index: ib, B: nb.data})
+		}
+	}
+	return diff
+}
+
+/*
+按差异修改跳表，使其与B一致
+diff 为 Diff(sl, b) 的结果，生成差异之后跳表不能被修改。先校验差异与跳表是否匹配，不匹配时返回错误且不做任何修改
+*/
+func (sl *SkipList) ApplyDiff(diff []DiffEntry) error {
+	if err := sl.validateDiff(diff); err != nil {
+		return err
+	}
+	path := sl.newSearchPath()
+	for start := 0; start < len(diff); {
+		key := diff[start].Key
+		end := start
+		for end < len(diff) && sl.equals(diff[end].Key, key) {
+			end++
+		}
+		//path 为相同key的第一个结点的前置路径
+		sl.seekPath(path, key, false)
+		first := path.rank[0] + 1
+		deleted := 0
+		for _, e := range diff[start:end] {
+			switch e.Kind {
+			case DiffChanged:
+				sl.seekRankPath(path, first+e.Index-deleted)
+				sl.updateByNode(path.prev[0].level[0].next, e.B)
+			case DiffOnlyInA:
+				sl.seekRankPath(path, first+e.Index-deleted)
+				sl.freeNode(sl.unlinkNext(path))
+				deleted++
+			case DiffOnlyInB:
+				sl.seekPath(path, key, true)
+				sl.linkNode(path, sl.nodeGenerate(e.Key, e.B))
+			}
+		}
+		start = end
+	}
+	return nil
+}
+
+// 校验差异  key需要升序，DiffChanged、DiffOnlyInA 的位置需要存在，DiffOnlyInB 的位置需要在已有结点之后
+func (sl *SkipList) validateDiff(diff []DiffEntry) error {
+	path := sl.newSearchPath()
+	for start := 0; start < len(diff); {
+		key := diff[start].Key
+		if start > 0 && sl.compare(diff[start-1].Key, key) > 0 {
+			return fmt.Errorf("skiplist: diff entry %d: %w", start, diffStaleErr)
+		}
+		sl.seekPath(path, key, false)
+		count := 0
+		for node := path.prev[0].level[0].next; node != nil && sl.equals(node.key, key); node = node.level[0].next {
+			count++
+		}
+		last := -1 //上一个A中的位置
+		for ; start < len(diff) && sl.equals(diff[start].Key, key); start++ {
+			e := diff[start]
+			switch e.Kind {
+			case DiffChanged, DiffOnlyInA:
+				if e.Index <= last || e.Index >= count {
+					return fmt.Errorf("skiplist: diff entry %d: %w", start, diffStaleErr)
+				}
+				last = e.Index
+			case DiffOnlyInB:
+				if e.Index < count {
+					return fmt.Errorf("skiplist: diff entry %d: %w", start, diffStaleErr)
+				}
+			default:
+				return fmt.Errorf("skiplist: diff entry %d: %w", start, diffStaleErr)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package skiplist
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func Test_Diff(t *testing.T) {
+	var cmp *CmpInstanceInt
+	a, _ := New(cmp)
+	b, _ := New(cmp)
+	for _, kv := range [][2]int{{1, 1}, {2, 2}, {3, 3}, {3, 4}, {5, 5}} {
+		a.Insert(CmpInstanceInt(kv[0]), kv[1])
+	}
+	for _, kv := range [][2]int{{2, 2}, {3, 30}, {4, 4}, {5, 5}, {5, 6}} {
+		b.Insert(CmpInstanceInt(kv[0]), kv[1])
+	}
+	want := []DiffEntry{
+		{Kind: DiffOnlyInA, Key: CmpInstanceInt(1), Index: 0, A: 1},
+		{Kind: DiffChanged, Key: CmpInstanceInt(3), Index: 0, A: 3, B: 30},
+		{Kind: DiffOnlyInA, Key: CmpInstanceInt(3), Index: 1, A: 4},
+		{Kind: DiffOnlyInB, Key: CmpInstanceInt(4), Index: 0, B: 4},
+		{Kind: DiffOnlyInB, Key: CmpInstanceInt(5), Index: 1, B: 6},
+	}
+	diff := Diff(a, b, nil)
+	if len(diff) != len(want) {
+		t.Fatalf("差异数量 %d want %d: %+v", len(diff), len(want), diff)
+	}
+	for i := range want {
+		if diff[i] != want[i] {
+			t.Fatalf("第 %d 条差异 %+v want %+v", i, diff[i], want[i])
+		}
+	}
+	//自定义相等比较: 模3相等即认为相同
+	mod3 := func(x, y interface{}) bool { return x.(int)%3 == y.(int)%3 }
+	for _, e := range Diff(a, b, mod3) {
+		if e.Kind == DiffChanged {
+			t.Fatalf("模3相等不应当有差异 %+v", e)
+		}
+	}
+	if err := a.ApplyDiff(diff); err != nil {
+		t.Fatal(err)
+	}
+	checkStructure(t, a)
+	if d := Diff(a, b, nil); len(d) != 0 {
+		t.Fatalf("应用后仍有差异 %+v", d)
+	}
+	//再次应用时差异与跳表不匹配
+	if err := a.ApplyDiff(diff); !errors.Is(err, diffStaleErr) {
+		t.Fatalf("want diffStaleErr, got %v", err)
+	}
+}
+
+// Test_DiffRandom 随机生成两个跳表，应用差异后完全一致
+func Test_DiffRandom(t *testing.T) {
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		a, _ := New(cmp)
+		b, _ := New(cmp)
+		for i := 0; i < r.Intn(300); i++ {
+			a.Insert(CmpInstanceInt(r.Intn(50)), r.Intn(5))
+		}
+		for i := 0; i < r.Intn(300); i++ {
+			b.Insert(CmpInstanceInt(r.Intn(50)), r.Intn(5))
+		}
+		if err := a.ApplyDiff(Diff(a, b, nil)); err != nil {
+			t.Fatal(err)
+		}
+		checkStructure(t, a)
+		if got, exp := a.GetByRankRange(1, a.GetLength()), b.GetByRankRange(1, b.GetLength()); !equalSlice(got, exp) {
+			t.Fatalf("round %d 结果不一致", round)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package skiplist
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	decodeDuplicateErr = errors.New("duplicate key in encoded list")
+	decodeTargetErr    = errors.New("skiplist must be created by New before decoding")
+)
+
+/*
+	序列化
+	跳表按顺序编码为 key/value 对的数组，相同key保持原有顺序。
+	解码时清空接收者并按顺序插入，接收者需要先通过 New 创建以确定比较方式及参数;
+	不允许重复key时遇到重复的key返回错误，此时跳表中只有出错之前的结点
+*/
+
+// JSON 中的一个结点
+type jsonPair struct {
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// 解码时的结点  先保留原始数据，再按设置的类型解码
+type rawPair struct {
+	Key   json.RawMessage `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// gob 中的一个结点  key与数据的具体类型需要预先通过 gob.Register 注册
+type gobPair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// 按顺序插入解码出的结点  路径沿用上一次插入的位置，有序输入时不必每次从头结点查找
+func (sl *SkipList) appendDecoded(path *searchPath, key, data interface{}) error {
+	if path.prev[0] != sl.head && sl.compare(path.prev[0].key, key) > 0 {
+		sl.resetPath(path)
+	}
+	sl.seekPath(path, key, true)
+	if !sl.allowSameKey && path.prev[0] != sl.head && sl.equals(path.prev[0].key, key) {
+		return fmt.Errorf("skiplist: decode key %v: %w", key, decodeDuplicateErr)
+	}
+	sl.linkNode(path, sl.nodeGenerate(key, data))
+	return nil
+}
+
+// 按类型解码JSON  类型为nil时使用默认类型
+func decodeJSON(raw json.RawMessage, typ reflect.Type) (interface{}, error) {
+	if typ == nil {
+		var v interface{}
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+	v := reflect.New(typ)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
+
+// 实现 json.Marshaler  编码为 [{"key":...,"value":...}, ...]
+func (sl *SkipList) MarshalJSON() ([]byte, error) {
+	pairs := make([]jsonPair, 0, sl.length)
+	for node := sl.head.level[0].next; node != nil; node = node.level[0].next {
+		pairs = append(pairs, jsonPair{Key: node.key, Value: node.data})
+	}
+	return json.Marshal(pairs)
+}
+
+// 实现 json.Unmarshaler  key与数据的类型通过 WithTypes 设置
+func (sl *SkipList) UnmarshalJSON(b []byte) error {
+	if sl.compareAble == nil {
+		return decodeTargetErr
+	}
+	var pairs []rawPair
+	if err := json.Unmarshal(b, &pairs); err != nil {
+		return err
+	}
+	sl.deleteAll()
+	path := sl.newSearchPath()
+	for k := range pairs {
+		key, err := decodeJSON(pairs[k].Key, sl.keyType)
+		if err != nil {
+			return fmt.Errorf("skiplist: decode key %d: %w", k, err)
+		}
+		data, err := decodeJSON(pairs[k].Value, sl.dataType)
+		if err != nil {
+			return fmt.Errorf("skiplist: decode value %d: %w", k, err)
+		}
+		if err = sl.appendDecoded(path, key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 实现 gob.GobEncoder
+func (sl *SkipList) GobEncode() ([]byte, error) {
+	pairs := make([]gobPair, 0, sl.length)
+	for node := sl.head.level[0].next; node != nil; node = node.level[0].next {
+		pairs = append(pairs, gobPair{Key: node.key, Value: node.data})
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(pairs); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 实现 gob.GobDecoder
+func (sl *SkipList) GobDecode(b []byte) error {
+	if sl.compareAble == nil {
+		return decodeTargetErr
+	}
+	var pairs []gobPair
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&pairs); err != nil {
+		return err
+	}
+	sl.deleteAll()
+	path := sl.newSearchPath()
+	for k := range pairs {
+		if err := sl.appendDecoded(path, pairs[k].Key, pairs[k].Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package skiplist
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type encodingScore struct {
+	Name  string
+	Score int
+}
+
+func Test_JSON(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	sl.Insert(CmpInstanceInt(3), encodingScore{"c", 3})
+	sl.Insert(CmpInstanceInt(1), encodingScore{"a", 1})
+	sl.Insert(CmpInstanceInt(3), encodingScore{"d", 3})
+	sl.Insert(CmpInstanceInt(2), encodingScore{"b", 2})
+	b, err := json.Marshal(sl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"key":1,"value":{"Name":"a","Score":1}},{"key":2,"value":{"Name":"b","Score":2}},` +
+		`{"key":3,"value":{"Name":"c","Score":3}},{"key":3,"value":{"Name":"d","Score":3}}]`
+	if string(b) != want {
+		t.Fatalf("MarshalJSON = %s", b)
+	}
+	got, _ := New(cmp, WithTypes[CmpInstanceInt, encodingScore]())
+	got.Insert(CmpInstanceInt(9), encodingScore{"old", 9})
+	if err = json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	checkStructure(t, got)
+	if a, e := got.GetByRankRange(1, 10), sl.GetByRankRange(1, 10); !equalSlice(a, e) {
+		t.Fatalf("UnmarshalJSON = %v want %v", a, e)
+	}
+	if data, rk := got.GetTailWithRankByKey(CmpInstanceInt(3)); data != (encodingScore{"d", 3}) || rk != 4 {
+		t.Fatalf("相同key的顺序错误 %v %d", data, rk)
+	}
+
+	//未设置类型时使用默认类型
+	strs, _ := New(Ordered[string]())
+	if err = json.Unmarshal([]byte(`[{"key":"b","value":2},{"key":"a","value":"x"}]`), strs); err != nil {
+		t.Fatal(err)
+	}
+	if list := strs.GetByRankRange(1, 10); !equalSlice(list, []interface{}{"x", float64(2)}) {
+		t.Fatalf("默认类型解码 %v", list)
+	}
+
+	unique, _ := New(cmp, WithAllowTheSameKey(false), WithTypes[CmpInstanceInt, encodingScore]())
+	if err = json.Unmarshal(b, unique); !errors.Is(err, decodeDuplicateErr) {
+		t.Fatalf("want decodeDuplicateErr, got %v", err)
+	}
+	if err = json.Unmarshal([]byte(`[{"key":"x","value":{}}]`), got); err == nil {
+		t.Fatal("key 类型错误时应当返回错误")
+	}
+	if err = json.Unmarshal(b, &SkipList{}); !errors.Is(err, decodeTargetErr) {
+		t.Fatalf("want decodeTargetErr, got %v", err)
+	}
+	empty, _ := New(cmp)
+	if b, _ = json.Marshal(empty); string(b) != "[]" {
+		t.Fatalf("空跳表 MarshalJSON = %s", b)
+	}
+}
+
+func Test_Gob(t *testing.T) {
+	gob.Register(CmpInstanceInt(0))
+	gob.Register(encodingScore{})
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	for i := 0; i < 200; i++ {
+		sl.Insert(CmpInstanceInt(i%17), encodingScore{Score: i})
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(sl); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := New(cmp, WithDeterministic(true))
+	if err := gob.NewDecoder(&buf).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	checkGaps(t, got)
+	if a, e := got.GetByRankRange(1, got.GetLength()), sl.GetByRankRange(1, sl.GetLength()); !equalSlice(a, e) {
+		t.Fatalf("GobDecode = %v want %v", a, e)
+	}
+}
+
+// Test_DecodeNotify 解码到非空跳表时原有结点逐个删除并发送通知，观察者维护的副本保持一致
+func Test_DecodeNotify(t *testing.T) {
+	gob.Register(CmpInstanceInt(0))
+	var cmp *CmpInstanceInt
+	src, _ := New(cmp)
+	for i := 0; i < 30; i++ {
+		src.Insert(CmpInstanceInt(i%7), i)
+	}
+	b, err := src.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl, _ := New(cmp, WithWeight(func(data interface{}) float64 { return float64(data.(int)) }), WithArena(8))
+	mirror, _ := New(cmp)
+	for i := 0; i < 50; i++ {
+		sl.Insert(CmpInstanceInt(i), 100+i)
+		mirror.Insert(CmpInstanceInt(i), 100+i)
+	}
+	deletes := 0
+	sl.AddObserver(Observer{
+		OnInsert: func(key, data interface{}, rank int) {
+			if rk, _ := mirror.Insert(key, data); rk != rank {
+				t.Fatalf("副本插入排位 %d != %d", rk, rank)
+			}
+		},
+		OnDelete: func(key, data interface{}, rank int) {
+			deletes++
+			mirror.DeleteByRank(rank)
+		},
+	})
+	if err = sl.GobDecode(b); err != nil {
+		t.Fatal(err)
+	}
+	if deletes != 50 {
+		t.Fatalf("删除通知 %d 个, want 50", deletes)
+	}
+	want := src.GetByRankRange(1, src.GetLength())
+	if !equalSlice(sl.GetByRankRange(1, sl.GetLength()), want) || !equalSlice(mirror.GetByRankRange(1, mirror.GetLength()), want) {
+		t.Fatal("解码后数据或副本不一致")
+	}
+	checkStructure(t, sl)
+	checkWeights(t, sl)
+}
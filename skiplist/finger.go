@@ -0,0 +1,127 @@
+package skiplist
+
+/*
+插入位置句柄
+记录一次插入后的查找路径。下一次查找从该路径向上爬升: key在记录位置之前时爬升到前置结点在key之前的层 (向后移动)，
+key在记录位置之后时爬升到下一个结点不在key之前的层 (向前移动)，再向下查找，代价为 O(log d)，d 为两次查找位置之间的距离。
+跳表在句柄记录之后发生了其他插入或删除时句柄失效，失效的句柄会退化为从头结点查找。
+零值可以直接使用
+*/
+type Hint struct {
+	sl      *SkipList
+	path    *searchPath
+	version uint64
+}
+
+// 句柄是否仍然可以用于查找  句柄记录之后跳表没有其他插入或删除
+func (sl *SkipList) hintUsable(h *Hint) bool {
+	return h.sl == sl && h.path != nil && h.version == sl.version
+}
+
+// a是否在key之前  inclusive 为 true 时相等也算在key之前 (同 seekPath)
+func (sl *SkipList) before(a, key interface{}, inclusive bool) bool {
+	c := sl.compare(a, key)
+	return c < 0 || (c == 0 && inclusive)
+}
+
+/*
+从句柄的路径向上爬升，返回第一个可以作为查找起点的层: 该层前置结点在key之前，且下一个结点不在key之前 (顶层只要求前者)。
+back 为前置结点在key之后、需要向后移动的层数，这些层总是最低的若干层。爬升到顶层仍不满足时返回-1
+*/
+func (sl *SkipList) fingerLevel(path *searchPath, key interface{}, inclusive bool) (level, back int) {
+	for ; level <= sl.currentMaxLevel; level++ {
+		prev := path.prev[level]
+		if prev != sl.head && !sl.before(prev.key, key, inclusive) {
+			back = level + 1
+			continue
+		}
+		if next := prev.level[level].next; level == sl.currentMaxLevel || next == nil || !sl.before(next.key, key, inclusive) {
+			return level, back
+		}
+	}
+	return -1, back
+}
+
+// 获取key的查找路径  句柄可用时从句柄开始查找，否则复用句柄的路径缓冲区从头结点查找
+func (sl *SkipList) hintPath(h *Hint, key interface{}, inclusive bool) *searchPath {
+	if sl.hintUsable(h) {
+		if level, back := sl.fingerLevel(h.path, key, inclusive); level >= 0 {
+			sl.countSearch()
+			//向后移动的层的前置结点在key之后，重置为头结点，向下查找时从上一层的位置继续
+			for l := 0; l < back; l++ {
+				h.path.prev[l], h.path.rank[l] = sl.head, 0
+			}
+			sl.seekPathFrom(h.path, key, inclusive, level)
+			return h.path
+		}
+	}
+	path := h.path
+	if h.sl != sl || path == nil {
+		path = sl.newSearchPath()
+	} else {
+		for level := range path.prev {
+			path.prev[level], path.rank[level] = sl.head, 0
+		}
+	}
+	sl.seekPath(path, key, inclusive)
+	return path
+}
+
+// 在句柄处插入  插入后句柄指向新结点
+func (sl *SkipList) insertAfterHint(h *Hint, key, data interface{}) (int, bool) {
+	path := sl.hintPath(h, key, true)
+	h.sl, h.path = sl, path
+	if !sl.allowSameKey && path.prev[0] != sl.head && sl.equals(path.prev[0].key, key) {
+		h.version = sl.version
+		return 0, false
+	}
+	rank := sl.linkNode(path, sl.nodeGenerate(key, data))
+	h.version = sl.version
+	return rank, true
+}
+
+/*
+按句柄插入数据
+适合按key升序 (或基本有序) 插入的场景: 每次插入后句柄指向新结点，下一次插入从句柄处向前或向后查找。
+句柄已失效时从头结点查找，结果与 Insert 相同。
+返回当前排名和插入结果
+*/
+func (sl *SkipList) InsertAfterHint(hint *Hint, key, data interface{}) (int, bool) {
+	return sl.insertAfterHint(hint, key, data)
+}
+
+/*
+写操作 (按key插入、删除) 的查找路径
+开启 finger search 时从 finger 开始查找，并由 finger 记录该路径; 修改跳表后需要调用 fingerSync
+*/
+func (sl *SkipList) writePath(key interface{}, inclusive bool) *searchPath {
+	if sl.finger == nil {
+		path := sl.scratchPath()
+		sl.seekPath(path, key, inclusive)
+		return path
+	}
+	path := sl.hintPath(sl.finger, key, inclusive)
+	sl.finger.sl, sl.finger.path, sl.finger.version = sl, path, sl.version
+	return path
+}
+
+// 写操作修改跳表后，finger 的路径仍然有效 (删除后路径仍指向被删除结点的前置结点)
+func (sl *SkipList) fingerSync() {
+	if sl.finger != nil {
+		sl.finger.version = sl.version
+	}
+}
+
+/*
+读操作的查找起点
+开启 finger search 且 finger 有效时从其路径向上爬升，返回起始结点、其rank及所在层; 否则为头结点及当前最大层。
+读操作只读取 finger，不移动它
+*/
+func (sl *SkipList) searchStart(key interface{}, inclusive bool) (*skipListNode, int, int) {
+	if sl.finger != nil && sl.hintUsable(sl.finger) {
+		if level, _ := sl.fingerLevel(sl.finger.path, key, inclusive); level >= 0 {
+			return sl.finger.path.prev[level], sl.finger.path.rank[level], level
+		}
+	}
+	return sl.head, 0, sl.currentMaxLevel
+}
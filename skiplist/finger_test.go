@@ -0,0 +1,205 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Test_FingerSearch 开启 finger search 后结果与普通插入一致
+func Test_FingerSearch(t *testing.T) {
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(1))
+	for _, allowSame := range []bool{true, false} {
+		sl, _ := New(cmp, WithFingerSearch(true), WithAllowTheSameKey(allowSame))
+		want, _ := New(cmp, WithAllowTheSameKey(allowSame))
+		base := 0
+		for i := 0; i < 3000; i++ {
+			//基本有序，偶尔回退或删除
+			base += r.Intn(3)
+			key := CmpInstanceInt(base - r.Intn(5))
+			if r.Intn(20) == 0 {
+				key = CmpInstanceInt(r.Intn(base + 1))
+			}
+			if r.Intn(10) == 0 {
+				if sl.DeleteBatchByKey(key) != want.DeleteBatchByKey(key) {
+					t.Fatalf("删除 %d 结果不一致", key)
+				}
+				continue
+			}
+			rk, ok := sl.Insert(key, i)
+			wrk, wok := want.Insert(key, i)
+			if rk != wrk || ok != wok {
+				t.Fatalf("插入 %d = %d,%v want %d,%v", key, rk, ok, wrk, wok)
+			}
+		}
+		checkStructure(t, sl)
+		if got, exp := sl.GetByRankRange(1, sl.GetLength()), want.GetByRankRange(1, want.GetLength()); !equalSlice(got, exp) {
+			t.Fatalf("allowSame %v 结果不一致", allowSame)
+		}
+	}
+}
+
+// Test_FingerBackward finger 向前、向后移动，用于插入、删除及查找，结果与从头查找一致
+func Test_FingerBackward(t *testing.T) {
+	var cmp *CmpInstanceInt
+	modes := map[string][]Option{
+		"random":        nil,
+		"unique":        {WithAllowTheSameKey(false)},
+		"deterministic": {WithDeterministic(true)},
+		"arena":         {WithArena(16)},
+	}
+	for name, options := range modes {
+		r := rand.New(rand.NewSource(1))
+		sl, _ := New(cmp, append([]Option{WithFingerSearch(true)}, options...)...)
+		want, _ := New(cmp, options...)
+		base := 1000
+		for i := 0; i < 5000; i++ {
+			//在最近位置附近前后移动，偶尔跳到远处
+			base += r.Intn(9) - 4
+			key := CmpInstanceInt(base)
+			if r.Intn(30) == 0 {
+				key = CmpInstanceInt(r.Intn(2000))
+			}
+			switch r.Intn(5) {
+			case 0, 1:
+				rk, ok := sl.Insert(key, i)
+				wrk, wok := want.Insert(key, i)
+				if rk != wrk || ok != wok {
+					t.Fatalf("%s: 插入 %d = %d,%v want %d,%v", name, key, rk, ok, wrk, wok)
+				}
+			case 2:
+				if sl.DeleteByKey(key) != want.DeleteByKey(key) {
+					t.Fatalf("%s: 删除 %d 结果不一致", name, key)
+				}
+			case 3:
+				if sl.DeleteBatchByKey(key) != want.DeleteBatchByKey(key) {
+					t.Fatalf("%s: 批量删除 %d 结果不一致", name, key)
+				}
+			case 4:
+				finger, version := *sl.finger.path, sl.finger.version
+				d, rk := sl.GetFirstWithRankByKey(key)
+				wd, wrk := want.GetFirstWithRankByKey(key)
+				td, trk := sl.GetTailWithRankByKey(key)
+				wtd, wtrk := want.GetTailWithRankByKey(key)
+				cd, crk := sl.GetCeilingWithRankByKey(key)
+				wcd, wcrk := want.GetCeilingWithRankByKey(key)
+				if d != wd || rk != wrk || td != wtd || trk != wtrk || cd != wcd || crk != wcrk {
+					t.Fatalf("%s: 查找 %d 结果不一致", name, key)
+				}
+				if !equalSlice(sl.GetAllByKey(key), want.GetAllByKey(key)) {
+					t.Fatalf("%s: 查找全部 %d 结果不一致", name, key)
+				}
+				//查找不移动 finger
+				if sl.finger.version != version || sl.finger.path.prev[0] != finger.prev[0] {
+					t.Fatalf("%s: 查找修改了 finger", name)
+				}
+			}
+		}
+		checkStructure(t, sl)
+		if !equalSlice(sl.GetByRankRange(1, sl.GetLength()), want.GetByRankRange(1, want.GetLength())) {
+			t.Fatalf("%s: 结果不一致", name)
+		}
+	}
+}
+
+// Test_FingerDescending 按key降序插入时 finger 向后移动，比较次数少于从头查找
+func Test_FingerDescending(t *testing.T) {
+	var cmp *CmpInstanceInt
+	comparisons := func(options ...Option) uint64 {
+		sl, _ := New(cmp, append([]Option{WithInstrumentation(true)}, options...)...)
+		defer sl.Close()
+		for i := 20000; i > 0; i-- {
+			sl.Insert(CmpInstanceInt(i), i)
+		}
+		for i := 20000; i > 0; i -= 2 {
+			sl.DeleteByKey(CmpInstanceInt(i))
+		}
+		checkStructure(t, sl)
+		return sl.Stats().Comparisons
+	}
+	head, finger := comparisons(), comparisons(WithFingerSearch(true))
+	if finger*2 > head {
+		t.Fatalf("降序插入比较次数 finger %d head %d", finger, head)
+	}
+}
+
+// Test_InsertAfterHint 句柄向后移动，失效时退化为从头查找
+func Test_InsertAfterHint(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	var hint Hint
+	for i := 0; i < 100; i += 2 {
+		if rk, ok := sl.InsertAfterHint(&hint, CmpInstanceInt(i), i); !ok || rk != i/2+1 {
+			t.Fatalf("插入 %d rank = %d", i, rk)
+		}
+	}
+	//key回退时句柄向后移动
+	if rk, _ := sl.InsertAfterHint(&hint, CmpInstanceInt(11), 11); rk != 7 {
+		t.Fatalf("回退插入 rank = %d want 7", rk)
+	}
+	//其他修改使句柄失效
+	sl.DeleteByKey(CmpInstanceInt(0))
+	if hint.version == sl.version {
+		t.Fatal("删除后句柄应当失效")
+	}
+	if rk, _ := sl.InsertAfterHint(&hint, CmpInstanceInt(13), 13); rk != 8 {
+		t.Fatalf("失效句柄插入 rank = %d want 8", rk)
+	}
+	if rk, _ := sl.InsertAfterHint(&hint, CmpInstanceInt(100), 100); rk != 52 {
+		t.Fatalf("句柄插入 rank = %d want 52", rk)
+	}
+	//句柄用于另一个跳表时从头查找
+	other, _ := New(cmp)
+	other.Insert(CmpInstanceInt(200), 200)
+	if rk, _ := other.InsertAfterHint(&hint, CmpInstanceInt(101), 101); rk != 1 {
+		t.Fatalf("其他跳表插入 rank = %d want 1", rk)
+	}
+	checkStructure(t, sl)
+	checkStructure(t, other)
+}
+
+// 单调递增插入  普通插入与 finger search、句柄插入对比
+func Benchmark_InsertMonotonic(b *testing.B) {
+	var cmp *CmpInstanceInt
+	b.Run("head", func(b *testing.B) {
+		sl, _ := New(cmp)
+		for i := 0; i < b.N; i++ {
+			sl.Insert(CmpInstanceInt(i), i)
+		}
+	})
+	b.Run("finger", func(b *testing.B) {
+		sl, _ := New(cmp, WithFingerSearch(true))
+		for i := 0; i < b.N; i++ {
+			sl.Insert(CmpInstanceInt(i), i)
+		}
+	})
+	b.Run("hint", func(b *testing.B) {
+		sl, _ := New(cmp)
+		var hint Hint
+		for i := 0; i < b.N; i++ {
+			sl.InsertAfterHint(&hint, CmpInstanceInt(i), i)
+		}
+	})
+}
+
+// 基本有序插入  key在最近位置附近小幅抖动
+func Benchmark_InsertNearlySorted(b *testing.B) {
+	var cmp *CmpInstanceInt
+	keys := make([]CmpInstanceInt, 1<<20)
+	r := rand.New(rand.NewSource(1))
+	for i := range keys {
+		keys[i] = CmpInstanceInt(i*4 + r.Intn(64))
+	}
+	b.Run("head", func(b *testing.B) {
+		sl, _ := New(cmp)
+		for i := 0; i < b.N; i++ {
+			sl.Insert(keys[i%len(keys)]+CmpInstanceInt(i/len(keys)*len(keys)*4), i)
+		}
+	})
+	b.Run("finger", func(b *testing.B) {
+		sl, _ := New(cmp, WithFingerSearch(true))
+		for i := 0; i < b.N; i++ {
+			sl.Insert(keys[i%len(keys)]+CmpInstanceInt(i/len(keys)*len(keys)*4), i)
+		}
+	})
+}
@@ -38,6 +38,33 @@ func (it *iterator) Node() *skipListNode {
 	return it.node
 }
 
+//定位到第一个大于等于key的结点，不存在时结点为nil
+func (it *iterator) Seek(key interface{}) *skipListNode {
+	it.node, _ = it.sl.searchCeilingNodeAndRankByKey(key)
+	return it.node
+}
+
+//当前结点是否有效 (非头结点且不为nil)
+func (it *iterator) Valid() bool {
+	return it.node != nil && it.node != it.sl.head
+}
+
+//获取当前结点的key
+func (it *iterator) Key() interface{} {
+	if it.Valid() {
+		return it.node.key
+	}
+	return nil
+}
+
+//获取当前结点的数据
+func (it *iterator) Data() interface{} {
+	if it.Valid() {
+		return it.node.data
+	}
+	return nil
+}
+
 //获取当前结点当前层的span
 func (it *iterator) Span(level int) int {
 	return it.node.level[level].span
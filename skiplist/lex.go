@@ -0,0 +1,213 @@
+package skiplist
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+var (
+	lexBoundErr = errors.New("lex bound must start with '[' or '(' or be '-' or '+'")
+	lexKeyErr   = errors.New("lex key type is not set by WithLexKey or can not be compared")
+)
+
+/*
+	前缀及字典序区间查找
+	key 的类型必须为 string 或 []byte，并使用按字节比较的排序方式 (Ordered[string]()、Bytes())
+	key 的类型需要通过 WithLexKey 指定，未指定时返回 lexKeyErr
+	前缀及区间边界统一使用 string，key 为 []byte 时转换为 []byte 后比较
+*/
+
+// 字典序查找的key类型
+type lexKind int8
+
+const (
+	lexNone   lexKind = iota //未指定
+	lexString                //string
+	lexBytes                 //[]byte
+)
+
+// 比较接口能否比较key  比较时 panic 视为不能比较
+func (sl *SkipList) canCompare(key interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	sl.compareAble.Compare(key, key)
+	return true
+}
+
+// 字典序区间的边界
+type lexBound struct {
+	key       interface{}
+	exclusive bool //是否不包含边界
+	infinite  int  //-1: "-" 负无穷  1: "+" 正无穷
+}
+
+// 解析区间边界  "[a" 包含a，"(a" 不包含a，"-" 负无穷，"+" 正无穷
+func (sl *SkipList) parseLexBound(spec string) (lexBound, error) {
+	switch {
+	case spec == "-":
+		return lexBound{infinite: -1}, nil
+	case spec == "+":
+		return lexBound{infinite: 1}, nil
+	case strings.HasPrefix(spec, "["):
+		return lexBound{key: sl.lexKey(spec[1:])}, nil
+	case strings.HasPrefix(spec, "("):
+		return lexBound{key: sl.lexKey(spec[1:]), exclusive: true}, nil
+	}
+	return lexBound{}, lexBoundErr
+}
+
+// 解析区间的上下界
+func (sl *SkipList) parseLexRange(min, max string) (lexBound, lexBound, error) {
+	if sl.lex == lexNone {
+		return lexBound{}, lexBound{}, lexKeyErr
+	}
+	lo, err := sl.parseLexBound(min)
+	if err != nil {
+		return lexBound{}, lexBound{}, err
+	}
+	hi, err := sl.parseLexBound(max)
+	return lo, hi, err
+}
+
+// 转换为 WithLexKey 指定的key类型
+func (sl *SkipList) lexKey(s string) interface{} {
+	if sl.lex == lexBytes {
+		return []byte(s)
+	}
+	return s
+}
+
+// key是否以prefix开头  key的类型与prefix不同时视为不匹配
+func hasPrefix(key, prefix interface{}) bool {
+	switch p := prefix.(type) {
+	case []byte:
+		k, ok := key.([]byte)
+		return ok && bytes.HasPrefix(k, p)
+	case string:
+		k, ok := key.(string)
+		return ok && strings.HasPrefix(k, p)
+	}
+	return false
+}
+
+// 大于所有以prefix开头的字符串的最小字符串  prefix为空或全部为0xff时不存在
+func prefixEnd(prefix string) (string, bool) {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xff {
+			return prefix[:i] + string([]byte{prefix[i] + 1}), true
+		}
+	}
+	return "", false
+}
+
+// 区间下界前的最后一个结点及其rank
+func (sl *SkipList) lexStart(min lexBound) (*skipListNode, int) {
+	switch min.infinite {
+	case -1:
+		return sl.head, 0
+	case 1:
+		return sl.tail, sl.length
+	}
+	return sl.searchPrevNodeAndRank(min.key, min.exclusive)
+}
+
+// 区间上界内的最后一个rank
+func (sl *SkipList) lexEnd(max lexBound) int {
+	switch max.infinite {
+	case -1:
+		return 0
+	case 1:
+		return sl.length
+	}
+	_, rank := sl.searchPrevNodeAndRank(max.key, !max.exclusive)
+	return rank
+}
+
+// 结点是否在区间上界内
+func (sl *SkipList) lexBelow(node *skipListNode, max lexBound) bool {
+	switch max.infinite {
+	case -1:
+		return false
+	case 1:
+		return true
+	}
+	c := sl.compare(node.key, max.key)
+	return c < 0 || (c == 0 && !max.exclusive)
+}
+
+/*
+按字典序区间遍历  min、max 的格式同 redis ZRANGEBYLEX: "[a" 包含a，"(a" 不包含a，"-" 负无穷，"+" 正无穷
+fn 返回 false 时停止遍历
+*/
+func (sl *SkipList) RangeByLex(min, max string, fn func(key, data interface{}) bool) error {
+	lo, hi, err := sl.parseLexRange(min, max)
+	if err != nil {
+		return err
+	}
+	preNode, _ := sl.lexStart(lo)
+	if preNode == nil {
+		return nil
+	}
+	for node := preNode.level[0].next; node != nil && sl.lexBelow(node, hi); node = node.level[0].next {
+		if !fn(node.key, node.data) {
+			break
+		}
+	}
+	return nil
+}
+
+// 获取字典序区间内的数据
+func (sl *SkipList) GetByLex(min, max string) ([]interface{}, error) {
+	list := []interface{}{}
+	err := sl.RangeByLex(min, max, func(key, data interface{}) bool {
+		list = append(list, data)
+		return true
+	})
+	return list, err
+}
+
+// 字典序区间内的结点数量  O(log n)
+func (sl *SkipList) CountLex(min, max string) (int, error) {
+	lo, hi, err := sl.parseLexRange(min, max)
+	if err != nil {
+		return 0, err
+	}
+	_, start := sl.lexStart(lo)
+	if end := sl.lexEnd(hi); end > start {
+		return end - start, nil
+	}
+	return 0, nil
+}
+
+// 按顺序遍历以prefix开头的结点  定位到第一个大于等于prefix的结点后遍历到第一个不匹配的结点为止，fn 返回 false 时停止遍历
+func (sl *SkipList) PrefixScan(prefix string, fn func(key, data interface{}) bool) error {
+	if sl.lex == lexNone {
+		return lexKeyErr
+	}
+	p := sl.lexKey(prefix)
+	preNode, _ := sl.searchPrevNodeAndRank(p, false)
+	for node := preNode.level[0].next; node != nil && hasPrefix(node.key, p); node = node.level[0].next {
+		if !fn(node.key, node.data) {
+			break
+		}
+	}
+	return nil
+}
+
+// 以prefix开头的结点数量  O(log n)
+func (sl *SkipList) CountPrefix(prefix string) (int, error) {
+	if sl.lex == lexNone {
+		return 0, lexKeyErr
+	}
+	_, start := sl.searchPrevNodeAndRank(sl.lexKey(prefix), false)
+	end, ok := prefixEnd(prefix)
+	if !ok {
+		return sl.length - start, nil
+	}
+	_, rank := sl.searchPrevNodeAndRank(sl.lexKey(end), false)
+	return rank - start, nil
+}
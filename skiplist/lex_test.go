@@ -0,0 +1,195 @@
+package skiplist
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_PrefixScan(t *testing.T) {
+	sl, _ := New(Ordered[string](), WithLexKey[string]())
+	for _, k := range []string{"user:1:name", "user:10:name", "user:1:age", "user:2:name", "user:", "usea", "user;", "order:1", "user:1:age"} {
+		sl.Insert(k, k)
+	}
+	keys := []string{}
+	sl.PrefixScan("user:1", func(key, data interface{}) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	if got := strings.Join(keys, ","); got != "user:10:name,user:1:age,user:1:age,user:1:name" {
+		t.Fatalf("PrefixScan user:1 = %s", got)
+	}
+	for prefix, want := range map[string]int{"user:1": 4, "user:1:": 3, "user:": 6, "user": 7, "": 9, "x": 0, "order:1": 1} {
+		if n, _ := sl.CountPrefix(prefix); n != want {
+			t.Fatalf("CountPrefix(%q) = %d want %d", prefix, n, want)
+		}
+	}
+	//提前结束遍历
+	count := 0
+	sl.PrefixScan("user", func(key, data interface{}) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Fatalf("提前结束遍历 count = %d", count)
+	}
+}
+
+func Test_PrefixBytes(t *testing.T) {
+	sl, _ := New(Bytes(), WithLexKey[[]byte]())
+	for _, k := range [][]byte{{0x01, 0xff}, {0x01, 0xff, 0x00}, {0x02}, {0xff}, {0xff, 0xff}, {0x01}} {
+		sl.Insert(k, len(k))
+	}
+	for prefix, want := range map[string]int{"\x01": 3, "\x01\xff": 2, "\xff": 2, "\xff\xff": 1, "\x03": 0} {
+		if n, _ := sl.CountPrefix(prefix); n != want {
+			t.Fatalf("CountPrefix(%q) = %d want %d", prefix, n, want)
+		}
+		scanned := 0
+		sl.PrefixScan(prefix, func(key, data interface{}) bool {
+			scanned++
+			return true
+		})
+		if scanned != want {
+			t.Fatalf("PrefixScan(%q) = %d want %d", prefix, scanned, want)
+		}
+	}
+	list, err := sl.GetByLex("[\x01\xff", "(\xff")
+	if err != nil || fmt.Sprint(list) != "[2 3 1]" {
+		t.Fatalf("GetByLex = %v, %v", list, err)
+	}
+}
+
+func Test_Lex(t *testing.T) {
+	sl, _ := New(Ordered[string](), WithLexKey[string]())
+	for _, k := range []string{"a", "b", "c", "c", "d", "e", "f", "g"} {
+		sl.Insert(k, k)
+	}
+	cases := []struct {
+		min, max, want string
+	}{
+		{"-", "+", "[a b c c d e f g]"},
+		{"[c", "[e", "[c c d e]"},
+		{"(c", "[e", "[d e]"},
+		{"[c", "(e", "[c c d]"},
+		{"(c", "(d", "[]"},
+		{"-", "(c", "[a b]"},
+		{"[f", "+", "[f g]"},
+		{"[bb", "[dd", "[c c d]"},
+		{"[e", "[c", "[]"},
+		{"+", "-", "[]"},
+		{"+", "+", "[]"},
+		{"-", "-", "[]"},
+	}
+	for _, c := range cases {
+		list, err := sl.GetByLex(c.min, c.max)
+		if err != nil || fmt.Sprint(list) != c.want {
+			t.Fatalf("GetByLex(%s, %s) = %v, %v want %s", c.min, c.max, list, err, c.want)
+		}
+		n, err := sl.CountLex(c.min, c.max)
+		if err != nil || n != len(list) {
+			t.Fatalf("CountLex(%s, %s) = %d, %v want %d", c.min, c.max, n, err, len(list))
+		}
+	}
+	for _, spec := range []string{"a", "", "*"} {
+		if _, err := sl.GetByLex(spec, "+"); !errors.Is(err, lexBoundErr) {
+			t.Fatalf("GetByLex(%q) want lexBoundErr, got %v", spec, err)
+		}
+		if _, err := sl.CountLex("-", spec); !errors.Is(err, lexBoundErr) {
+			t.Fatalf("CountLex(%q) want lexBoundErr, got %v", spec, err)
+		}
+	}
+	empty, _ := New(Ordered[string](), WithLexKey[string]())
+	if list, err := empty.GetByLex("-", "+"); err != nil || len(list) != 0 {
+		t.Fatalf("空跳表 GetByLex = %v, %v", list, err)
+	}
+	if list, err := empty.GetByLex("+", "+"); err != nil || len(list) != 0 {
+		t.Fatalf("空跳表 GetByLex = %v, %v", list, err)
+	}
+	if n, err := empty.CountPrefix("a"); err != nil || n != 0 {
+		t.Fatalf("空跳表 CountPrefix = %d, %v", n, err)
+	}
+}
+
+// 未指定key类型或比较接口不能比较该类型时返回 lexKeyErr，不会 panic
+func Test_LexKey(t *testing.T) {
+	sl, _ := New(Ordered[string]())
+	sl.Insert("a", "a")
+	if _, err := sl.GetByLex("-", "+"); !errors.Is(err, lexKeyErr) {
+		t.Fatalf("GetByLex want lexKeyErr, got %v", err)
+	}
+	if _, err := sl.CountLex("-", "+"); !errors.Is(err, lexKeyErr) {
+		t.Fatalf("CountLex want lexKeyErr, got %v", err)
+	}
+	if _, err := sl.CountPrefix("a"); !errors.Is(err, lexKeyErr) {
+		t.Fatalf("CountPrefix want lexKeyErr, got %v", err)
+	}
+	if err := sl.PrefixScan("a", func(key, data interface{}) bool { return true }); !errors.Is(err, lexKeyErr) {
+		t.Fatalf("PrefixScan want lexKeyErr, got %v", err)
+	}
+	var cmp *CmpInstanceInt
+	cases := []struct {
+		c                     CompareAble
+		withString, withBytes bool
+	}{
+		{Ordered[string](), true, false},
+		{Bytes(), false, true},
+		{cmp, false, false},
+		{nil, false, false},
+	}
+	for _, c := range cases {
+		if _, err := New(c.c, WithLexKey[string]()); (err == nil) != c.withString || (err != nil && !errors.Is(err, lexKeyErr)) {
+			t.Fatalf("%T WithLexKey[string] = %v", c.c, err)
+		}
+		if _, err := New(c.c, WithLexKey[[]byte]()); (err == nil) != c.withBytes || (err != nil && !errors.Is(err, lexKeyErr)) {
+			t.Fatalf("%T WithLexKey[[]byte] = %v", c.c, err)
+		}
+	}
+}
+
+// 随机key与排序后的切片比较
+func Test_LexRandom(t *testing.T) {
+	rd := rand.New(rand.NewSource(7))
+	sl, _ := New(Ordered[string](), WithLexKey[string]())
+	keys := []string{}
+	randKey := func() string {
+		b := make([]byte, 1+rd.Intn(4))
+		for i := range b {
+			b[i] = "abc"[rd.Intn(3)]
+		}
+		return string(b)
+	}
+	for i := 0; i < 2000; i++ {
+		k := randKey()
+		sl.Insert(k, k)
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i := 0; i < 200; i++ {
+		prefix := randKey()
+		want := 0
+		for _, k := range keys {
+			if strings.HasPrefix(k, prefix) {
+				want++
+			}
+		}
+		if n, _ := sl.CountPrefix(prefix); n != want {
+			t.Fatalf("CountPrefix(%q) = %d want %d", prefix, n, want)
+		}
+		lo, hi := randKey(), randKey()
+		want = 0
+		for _, k := range keys {
+			if k > lo && k <= hi {
+				want++
+			}
+		}
+		if n, _ := sl.CountLex("("+lo, "["+hi); n != want {
+			t.Fatalf("CountLex(%s, %s) = %d want %d", lo, hi, n, want)
+		}
+		if list, _ := sl.GetByLex("("+lo, "["+hi); len(list) != want {
+			t.Fatalf("GetByLex(%s, %s) = %d want %d", lo, hi, len(list), want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package skiplist
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	bufferSizeErr     = errors.New("buffer size must grater than 0")
+	overflowPolicyErr = errors.New("unknown overflow policy")
+)
+
+// 变更事件类型
+type EventType int
+
+const (
+	EventInsert EventType = iota + 1 //插入
+	EventDelete                      //删除
+	EventUpdate                      //更新数据
+)
+
+// 变更事件  Rank 为插入后、删除前、更新时结点所在的排位
+type Event struct {
+	Type    EventType
+	Key     interface{}
+	Data    interface{} //插入、更新后的数据; 删除时为nil
+	OldData interface{} //更新前、删除前的数据; 插入时为nil
+	Rank    int
+}
+
+/*
+观察者回调，未设置的回调不会被调用
+回调在变更操作内同步执行，不能在回调中修改同一个跳表; Apply 的回调在全部操作提交之后执行
+*/
+type Observer struct {
+	OnInsert func(key, data interface{}, rank int)
+	OnDelete func(key, data interface{}, rank int)
+	OnUpdate func(key, oldData, data interface{}, rank int)
+}
+
+// 订阅通道满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowDrop  OverflowPolicy = iota //丢弃新事件并计数
+	OverflowBlock                       //阻塞变更操作直到消费者取走事件
+)
+
+// 事件订阅
+type Subscription struct {
+	C       <-chan Event //事件通道，取消订阅后关闭
+	ch      chan Event
+	policy  OverflowPolicy
+	dropped atomic.Uint64
+	mu      sync.RWMutex //发送时持读锁，关闭通道时持写锁
+	done    chan struct{}
+	once    sync.Once
+	closed  bool
+	sl      *SkipList
+}
+
+// 观察者与订阅者登记表
+type hookRegistry struct {
+	mu        sync.Mutex
+	nextID    int
+	count     atomic.Int32    //观察者与订阅者总数，用于无人关注时跳过事件构造
+	observers []observerEntry //按添加顺序回调
+	subs      []*Subscription
+}
+
+type observerEntry struct {
+	id       int
+	observer Observer
+}
+
+// 添加观察者，返回的id用于移除
+func (sl *SkipList) AddObserver(observer Observer) int {
+	h := &sl.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextID++
+	h.observers = append(h.observers, observerEntry{id: h.nextID, observer: observer})
+	h.count.Add(1)
+	return h.nextID
+}
+
+// 移除观察者
+func (sl *SkipList) RemoveObserver(id int) bool {
+	h := &sl.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k := range h.observers {
+		if h.observers[k].id == id {
+			h.observers = append(h.observers[:k:k], h.observers[k+1:]...)
+			h.count.Add(-1)
+			return true
+		}
+	}
+	return false
+}
+
+/*
+订阅变更事件
+bufferSize 为通道缓冲区大小，缓冲区满时按 policy 丢弃或阻塞。
+使用 OverflowBlock 时消费者需要持续读取，否则会阻塞跳表的变更操作
+*/
+func (sl *SkipList) Subscribe(bufferSize int, policy OverflowPolicy) (*Subscription, error) {
+	if bufferSize < 1 {
+		return nil, bufferSizeErr
+	}
+	if policy != OverflowDrop && policy != OverflowBlock {
+		return nil, overflowPolicyErr
+	}
+	ch := make(chan Event, bufferSize)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		policy: policy,
+		done:   make(chan struct{}),
+		sl:     sl,
+	}
+	h := &sl.hooks
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs = append(h.subs, sub)
+	h.count.Add(1)
+	return sub, nil
+}
+
+// 取消订阅并关闭事件通道，可重复调用
+func (s *Subscription) Cancel() {
+	s.once.Do(func() {
+		h := &s.sl.hooks
+		h.mu.Lock()
+		for k := range h.subs {
+			if h.subs[k] == s {
+				h.subs = append(h.subs[:k:k], h.subs[k+1:]...)
+				h.count.Add(-1)
+				break
+			}
+		}
+		h.mu.Unlock()
+		close(s.done) //唤醒阻塞中的发送
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// 因缓冲区满被丢弃的事件数
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// 发送事件
+func (s *Subscription) send(event Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	if s.policy == OverflowBlock {
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// 是否存在观察者或订阅者
+func (sl *SkipList) hasObservers() bool {
+	return sl.hooks.count.Load() > 0
+}
+
+// 通知观察者与订阅者  批量操作执行期间先缓存，提交后再发送
+func (sl *SkipList) notify(eventType EventType, key, data, oldData interface{}, rank int) {
+	if sl.muted || !sl.hasObservers() {
+		return
+	}
+	event := Event{Type: eventType, Key: key, Data: data, OldData: oldData, Rank: rank}
+	if sl.deferEvents {
+		sl.pending = append(sl.pending, event)
+		return
+	}
+	sl.dispatch(event)
+}
+
+// 发送一个事件
+func (sl *SkipList) dispatch(event Event) {
+	h := &sl.hooks
+	h.mu.Lock()
+	//移除时会生成新的切片，这里持有的快照不受并发移除影响
+	observers, subs := h.observers, h.subs
+	h.mu.Unlock()
+
+	for k := range observers {
+		switch o := observers[k].observer; event.Type {
+		case EventInsert:
+			if o.OnInsert != nil {
+				o.OnInsert(event.Key, event.Data, event.Rank)
+			}
+		case EventDelete:
+			if o.OnDelete != nil {
+				o.OnDelete(event.Key, event.OldData, event.Rank)
+			}
+		case EventUpdate:
+			if o.OnUpdate != nil {
+				o.OnUpdate(event.Key, event.OldData, event.Data, event.Rank)
+			}
+		}
+	}
+	for k := range subs {
+		subs[k].send(event)
+	}
+}
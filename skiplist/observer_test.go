@@ -0,0 +1,115 @@
+package skiplist
+
+import (
+	"testing"
+	"time"
+)
+
+// Test_Observer 测试观察者回调的key、数据与rank
+func Test_Observer(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	var events []Event
+	id := sl.AddObserver(Observer{
+		OnInsert: func(key, data interface{}, rank int) {
+			events = append(events, Event{Type: EventInsert, Key: key, Data: data, Rank: rank})
+		},
+		OnDelete: func(key, data interface{}, rank int) {
+			events = append(events, Event{Type: EventDelete, Key: key, OldData: data, Rank: rank})
+		},
+		OnUpdate: func(key, oldData, data interface{}, rank int) {
+			events = append(events, Event{Type: EventUpdate, Key: key, Data: data, OldData: oldData, Rank: rank})
+		},
+	})
+	sl.Insert(CmpInstanceInt(5), "a")
+	sl.Insert(CmpInstanceInt(1), "b")
+	sl.Insert(CmpInstanceInt(5), "c")
+	sl.Insert(CmpInstanceInt(3), "d")
+	sl.UpdateBatchByKey(CmpInstanceInt(5), "e")
+	sl.DeleteByRank(2)
+	sl.DeleteBatchByKey(CmpInstanceInt(5))
+
+	want := []Event{
+		{EventInsert, CmpInstanceInt(5), "a", nil, 1},
+		{EventInsert, CmpInstanceInt(1), "b", nil, 1},
+		{EventInsert, CmpInstanceInt(5), "c", nil, 3},
+		{EventInsert, CmpInstanceInt(3), "d", nil, 2},
+		{EventUpdate, CmpInstanceInt(5), "e", "a", 3},
+		{EventUpdate, CmpInstanceInt(5), "e", "c", 4},
+		{EventDelete, CmpInstanceInt(3), nil, "d", 2},
+		{EventDelete, CmpInstanceInt(5), nil, "e", 2},
+		{EventDelete, CmpInstanceInt(5), nil, "e", 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("事件数量 %d != %d: %v", len(events), len(want), events)
+	}
+	for k := range want {
+		if events[k] != want[k] {
+			t.Fatalf("第 %d 个事件 %v != %v", k, events[k], want[k])
+		}
+	}
+
+	if !sl.RemoveObserver(id) || sl.RemoveObserver(id) {
+		t.Fatal("移除观察者结果错误")
+	}
+	sl.Insert(CmpInstanceInt(9), "f")
+	if len(events) != len(want) {
+		t.Fatal("移除后不应再回调")
+	}
+}
+
+// Test_SubscribeDrop 测试缓冲区满时丢弃事件
+func Test_SubscribeDrop(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	sub, _ := sl.Subscribe(2, OverflowDrop)
+	for i := 0; i < 5; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	if sub.Dropped() != 3 {
+		t.Fatalf("应丢弃 3 个事件, got: %d", sub.Dropped())
+	}
+	if e := <-sub.C; e.Type != EventInsert || e.Data != 0 || e.Rank != 1 {
+		t.Fatalf("第一个事件错误: %v", e)
+	}
+	sub.Cancel()
+	sub.Cancel()
+	sl.Insert(CmpInstanceInt(10), 10)
+	if _, ok := <-sub.C; !ok {
+		t.Fatal("取消前缓冲的事件应可读取")
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("取消订阅后通道应关闭")
+	}
+	if _, err := sl.Subscribe(0, OverflowDrop); err != bufferSizeErr {
+		t.Fatalf("缓冲区大小校验错误: %v", err)
+	}
+}
+
+// Test_SubscribeBlock 测试阻塞策略不丢事件，且取消订阅能解除阻塞
+func Test_SubscribeBlock(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	sub, _ := sl.Subscribe(1, OverflowBlock)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			sl.Insert(CmpInstanceInt(i), i)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if e := <-sub.C; e.Data != i {
+			t.Fatalf("第 %d 个事件错误: %v", i, e)
+		}
+	}
+	sub.Cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("取消订阅后写入方仍被阻塞")
+	}
+	if sub.Dropped() != 0 || sl.GetLength() != 100 {
+		t.Fatalf("dropped: %d length: %d", sub.Dropped(), sl.GetLength())
+	}
+}
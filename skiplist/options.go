@@ -3,6 +3,7 @@ package skiplist
 import (
 	"errors"
 	"math/rand"
+	"reflect"
 )
 
 var (
@@ -11,6 +12,8 @@ var (
 	randErr        = errors.New("*rand.Rand is nil")
 	cacheErr       = errors.New("cache size  must grater than 0")
 	cacheParamsErr = errors.New("cache params can not greater than cache size")
+	slabSizeErr    = errors.New("slab size must not less than 0")
+	weightErr      = errors.New("weight func is nil")
 )
 
 type Option func(*SkipList) error
@@ -72,3 +75,100 @@ func WithAllowTheSameKey(allow bool) Option {
 		return nil
 	}
 }
+
+//设置随机采样使用的随机数  默认使用 math/rand 的全局随机数
+//(层数生成在单独的 goroutine 中使用 WithLevelRandSource 设置的随机数，两者不能共用)
+func WithSampleRandSource(rd *rand.Rand) Option {
+	return func(sl *SkipList) error {
+		if rd == nil {
+			return randErr
+		}
+		sl.sampleRd = rd
+		return nil
+	}
+}
+
+//设置加权采样的权重函数  设置后各层维护到下一个结点的权重和，RandomWeighted、SampleWeightedN 为 O(log n)
+//权重小于0时按0处理，数据的权重需要保持不变 (通过 Update 系列方法修改数据时会重新计算)
+func WithWeight(weight func(data interface{}) float64) Option {
+	return func(sl *SkipList) error {
+		if weight == nil {
+			return weightErr
+		}
+		sl.weigher = weight
+		return nil
+	}
+}
+
+//开启查找统计  记录查找次数、比较次数及指针跳转次数，可通过 Stats 获取
+func WithInstrumentation(enable bool) Option {
+	return func(sl *SkipList) error {
+		if enable {
+			sl.counters = &searchCounters{}
+		} else {
+			sl.counters = nil
+		}
+		return nil
+	}
+}
+
+//开启 finger search  按key插入、删除及查找时从上一次按key插入或删除的路径开始查找，适合按key基本有序访问的场景
+func WithFingerSearch(enable bool) Option {
+	return func(sl *SkipList) error {
+		if enable {
+			sl.finger = &Hint{}
+		} else {
+			sl.finger = nil
+		}
+		return nil
+	}
+}
+
+//开启结点分配器  结点按块分配并复用删除的结点，slabSize 为每块的结点数，为0时使用默认值
+//开启后被删除的结点会被复用，迭代器在删除操作之后不能继续使用
+func WithArena(slabSize int) Option {
+	return func(sl *SkipList) error {
+		if slabSize < 0 {
+			return slabSizeErr
+		}
+		if slabSize == 0 {
+			slabSize = defaultArenaSlabSize
+		}
+		sl.arena = newArena(slabSize)
+		return nil
+	}
+}
+
+//使用确定性跳表 (1-2-3 skip list)  不再随机生成层数，插入删除时调整结点层高，查找、插入、删除在最坏情况下为 O(log n)
+//开启后 WithProbability、WithLevelRandSource、WithLevelCacheSize 不生效; WithMaxLevel 同样不生效，最大层数固定为 64
+func WithDeterministic(enable bool) Option {
+	return func(sl *SkipList) error {
+		sl.deterministic = enable
+		return nil
+	}
+}
+
+//设置 JSON 解码时 key 与数据的类型  未设置时 key 与数据按 encoding/json 的默认类型解码 (数字为 float64)
+func WithTypes[K, V any]() Option {
+	return func(sl *SkipList) error {
+		sl.keyType = reflect.TypeOf((*K)(nil)).Elem()
+		sl.dataType = reflect.TypeOf((*V)(nil)).Elem()
+		return nil
+	}
+}
+
+//设置前缀及字典序区间查找的key类型 (string 或 []byte)  比较接口不能比较该类型的key时返回错误
+func WithLexKey[K string | []byte]() Option {
+	return func(sl *SkipList) error {
+		var key K
+		kind := lexString
+		if _, ok := any(key).([]byte); ok {
+			kind = lexBytes
+		}
+		if sl.compareAble == nil || !sl.canCompare(key) {
+			return lexKeyErr
+		}
+		sl.lex = kind
+		return nil
+	}
+}
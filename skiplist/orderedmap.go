@@ -0,0 +1,118 @@
+package skiplist
+
+/*
+有序映射
+基于不允许重复key的跳表，提供 Set/Get/Has/Delete 等映射语义，能区分key不存在与数据为nil。
+与 SkipList 相同，OrderedMap 不是并发安全的
+*/
+type OrderedMap struct {
+	sl *SkipList
+}
+
+// 创建有序映射  options 中的 WithAllowTheSameKey 会被忽略，映射的key总是唯一的
+func NewOrderedMap(compareAble CompareAble, options ...Option) (*OrderedMap, error) {
+	sl, err := New(compareAble, append(options[:len(options):len(options)], WithAllowTheSameKey(false))...)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderedMap{sl: sl}, nil
+}
+
+// 查找key，返回查找路径及key所在的结点 (不存在时为nil)  路径可用于随后的插入或删除
+func (m *OrderedMap) seek(key interface{}) (*searchPath, *skipListNode) {
+	path := m.sl.scratchPath()
+	m.sl.seekPath(path, key, false)
+	if node := path.prev[0].level[0].next; node != nil && m.sl.equals(node.key, key) {
+		return path, node
+	}
+	return path, nil
+}
+
+// 设置key的数据，返回之前的数据及key是否已存在
+func (m *OrderedMap) Set(key, data interface{}) (interface{}, bool) {
+	path, node := m.seek(key)
+	if node == nil {
+		m.sl.linkNode(path, m.sl.nodeGenerate(key, data))
+		return nil, false
+	}
+	old := node.data
+	m.sl.updateByNode(node, data)
+	return old, true
+}
+
+// 获取key的数据
+func (m *OrderedMap) Get(key interface{}) (interface{}, bool) {
+	if node := m.sl.searchRandOneByKey(key); node != nil {
+		return node.data, true
+	}
+	return nil, false
+}
+
+// key是否存在
+func (m *OrderedMap) Has(key interface{}) bool {
+	return m.sl.searchRandOneByKey(key) != nil
+}
+
+// 删除key，返回被删除的数据及key是否存在
+func (m *OrderedMap) Delete(key interface{}) (interface{}, bool) {
+	path, node := m.seek(key)
+	if node == nil {
+		return nil, false
+	}
+	data := node.data
+	m.sl.freeNode(m.sl.unlinkNext(path))
+	return data, true
+}
+
+// key存在时返回已有数据及true，否则插入data并返回data及false
+func (m *OrderedMap) GetOrInsert(key, data interface{}) (interface{}, bool) {
+	path, node := m.seek(key)
+	if node != nil {
+		return node.data, true
+	}
+	m.sl.linkNode(path, m.sl.nodeGenerate(key, data))
+	return data, false
+}
+
+/*
+计算并更新key的数据
+fn 接收旧数据及key是否存在，返回新数据及是否保留: 保留时插入或更新，不保留时删除已存在的key。
+fn 中可以读写映射，fn 返回后重新查找key，再按返回值修改。返回最终的数据及key是否存在
+*/
+func (m *OrderedMap) Compute(key interface{}, fn func(old interface{}, exists bool) (interface{}, bool)) (interface{}, bool) {
+	_, node := m.seek(key)
+	var old interface{}
+	if node != nil {
+		old = node.data
+	}
+	data, keep := fn(old, node != nil)
+	//fn 可能修改了映射，之前的查找路径及结点已经失效
+	path, node := m.seek(key)
+	switch {
+	case keep && node != nil:
+		m.sl.updateByNode(node, data)
+	case keep:
+		m.sl.linkNode(path, m.sl.nodeGenerate(key, data))
+	case node != nil:
+		m.sl.freeNode(m.sl.unlinkNext(path))
+		return nil, false
+	default:
+		return nil, false
+	}
+	return data, true
+}
+
+// key的数量
+func (m *OrderedMap) Len() int {
+	return m.sl.GetLength()
+}
+
+// 关闭映射  结束底层跳表的层数生成协程，关闭后仍可读写
+func (m *OrderedMap) Close() {
+	m.sl.Close()
+}
+
+// 底层跳表，可用于排名、区间查询及迭代  不应通过它插入重复key
+func (m *OrderedMap) SkipList() *SkipList {
+	return m.sl
+}
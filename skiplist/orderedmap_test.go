@@ -0,0 +1,141 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_OrderedMap(t *testing.T) {
+	var cmp *CmpInstanceInt
+	m, err := NewOrderedMap(cmp, WithAllowTheSameKey(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, ok := m.Set(CmpInstanceInt(1), "a"); ok || old != nil {
+		t.Fatalf("Set new key = %v, %v", old, ok)
+	}
+	if old, ok := m.Set(CmpInstanceInt(1), "b"); !ok || old != "a" {
+		t.Fatalf("Set existing key = %v, %v", old, ok)
+	}
+	//nil数据与不存在的key可以区分
+	m.Set(CmpInstanceInt(2), nil)
+	if v, ok := m.Get(CmpInstanceInt(2)); !ok || v != nil {
+		t.Fatalf("Get nil data = %v, %v", v, ok)
+	}
+	if _, ok := m.Get(CmpInstanceInt(3)); ok || m.Has(CmpInstanceInt(3)) || !m.Has(CmpInstanceInt(2)) {
+		t.Fatal("Get/Has mismatch")
+	}
+	if v, loaded := m.GetOrInsert(CmpInstanceInt(1), "c"); !loaded || v != "b" {
+		t.Fatalf("GetOrInsert existing = %v, %v", v, loaded)
+	}
+	if v, loaded := m.GetOrInsert(CmpInstanceInt(3), "c"); loaded || v != "c" {
+		t.Fatalf("GetOrInsert new = %v, %v", v, loaded)
+	}
+	if m.Len() != 3 {
+		t.Fatalf("Len = %d", m.Len())
+	}
+	if v, ok := m.Delete(CmpInstanceInt(1)); !ok || v != "b" {
+		t.Fatalf("Delete = %v, %v", v, ok)
+	}
+	if _, ok := m.Delete(CmpInstanceInt(1)); ok {
+		t.Fatal("Delete missing key should fail")
+	}
+	//不允许重复key
+	if _, ok := m.SkipList().Insert(CmpInstanceInt(2), 1); ok {
+		t.Fatal("OrderedMap must not allow the same key")
+	}
+	checkStructure(t, m.SkipList())
+	//关闭后仍可读写
+	m.Close()
+	m.Set(CmpInstanceInt(4), "d")
+	if v, ok := m.Get(CmpInstanceInt(4)); !ok || v != "d" || m.Len() != 3 {
+		t.Fatalf("Get after Close = %v, %v", v, ok)
+	}
+	checkStructure(t, m.SkipList())
+}
+
+func Test_OrderedMapCompute(t *testing.T) {
+	var cmp *CmpInstanceInt
+	m, _ := NewOrderedMap(cmp)
+	incr := func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, true
+		}
+		return old.(int) + 1, true
+	}
+	for i := 0; i < 3; i++ {
+		m.Compute(CmpInstanceInt(7), incr)
+	}
+	if v, ok := m.Get(CmpInstanceInt(7)); !ok || v != 3 {
+		t.Fatalf("Compute incr = %v, %v", v, ok)
+	}
+	if v, ok := m.Compute(CmpInstanceInt(7), func(old interface{}, exists bool) (interface{}, bool) {
+		return nil, false
+	}); ok || v != nil || m.Has(CmpInstanceInt(7)) {
+		t.Fatal("Compute should delete the key")
+	}
+	if _, ok := m.Compute(CmpInstanceInt(8), func(old interface{}, exists bool) (interface{}, bool) {
+		return 1, false
+	}); ok || m.Len() != 0 {
+		t.Fatal("Compute without keep should not insert")
+	}
+	for i := 0; i < 20; i++ {
+		m.Set(CmpInstanceInt(i), i)
+	}
+	//fn 中修改映射
+	if v, ok := m.Compute(CmpInstanceInt(10), func(old interface{}, exists bool) (interface{}, bool) {
+		m.Delete(CmpInstanceInt(10))
+		m.Delete(CmpInstanceInt(9))
+		m.Set(CmpInstanceInt(11), "b")
+		return "a", true
+	}); !ok || v != "a" || m.Len() != 19 {
+		t.Fatalf("Compute with fn writing the map = %v, %v, len %d", v, ok, m.Len())
+	}
+	if v, _ := m.Get(CmpInstanceInt(10)); v != "a" {
+		t.Fatalf("Get after Compute = %v", v)
+	}
+	if v, _ := m.Get(CmpInstanceInt(11)); v != "b" {
+		t.Fatalf("Get key set in fn = %v", v)
+	}
+	if _, ok := m.Compute(CmpInstanceInt(12), func(old interface{}, exists bool) (interface{}, bool) {
+		m.Compute(CmpInstanceInt(12), func(interface{}, bool) (interface{}, bool) { return nil, false })
+		return nil, false
+	}); ok || m.Has(CmpInstanceInt(12)) || m.Len() != 18 {
+		t.Fatal("Compute should not delete twice")
+	}
+	checkStructure(t, m.SkipList())
+}
+
+// Test_OrderedMapRandom 与 map 对比
+func Test_OrderedMapRandom(t *testing.T) {
+	var cmp *CmpInstanceInt
+	m, _ := NewOrderedMap(cmp)
+	want := map[CmpInstanceInt]int{}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		key := CmpInstanceInt(r.Intn(200))
+		switch r.Intn(3) {
+		case 0:
+			old, ok := m.Set(key, i)
+			if w, exists := want[key]; ok != exists || (ok && old != w) {
+				t.Fatalf("Set %d = %v, %v want %v, %v", key, old, ok, w, exists)
+			}
+			want[key] = i
+		case 1:
+			old, ok := m.Delete(key)
+			if w, exists := want[key]; ok != exists || (ok && old != w) {
+				t.Fatalf("Delete %d = %v, %v want %v, %v", key, old, ok, w, exists)
+			}
+			delete(want, key)
+		default:
+			v, ok := m.Get(key)
+			if w, exists := want[key]; ok != exists || (ok && v != w) {
+				t.Fatalf("Get %d = %v, %v want %v, %v", key, v, ok, w, exists)
+			}
+		}
+	}
+	checkStructure(t, m.SkipList())
+	if m.Len() != len(want) {
+		t.Fatalf("Len = %d want %d", m.Len(), len(want))
+	}
+}
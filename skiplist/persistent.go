@@ -0,0 +1,486 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sort"
+)
+
+/*
+不可变 (持久化) 跳表
+每次 Insert、Delete、Update 都返回新版本，旧版本保持不变且可以继续读取，新旧版本共享未修改的部分。
+
+普通跳表中一个结点会被多个前置结点的不同层指向，无法只复制查找路径。这里把跳表按层拆分为分组:
+第level层的分组从一个高度大于level的结点开始，到下一个此类结点之前为止，该结点在第level+1层的项指向这个分组。
+每个分组只被上一层的一个项引用，修改时只需复制查找路径经过的分组 (期望长度为 1/probability)，
+写操作复制 O(log n) 个分组。每一项记录子树中的结点数用于排名查询。
+
+分组创建后不再修改，不同版本可以在多个 goroutine 中同时读取、同时派生新版本
+*/
+type PersistentSkipList struct {
+	root          []persistentEntry //最高层的分组，第一项为头结点
+	levels        int               //当前层数
+	length        int               //结点数量
+	constMaxLevel int               //能生成的最大层数
+	probability   float64           //层数生成概率
+	allowSameKey  bool              //是否允许存在相同的key
+	compareAble   CompareAble
+}
+
+// 分组中的一项  第0层为结点本身，更高层为结点在该层的索引
+type persistentEntry struct {
+	key   interface{}
+	data  interface{}       //只在第0层保存
+	child []persistentEntry //下一层从该结点开始的分组，第0层为nil
+	count int               //子树中的结点数，头结点本身不计数
+	head  bool              //是否为头结点
+}
+
+type PersistentOption func(*PersistentSkipList) error
+
+// 设置持久化跳表的最大层数
+func WithPersistentMaxLevel(level int) PersistentOption {
+	return func(p *PersistentSkipList) error {
+		if level < 1 {
+			return levelErr
+		}
+		p.constMaxLevel = level
+		return nil
+	}
+}
+
+// 设置持久化跳表的层数生成概率
+func WithPersistentProbability(probability float64) PersistentOption {
+	return func(p *PersistentSkipList) error {
+		if probability <= 0 || probability >= 1 {
+			return probabilityErr
+		}
+		p.probability = probability
+		return nil
+	}
+}
+
+// 设置持久化跳表是否允许相同的key  如果为false，插入相同key时不生效
+func WithPersistentAllowTheSameKey(allow bool) PersistentOption {
+	return func(p *PersistentSkipList) error {
+		p.allowSameKey = allow
+		return nil
+	}
+}
+
+// 创建空的持久化跳表
+func NewPersistent(compareAble CompareAble, options ...PersistentOption) (*PersistentSkipList, error) {
+	p := &PersistentSkipList{
+		root:          []persistentEntry{{head: true}},
+		levels:        1,
+		constMaxLevel: defaultMaxLevel,
+		probability:   defaultProbability,
+		allowSameKey:  true,
+		compareAble:   compareAble,
+	}
+	for k := range options {
+		if err := options[k](p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// 生成层数  使用 math/rand 的全局随机数，多个 goroutine 可以同时派生新版本
+func (p *PersistentSkipList) levelGenerate() int {
+	level := 1
+	for level < p.constMaxLevel && rand.Float64() < p.probability {
+		level++
+	}
+	return level
+}
+
+// 分组中的结点数
+func groupCount(group []persistentEntry) int {
+	count := 0
+	for k := range group {
+		count += group[k].count
+	}
+	return count
+}
+
+// 复制分组并在下标i处插入一项
+func groupInsert(group []persistentEntry, i int, entry persistentEntry) []persistentEntry {
+	next := make([]persistentEntry, 0, len(group)+1)
+	next = append(next, group[:i]...)
+	next = append(next, entry)
+	return append(next, group[i:]...)
+}
+
+/*
+分组中最后一个位于key之前的项的下标
+inclusive 为 true 时为最后一个小于等于key的项，否则为最后一个小于key的项
+*/
+func (p *PersistentSkipList) lastBefore(group []persistentEntry, key interface{}, inclusive bool) int {
+	return sort.Search(len(group), func(k int) bool {
+		if group[k].head {
+			return false
+		}
+		c := p.compareAble.Compare(group[k].key, key)
+		return c > 0 || (c == 0 && !inclusive)
+	}) - 1
+}
+
+// 查找第0层中位于key之前的最后一项及其rank  不存在时为头结点及0
+func (p *PersistentSkipList) seek(key interface{}, inclusive bool) (*persistentEntry, int) {
+	group, rank := p.root, 0
+	for level := p.levels - 1; ; level-- {
+		i := p.lastBefore(group, key, inclusive)
+		for k := 0; k < i; k++ {
+			rank += group[k].count
+		}
+		if level == 0 {
+			return &group[i], rank + group[i].count
+		}
+		group = group[i].child
+	}
+}
+
+// 通过rank查找第0层的项
+func (p *PersistentSkipList) entryByRank(rk int) *persistentEntry {
+	if rk < 1 || rk > p.length {
+		return nil
+	}
+	group := p.root
+	for level := p.levels - 1; ; level-- {
+		for k := range group {
+			if rk > group[k].count {
+				rk -= group[k].count
+				continue
+			}
+			if level == 0 {
+				return &group[k]
+			}
+			group = group[k].child
+			break
+		}
+	}
+}
+
+// 从分组内第start个结点开始按顺序遍历第0层的项  fn 返回 false 时停止
+func walk(group []persistentEntry, level, start int, fn func(entry *persistentEntry) bool) bool {
+	for k := range group {
+		if start > group[k].count {
+			start -= group[k].count
+			continue
+		}
+		if level == 0 {
+			if !fn(&group[k]) {
+				return false
+			}
+		} else if !walk(group[k].child, level-1, start, fn) {
+			return false
+		}
+		start = 1
+	}
+	return true
+}
+
+// 从第start个结点开始遍历
+func (p *PersistentSkipList) walkFrom(start int, fn func(entry *persistentEntry) bool) {
+	if start < 1 {
+		start = 1
+	}
+	if start <= p.length {
+		walk(p.root, p.levels-1, start, fn)
+	}
+}
+
+// 以新的根分组派生版本
+func (p *PersistentSkipList) derive(root []persistentEntry, levels, length int) *PersistentSkipList {
+	next := *p
+	next.root, next.levels, next.length = root, levels, length
+	//删除后去掉只剩头结点的最高层
+	for next.levels > 1 && len(next.root) == 1 {
+		next.root = next.root[0].child
+		next.levels--
+	}
+	return &next
+}
+
+/*
+在分组中插入高度为height的结点，返回复制后的分组
+结点高度大于level+1时，分组在新结点处拆分，返回的right为从新结点开始的分组
+*/
+func (p *PersistentSkipList) insert(group []persistentEntry, level, height int, key, data interface{}, rank *int) (left, right []persistentEntry) {
+	i := p.lastBefore(group, key, true)
+	for k := 0; k < i; k++ {
+		*rank += group[k].count
+	}
+	var next []persistentEntry
+	if level == 0 {
+		*rank += group[i].count + 1
+		next = groupInsert(group, i+1, persistentEntry{key: key, data: data, count: 1})
+	} else {
+		childLeft, childRight := p.insert(group[i].child, level-1, height, key, data, rank)
+		next = make([]persistentEntry, len(group))
+		copy(next, group)
+		next[i].child, next[i].count = childLeft, groupCount(childLeft)
+		if childRight != nil {
+			next = groupInsert(next, i+1, persistentEntry{key: key, child: childRight, count: groupCount(childRight)})
+		}
+	}
+	if height > level+1 {
+		return next[: i+1 : i+1], next[i+1:]
+	}
+	return next, nil
+}
+
+// 合并同一层相邻的两个分组并去掉right的第一个结点 (被删除的结点)
+func merge(left, right []persistentEntry, level int) []persistentEntry {
+	next := make([]persistentEntry, 0, len(left)+len(right)-1)
+	next = append(next, left...)
+	if level > 0 {
+		last := &next[len(next)-1]
+		last.child = merge(last.child, right[0].child, level-1)
+		last.count += right[0].count - 1
+	}
+	return append(next, right[1:]...)
+}
+
+// 删除分组内第rk个结点 (rk相对于分组)，返回复制后的分组
+func (p *PersistentSkipList) deleteRank(group []persistentEntry, level, rk int) []persistentEntry {
+	k := 0
+	for ; rk > group[k].count; k++ {
+		rk -= group[k].count
+	}
+	next := make([]persistentEntry, 0, len(group))
+	switch {
+	case level == 0:
+		next = append(append(next, group[:k]...), group[k+1:]...)
+	case rk == 1 && !group[k].head:
+		//被删除的结点在本层有索引，它在下一层的分组并入前一项的分组
+		next = append(next, group[:k]...)
+		prev := &next[k-1]
+		prev.child = merge(prev.child, group[k].child, level-1)
+		prev.count += group[k].count - 1
+		next = append(next, group[k+1:]...)
+	default:
+		next = append(next, group...)
+		next[k].child = p.deleteRank(group[k].child, level-1, rk)
+		next[k].count--
+	}
+	return next
+}
+
+// 替换分组内第rk个结点的数据，返回复制后的分组
+func updateRank(group []persistentEntry, level, rk int, data interface{}) []persistentEntry {
+	k := 0
+	for ; rk > group[k].count; k++ {
+		rk -= group[k].count
+	}
+	next := make([]persistentEntry, len(group))
+	copy(next, group)
+	if level == 0 {
+		next[k].data = data
+	} else {
+		next[k].child = updateRank(group[k].child, level-1, rk, data)
+	}
+	return next
+}
+
+// 第一个等于key的结点的rank  不存在时返回-1
+func (p *PersistentSkipList) firstRank(key interface{}) int {
+	_, rank := p.seek(key, false)
+	if entry := p.entryByRank(rank + 1); entry != nil && p.compareAble.Compare(entry.key, key) == 0 {
+		return rank + 1
+	}
+	return -1
+}
+
+// 最后一个等于key的结点的rank  不存在时返回-1
+func (p *PersistentSkipList) tailRank(key interface{}) int {
+	entry, rank := p.seek(key, true)
+	if !entry.head && p.compareAble.Compare(entry.key, key) == 0 {
+		return rank
+	}
+	return -1
+}
+
+/*
+	写操作  返回新版本，原版本不变
+*/
+
+/*
+插入数据
+不允许重复key时，重复的key添加将会返回原版本和false
+返回新版本、当前排名和插入结果
+*/
+func (p *PersistentSkipList) Insert(key, data interface{}) (*PersistentSkipList, int, bool) {
+	if !p.allowSameKey && p.tailRank(key) > 0 {
+		return p, 0, false
+	}
+	root, levels := p.root, p.levels
+	height := p.levelGenerate()
+	for ; levels < height; levels++ {
+		root = []persistentEntry{{head: true, child: root, count: p.length}}
+	}
+	rank := 0
+	root, _ = p.insert(root, levels-1, height, key, data, &rank)
+	return p.derive(root, levels, p.length+1), rank, true
+}
+
+// 通过rank删除
+func (p *PersistentSkipList) DeleteByRank(rank int) (*PersistentSkipList, bool) {
+	if rank < 1 || rank > p.length {
+		return p, false
+	}
+	return p.derive(p.deleteRank(p.root, p.levels-1, rank), p.levels, p.length-1), true
+}
+
+// 通过key删除  无重复key时删除成功
+func (p *PersistentSkipList) DeleteByKey(key interface{}) (*PersistentSkipList, bool) {
+	first := p.firstRank(key)
+	if first < 0 || p.tailRank(key) != first {
+		return p, false
+	}
+	return p.DeleteByRank(first)
+}
+
+// 删除所有和key相同的结点 (删除结点数大于0时返回true)
+func (p *PersistentSkipList) DeleteBatchByKey(key interface{}) (*PersistentSkipList, bool) {
+	first := p.firstRank(key)
+	if first < 0 {
+		return p, false
+	}
+	next := p
+	for k := p.tailRank(key); k >= first; k-- {
+		next, _ = next.DeleteByRank(first)
+	}
+	return next, true
+}
+
+// 通过rank更新数据
+func (p *PersistentSkipList) UpdateByRank(rank int, data interface{}) (*PersistentSkipList, bool) {
+	if rank < 1 || rank > p.length {
+		return p, false
+	}
+	return p.derive(updateRank(p.root, p.levels-1, rank, data), p.levels, p.length), true
+}
+
+// 通过key更新  无重复key下更新成功
+func (p *PersistentSkipList) UpdateByKey(key, data interface{}) (*PersistentSkipList, bool) {
+	first := p.firstRank(key)
+	if first < 0 || p.tailRank(key) != first {
+		return p, false
+	}
+	return p.UpdateByRank(first, data)
+}
+
+/*
+	读操作  与 SkipList 相同
+*/
+
+// 获取结点数量
+func (p *PersistentSkipList) GetLength() int {
+	return p.length
+}
+
+// 获取第一个结点数据
+func (p *PersistentSkipList) GetFirst() interface{} {
+	return p.GetByRank(1)
+}
+
+// 获取最后一个节点数据
+func (p *PersistentSkipList) GetTail() interface{} {
+	return p.GetByRank(p.length)
+}
+
+// 通过key搜索相等的第一个结点数据
+func (p *PersistentSkipList) GetFirstByKey(key interface{}) interface{} {
+	data, _ := p.GetFirstWithRankByKey(key)
+	return data
+}
+
+// 通过key搜索相等的最后一个结点数据
+func (p *PersistentSkipList) GetTailByKey(key interface{}) interface{} {
+	data, _ := p.GetTailWithRankByKey(key)
+	return data
+}
+
+// 通过key搜索相等的某一个结点数据  持久化跳表返回第一个
+func (p *PersistentSkipList) GetRandByKey(key interface{}) interface{} {
+	return p.GetFirstByKey(key)
+}
+
+// 通过key搜索所有结点数据
+func (p *PersistentSkipList) GetAllByKey(key interface{}) []interface{} {
+	data := []interface{}{}
+	if first := p.firstRank(key); first > 0 {
+		p.walkFrom(first, func(entry *persistentEntry) bool {
+			if p.compareAble.Compare(entry.key, key) != 0 {
+				return false
+			}
+			data = append(data, entry.data)
+			return true
+		})
+	}
+	return data
+}
+
+// 获取指定key的任意相等结点数据及所在的排位  持久化跳表返回第一个
+func (p *PersistentSkipList) GetRandWithRankByKey(key interface{}) (interface{}, int) {
+	return p.GetFirstWithRankByKey(key)
+}
+
+// 获取指定key的第一个相等结点数据及所在的排位
+func (p *PersistentSkipList) GetFirstWithRankByKey(key interface{}) (interface{}, int) {
+	if rk := p.firstRank(key); rk > 0 {
+		return p.GetByRank(rk), rk
+	}
+	return nil, -1
+}
+
+// 获取指定key的最后一个相等结点数据及所在的排位
+func (p *PersistentSkipList) GetTailWithRankByKey(key interface{}) (interface{}, int) {
+	entry, rank := p.seek(key, true)
+	if !entry.head && p.compareAble.Compare(entry.key, key) == 0 {
+		return entry.data, rank
+	}
+	return nil, -1
+}
+
+// 获取第一个大于等于key的结点数据及所在的排位  不存在时返回 nil,-1
+func (p *PersistentSkipList) GetCeilingWithRankByKey(key interface{}) (interface{}, int) {
+	_, rank := p.seek(key, false)
+	if entry := p.entryByRank(rank + 1); entry != nil {
+		return entry.data, rank + 1
+	}
+	return nil, -1
+}
+
+// 获取指定排位的数据
+func (p *PersistentSkipList) GetByRank(rk int) interface{} {
+	if entry := p.entryByRank(rk); entry != nil {
+		return entry.data
+	}
+	return nil
+}
+
+// 获取指定排位区间的数据
+func (p *PersistentSkipList) GetByRankRange(start, end int) []interface{} {
+	data := []interface{}{}
+	if start < 1 {
+		start = 1
+	}
+	p.walkFrom(start, func(entry *persistentEntry) bool {
+		if start > end {
+			return false
+		}
+		data = append(data, entry.data)
+		start++
+		return true
+	})
+	return data
+}
+
+// 按顺序遍历所有结点  fn 返回 false 时停止遍历
+func (p *PersistentSkipList) Range(fn func(key, data interface{}) bool) {
+	p.walkFrom(1, func(entry *persistentEntry) bool {
+		return fn(entry.key, entry.data)
+	})
+}
@@ -0,0 +1,179 @@
+package skiplist
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_Persistent(t *testing.T) {
+	var cmp *CmpInstanceInt
+	empty, _ := NewPersistent(cmp)
+	v1, rk, ok := empty.Insert(CmpInstanceInt(5), "a")
+	if !ok || rk != 1 {
+		t.Fatalf("Insert = %d, %v", rk, ok)
+	}
+	v2, rk, _ := v1.Insert(CmpInstanceInt(3), "b")
+	v3, _, _ := v2.Insert(CmpInstanceInt(5), "c")
+	v4, rk, _ := v3.Insert(CmpInstanceInt(4), "d")
+	if rk != 2 {
+		t.Fatalf("Insert 4 rank = %d", rk)
+	}
+	v5, ok := v4.DeleteByKey(CmpInstanceInt(5))
+	if ok || v5 != v4 {
+		t.Fatal("重复key时 DeleteByKey 应当失败")
+	}
+	v5, ok = v4.DeleteBatchByKey(CmpInstanceInt(5))
+	if !ok {
+		t.Fatal("DeleteBatchByKey 失败")
+	}
+	v6, _ := v5.UpdateByKey(CmpInstanceInt(3), "e")
+	versions := []struct {
+		p    *PersistentSkipList
+		want string
+	}{
+		{empty, "[]"},
+		{v1, "[a]"},
+		{v2, "[b a]"},
+		{v3, "[b a c]"},
+		{v4, "[b d a c]"},
+		{v5, "[b d]"},
+		{v6, "[e d]"},
+	}
+	for k, v := range versions {
+		if got := fmt.Sprint(v.p.GetByRankRange(1, 10)); got != v.want || v.p.GetLength() != len(v.p.GetByRankRange(1, 10)) {
+			t.Fatalf("版本 %d = %s want %s", k, got, v.want)
+		}
+	}
+	if data, rk := v4.GetFirstWithRankByKey(CmpInstanceInt(5)); data != "a" || rk != 3 {
+		t.Fatalf("GetFirstWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := v4.GetTailWithRankByKey(CmpInstanceInt(5)); data != "c" || rk != 4 {
+		t.Fatalf("GetTailWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := v4.GetCeilingWithRankByKey(CmpInstanceInt(2)); data != "b" || rk != 1 {
+		t.Fatalf("GetCeilingWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := v4.GetCeilingWithRankByKey(CmpInstanceInt(6)); data != nil || rk != -1 {
+		t.Fatalf("GetCeilingWithRankByKey = %v, %d", data, rk)
+	}
+	if got := fmt.Sprint(v4.GetAllByKey(CmpInstanceInt(5))); got != "[a c]" {
+		t.Fatalf("GetAllByKey = %s", got)
+	}
+	if v4.GetFirst() != "b" || v4.GetTail() != "c" || v4.GetByRank(2) != "d" || empty.GetFirst() != nil {
+		t.Fatal("GetFirst/GetTail/GetByRank 错误")
+	}
+	if data, rk := v5.GetFirstWithRankByKey(CmpInstanceInt(5)); data != nil || rk != -1 {
+		t.Fatalf("删除后 GetFirstWithRankByKey = %v, %d", data, rk)
+	}
+
+	unique, _ := NewPersistent(cmp, WithPersistentAllowTheSameKey(false))
+	u1, _, _ := unique.Insert(CmpInstanceInt(1), 1)
+	if u2, _, ok := u1.Insert(CmpInstanceInt(1), 2); ok || u2 != u1 {
+		t.Fatal("不允许重复key时插入应当失败")
+	}
+	if _, err := NewPersistent(cmp, WithPersistentProbability(1)); err != probabilityErr {
+		t.Fatalf("want probabilityErr, got %v", err)
+	}
+	if _, err := NewPersistent(cmp, WithPersistentMaxLevel(0)); err != levelErr {
+		t.Fatalf("want levelErr, got %v", err)
+	}
+}
+
+// 随机操作并保留所有历史版本，与排序后的切片比较
+func Test_PersistentRandom(t *testing.T) {
+	var cmp *CmpInstanceInt
+	rd := rand.New(rand.NewSource(3))
+	p, _ := NewPersistent(cmp, WithPersistentProbability(0.25))
+	type item struct{ key, data int }
+	ref := []item{}
+	type version struct {
+		p   *PersistentSkipList
+		ref []item
+	}
+	history := []version{}
+	for i := 0; i < 3000; i++ {
+		next := append([]item{}, ref...)
+		key := rd.Intn(200)
+		switch op := rd.Intn(10); {
+		case op < 5:
+			var rk int
+			p, rk, _ = p.Insert(CmpInstanceInt(key), i)
+			pos := sort.Search(len(next), func(k int) bool { return next[k].key > key })
+			next = append(next[:pos], append([]item{{key, i}}, next[pos:]...)...)
+			if rk != pos+1 {
+				t.Fatalf("Insert rank = %d want %d", rk, pos+1)
+			}
+		case op < 7 && len(next) > 0:
+			rk := 1 + rd.Intn(len(next))
+			p, _ = p.DeleteByRank(rk)
+			next = append(next[:rk-1], next[rk:]...)
+		case op < 8:
+			var ok bool
+			p, ok = p.DeleteBatchByKey(CmpInstanceInt(key))
+			lo := sort.Search(len(next), func(k int) bool { return next[k].key >= key })
+			hi := sort.Search(len(next), func(k int) bool { return next[k].key > key })
+			if ok != (hi > lo) {
+				t.Fatalf("DeleteBatchByKey = %v", ok)
+			}
+			next = append(next[:lo], next[hi:]...)
+		case len(next) > 0:
+			rk := 1 + rd.Intn(len(next))
+			p, _ = p.UpdateByRank(rk, -i)
+			next[rk-1].data = -i
+		}
+		ref = next
+		history = append(history, version{p, ref})
+	}
+	for _, v := range history {
+		if v.p.GetLength() != len(v.ref) {
+			t.Fatalf("长度 %d want %d", v.p.GetLength(), len(v.ref))
+		}
+		k := 0
+		v.p.Range(func(key, data interface{}) bool {
+			if int(key.(CmpInstanceInt)) != v.ref[k].key || data != v.ref[k].data {
+				t.Fatalf("第 %d 个结点 %v:%v want %v", k, key, data, v.ref[k])
+			}
+			k++
+			return true
+		})
+		if k != len(v.ref) {
+			t.Fatalf("遍历 %d 个结点 want %d", k, len(v.ref))
+		}
+	}
+	//最后一个版本的排名查询
+	for key := -1; key <= 200; key++ {
+		lo := sort.Search(len(ref), func(k int) bool { return ref[k].key >= key })
+		hi := sort.Search(len(ref), func(k int) bool { return ref[k].key > key })
+		_, first := p.GetFirstWithRankByKey(CmpInstanceInt(key))
+		_, tail := p.GetTailWithRankByKey(CmpInstanceInt(key))
+		if hi > lo && (first != lo+1 || tail != hi || len(p.GetAllByKey(CmpInstanceInt(key))) != hi-lo) {
+			t.Fatalf("key %d rank %d %d want %d %d", key, first, tail, lo+1, hi)
+		}
+		if hi == lo && (first != -1 || tail != -1) {
+			t.Fatalf("key %d 不存在时 rank %d %d", key, first, tail)
+		}
+		for rk := lo + 1; rk <= hi; rk++ {
+			if p.GetByRank(rk) != ref[rk-1].data {
+				t.Fatalf("GetByRank(%d) = %v want %v", rk, p.GetByRank(rk), ref[rk-1].data)
+			}
+		}
+	}
+	if got, want := len(p.GetByRankRange(10, 20)), min(11, max(0, len(ref)-9)); got != want {
+		t.Fatalf("GetByRankRange = %d want %d", got, want)
+	}
+}
+
+func Benchmark_PersistentInsert(b *testing.B) {
+	var cmp *CmpInstanceInt
+	p, _ := NewPersistent(cmp)
+	for i := 0; i < 1<<14; i++ {
+		p, _, _ = p.Insert(CmpInstanceInt(rand.Intn(1<<20)), i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Insert(CmpInstanceInt(rand.Intn(1<<20)), i)
+	}
+}
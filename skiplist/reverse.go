@@ -0,0 +1,168 @@
+package skiplist
+
+/*
+	逆序查询
+	逆序排名 revRank 从 1 ~ n，第1名为最后一个结点; 相同key的结点在逆序中同样倒序排列。
+	通过尾结点及第0层的前置指针向前遍历，不需要另建一个反向比较的跳表
+*/
+
+// 排名与逆序排名互相转换
+func (sl *SkipList) revRank(rank int) int {
+	if rank < 1 || rank > sl.length {
+		return -1
+	}
+	return sl.length - rank + 1
+}
+
+// 从逆序排名start开始按逆序获取到end为止的结点
+func (sl *SkipList) searchByRevRankRange(start, end int) []*skipListNode {
+	list := []*skipListNode{}
+	if start < 1 {
+		start = 1
+	}
+	if start > end || start > sl.length {
+		return list
+	}
+	node := sl.tail
+	if start > 1 {
+		node = sl.searchByRank(sl.length - start + 1)
+	}
+	for ; node != nil && start <= end; start, node = start+1, node.prev {
+		list = append(list, node)
+	}
+	return list
+}
+
+// 获取指定逆序排位的数据
+func (sl *SkipList) GetByRevRank(rk int) interface{} {
+	if rk < 1 || rk > sl.length {
+		return nil
+	}
+	return sl.GetByRank(sl.length - rk + 1)
+}
+
+// 获取指定逆序排位区间的数据  按逆序排列
+func (sl *SkipList) GetByRevRankRange(start, end int) []interface{} {
+	list := sl.searchByRevRankRange(start, end)
+	data := make([]interface{}, len(list))
+	for k := range list {
+		data[k] = list[k].data
+	}
+	return data
+}
+
+// 获取key的逆序排位  相同key时为逆序中的第一个 (即正序中的最后一个)，不存在时返回-1
+func (sl *SkipList) RevRank(key interface{}) int {
+	_, rk := sl.searchTailNodeAndRankByKey(key)
+	return sl.revRank(rk)
+}
+
+// 逆序视图  所有读操作按降序进行，排名为逆序排名; 视图不复制数据，跳表的修改对视图立即可见
+type ReverseView struct {
+	sl *SkipList
+}
+
+// 获取跳表的逆序视图
+func (sl *SkipList) Reverse() *ReverseView {
+	return &ReverseView{sl: sl}
+}
+
+// 获取原跳表
+func (v *ReverseView) SkipList() *SkipList {
+	return v.sl
+}
+
+// 获取结点数量
+func (v *ReverseView) GetLength() int {
+	return v.sl.length
+}
+
+// 获取逆序的第一个结点数据 (正序的最后一个)
+func (v *ReverseView) GetFirst() interface{} {
+	return v.sl.GetTail()
+}
+
+// 获取逆序的最后一个结点数据 (正序的第一个)
+func (v *ReverseView) GetTail() interface{} {
+	return v.sl.GetFirst()
+}
+
+// 通过key搜索逆序中相等的第一个结点数据
+func (v *ReverseView) GetFirstByKey(key interface{}) interface{} {
+	return v.sl.GetTailByKey(key)
+}
+
+// 通过key搜索逆序中相等的最后一个结点数据
+func (v *ReverseView) GetTailByKey(key interface{}) interface{} {
+	return v.sl.GetFirstByKey(key)
+}
+
+// 通过key搜索相等的某一个结点数据
+func (v *ReverseView) GetRandByKey(key interface{}) interface{} {
+	return v.sl.GetRandByKey(key)
+}
+
+// 通过key按逆序搜索所有结点数据
+func (v *ReverseView) GetAllByKey(key interface{}) []interface{} {
+	list := v.sl.searchAllByKey(key)
+	data := make([]interface{}, len(list))
+	for k := range list {
+		data[len(list)-1-k] = list[k].data
+	}
+	return data
+}
+
+// 获取指定key的任意相等结点数据及所在的逆序排位
+func (v *ReverseView) GetRandWithRankByKey(key interface{}) (interface{}, int) {
+	node, rk := v.sl.searchRandNodeAndRankByKey(key)
+	if node != nil {
+		return node.data, v.sl.revRank(rk)
+	}
+	return nil, -1
+}
+
+// 获取指定key在逆序中第一个相等结点数据及所在的逆序排位
+func (v *ReverseView) GetFirstWithRankByKey(key interface{}) (interface{}, int) {
+	node, rk := v.sl.searchTailNodeAndRankByKey(key)
+	if node != nil {
+		return node.data, v.sl.revRank(rk)
+	}
+	return nil, -1
+}
+
+// 获取指定key在逆序中最后一个相等结点数据及所在的逆序排位
+func (v *ReverseView) GetTailWithRankByKey(key interface{}) (interface{}, int) {
+	node, rk := v.sl.searchFirstNodeAndRankByKey(key)
+	if node != nil {
+		return node.data, v.sl.revRank(rk)
+	}
+	return nil, -1
+}
+
+// 获取逆序中第一个小于等于key的结点数据及所在的逆序排位 (正序中最后一个小于等于key的结点)  不存在时返回 nil,-1
+func (v *ReverseView) GetCeilingWithRankByKey(key interface{}) (interface{}, int) {
+	node, rk := v.sl.searchPrevNodeAndRank(key, true)
+	if node != v.sl.head {
+		return node.data, v.sl.revRank(rk)
+	}
+	return nil, -1
+}
+
+// 获取指定逆序排位的数据
+func (v *ReverseView) GetByRank(rk int) interface{} {
+	return v.sl.GetByRevRank(rk)
+}
+
+// 获取指定逆序排位区间的数据
+func (v *ReverseView) GetByRankRange(start, end int) []interface{} {
+	return v.sl.GetByRevRankRange(start, end)
+}
+
+// 按逆序遍历所有结点  fn 返回 false 时停止遍历
+func (v *ReverseView) Range(fn func(key, data interface{}) bool) {
+	for node := v.sl.tail; node != nil; node = node.prev {
+		if !fn(node.key, node.data) {
+			break
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func Test_Reverse(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	for _, kv := range [][2]int{{1, 1}, {3, 31}, {2, 2}, {3, 32}, {5, 5}, {3, 33}} {
+		sl.Insert(CmpInstanceInt(kv[0]), kv[1])
+	}
+	//正序: 1 2 31 32 33 5  逆序: 5 33 32 31 2 1
+	if got := sl.GetByRevRankRange(1, 10); !equalSlice(got, []interface{}{5, 33, 32, 31, 2, 1}) {
+		t.Fatalf("GetByRevRankRange = %v", got)
+	}
+	if got := sl.GetByRevRankRange(2, 4); !equalSlice(got, []interface{}{33, 32, 31}) {
+		t.Fatalf("GetByRevRankRange(2, 4) = %v", got)
+	}
+	if got := sl.GetByRevRankRange(4, 2); len(got) != 0 {
+		t.Fatalf("GetByRevRankRange(4, 2) = %v", got)
+	}
+	if sl.GetByRevRank(1) != 5 || sl.GetByRevRank(6) != 1 || sl.GetByRevRank(7) != nil || sl.GetByRevRank(0) != nil {
+		t.Fatal("GetByRevRank 错误")
+	}
+	if sl.RevRank(CmpInstanceInt(3)) != 2 || sl.RevRank(CmpInstanceInt(1)) != 6 || sl.RevRank(CmpInstanceInt(4)) != -1 {
+		t.Fatalf("RevRank 错误 %d %d", sl.RevRank(CmpInstanceInt(3)), sl.RevRank(CmpInstanceInt(1)))
+	}
+
+	view := sl.Reverse()
+	if view.SkipList() != sl || view.GetLength() != 6 || view.GetFirst() != 5 || view.GetTail() != 1 {
+		t.Fatal("逆序视图首尾错误")
+	}
+	if view.GetFirstByKey(CmpInstanceInt(3)) != 33 || view.GetTailByKey(CmpInstanceInt(3)) != 31 {
+		t.Fatal("逆序视图 GetFirstByKey/GetTailByKey 错误")
+	}
+	if got := view.GetAllByKey(CmpInstanceInt(3)); !equalSlice(got, []interface{}{33, 32, 31}) {
+		t.Fatalf("逆序视图 GetAllByKey = %v", got)
+	}
+	if data, rk := view.GetFirstWithRankByKey(CmpInstanceInt(3)); data != 33 || rk != 2 {
+		t.Fatalf("GetFirstWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := view.GetTailWithRankByKey(CmpInstanceInt(3)); data != 31 || rk != 4 {
+		t.Fatalf("GetTailWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := view.GetRandWithRankByKey(CmpInstanceInt(3)); rk < 2 || rk > 4 || view.GetByRank(rk) != data {
+		t.Fatalf("GetRandWithRankByKey = %v, %d", data, rk)
+	}
+	if data, rk := view.GetCeilingWithRankByKey(CmpInstanceInt(4)); data != 33 || rk != 2 {
+		t.Fatalf("GetCeilingWithRankByKey(4) = %v, %d", data, rk)
+	}
+	if data, rk := view.GetCeilingWithRankByKey(CmpInstanceInt(0)); data != nil || rk != -1 {
+		t.Fatalf("GetCeilingWithRankByKey(0) = %v, %d", data, rk)
+	}
+	var keys []int
+	view.Range(func(key, data interface{}) bool {
+		keys = append(keys, int(key.(CmpInstanceInt)))
+		return len(keys) < 4
+	})
+	if len(keys) != 4 || keys[0] != 5 || keys[3] != 3 {
+		t.Fatalf("逆序遍历 %v", keys)
+	}
+	//视图随跳表修改
+	sl.Insert(CmpInstanceInt(9), 9)
+	if view.GetFirst() != 9 || view.GetByRank(2) != 5 {
+		t.Fatal("视图未反映跳表的修改")
+	}
+	empty, _ := New(cmp)
+	if empty.Reverse().GetFirst() != nil || len(empty.GetByRevRankRange(1, 3)) != 0 || empty.RevRank(CmpInstanceInt(1)) != -1 {
+		t.Fatal("空跳表的逆序查询错误")
+	}
+}
+
+// Test_ReverseRandom 与使用反向比较的跳表对比 (key不重复)
+func Test_ReverseRandom(t *testing.T) {
+	rd := rand.New(rand.NewSource(9))
+	sl, _ := New(Ordered[int](), WithAllowTheSameKey(false))
+	desc, _ := New(Reverse(Ordered[int]()), WithAllowTheSameKey(false))
+	for i := 0; i < 3000; i++ {
+		key := rd.Intn(1000)
+		if rd.Intn(3) == 0 {
+			sl.DeleteByKey(key)
+			desc.DeleteByKey(key)
+		} else {
+			sl.Insert(key, key)
+			desc.Insert(key, key)
+		}
+	}
+	view := sl.Reverse()
+	n := sl.GetLength()
+	if got, want := view.GetByRankRange(1, n), desc.GetByRankRange(1, n); !equalSlice(got, want) {
+		t.Fatal("逆序视图与反向比较的跳表不一致")
+	}
+	for i := 0; i < 200; i++ {
+		key := rd.Intn(1100)
+		_, want := desc.GetFirstWithRankByKey(key)
+		if got := sl.RevRank(key); got != want {
+			t.Fatalf("RevRank(%d) = %d want %d", key, got, want)
+		}
+		got, grk := view.GetCeilingWithRankByKey(key)
+		exp, erk := desc.GetCeilingWithRankByKey(key)
+		if got != exp || grk != erk {
+			t.Fatalf("GetCeilingWithRankByKey(%d) = %v,%d want %v,%d", key, got, grk, exp, erk)
+		}
+		start := 1 + rd.Intn(n)
+		if got, want := sl.GetByRevRankRange(start, start+20), desc.GetByRankRange(start, start+20); !equalSlice(got, want) {
+			t.Fatalf("GetByRevRankRange(%d) = %v want %v", start, got, want)
+		}
+	}
+}
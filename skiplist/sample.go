@@ -0,0 +1,282 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sort"
+)
+
+/*
+随机采样
+均匀采样借助 rank 索引 (searchByRank) 定位结点，单次 O(log n)。
+加权采样需要通过 WithWeight 设置权重函数，各层维护到下一个结点的权重和 (与 span 对应，保存在只在设置权重函数时分配的 weights 中)，
+按权重和下降即可定位结点，单次 O(log n)。采样只读取跳表，不修改结构及权重
+*/
+
+func (sl *SkipList) randIntn(n int) int {
+	if sl.sampleRd != nil {
+		return sl.sampleRd.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+func (sl *SkipList) randFloat64() float64 {
+	if sl.sampleRd != nil {
+		return sl.sampleRd.Float64()
+	}
+	return rand.Float64()
+}
+
+// 均匀随机取一个结点数据及其排位  跳表为空时返回 nil,-1
+func (sl *SkipList) RandomElement() (interface{}, int) {
+	if sl.length == 0 {
+		return nil, -1
+	}
+	rank := sl.randIntn(sl.length) + 1
+	return sl.searchByRank(rank).data, rank
+}
+
+/*
+随机取多个结点数据  语义同 redis ZRANDMEMBER
+count > 0 时不重复取样，count 大于等于结点数量时返回全部数据，结果按排位升序;
+count < 0 时允许重复，返回 -count 个数据，结果为随机顺序
+*/
+func (sl *SkipList) SampleN(count int) []interface{} {
+	if count == 0 || sl.length == 0 {
+		return []interface{}{}
+	}
+	if count < 0 {
+		data := make([]interface{}, -count)
+		for k := range data {
+			data[k] = sl.searchByRank(sl.randIntn(sl.length) + 1).data
+		}
+		return data
+	}
+	if count >= sl.length {
+		return sl.GetByRankRange(1, sl.length)
+	}
+	ranks := sl.sampleRanks(count)
+	data := make([]interface{}, len(ranks))
+	//排位升序，前后两个排位较近时沿第0层走过去，避免重复从头查找
+	var node *skipListNode
+	for k := range ranks {
+		if node != nil && ranks[k]-ranks[k-1] <= sl.currentMaxLevel+1 {
+			for step := ranks[k] - ranks[k-1]; step > 0; step-- {
+				node = node.level[0].next
+			}
+		} else {
+			node = sl.searchByRank(ranks[k])
+		}
+		data[k] = node.data
+	}
+	return data
+}
+
+// Floyd 算法生成 count 个不重复的排位 (1~n)，升序返回
+func (sl *SkipList) sampleRanks(count int) []int {
+	chosen := make(map[int]struct{}, count)
+	ranks := make([]int, 0, count)
+	for j := sl.length - count + 1; j <= sl.length; j++ {
+		rank := sl.randIntn(j) + 1
+		if _, ok := chosen[rank]; ok {
+			rank = j
+		}
+		chosen[rank] = struct{}{}
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	return ranks
+}
+
+/*
+按权重随机取一个结点数据及其排位  权重由 WithWeight 设置的权重函数计算，O(log n)
+权重为0的结点不会被选中; 未设置权重函数或所有权重都为0时返回 nil,-1
+*/
+func (sl *SkipList) RandomWeighted() (interface{}, int) {
+	if sl.weigher == nil {
+		return nil, -1
+	}
+	total := sl.totalWeight()
+	if total <= 0 {
+		return nil, -1
+	}
+	node, rank := sl.seekWeightPath(sl.newSearchPath(), sl.randFloat64()*total, nil)
+	if node == nil {
+		return nil, -1
+	}
+	return node.data, rank
+}
+
+/*
+按权重不重复地随机取多个结点数据  O(count*log n)
+依次按权重取一个结点，已选中的结点通过局部的权重修正排除 (从覆盖它的各层权重和中减去其权重)，
+每个结点的入选概率与权重成正比，采样不修改跳表。
+权重为0的结点不会被选中，未设置权重函数时返回空，结果按排位升序
+*/
+func (sl *SkipList) SampleWeightedN(count int) []interface{} {
+	if count <= 0 || sl.weigher == nil {
+		return []interface{}{}
+	}
+	type picked struct {
+		node *skipListNode
+		rank int
+	}
+	list := []picked{}
+	overlay := map[weightSlot]float64{}
+	total := sl.totalWeight()
+	for len(list) < count && total > 0 {
+		path := sl.newSearchPath()
+		node, rank := sl.seekWeightPath(path, sl.randFloat64()*total, overlay)
+		if node == nil {
+			break
+		}
+		list = append(list, picked{node: node, rank: rank})
+		//第0层的权重和就是被选中结点的权重
+		weight := sl.overlayWeight(path.prev[0], 0, overlay)
+		for level := 0; level <= sl.currentMaxLevel; level++ {
+			overlay[weightSlot{path.prev[level], level}] -= weight
+		}
+		total -= weight
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].rank < list[j].rank })
+	data := make([]interface{}, len(list))
+	for k := range list {
+		data[k] = list[k].node.data
+	}
+	return data
+}
+
+// 权重修正的位置  结点及层
+type weightSlot struct {
+	node  *skipListNode
+	level int
+}
+
+// 由数据计算权重
+type weightFunc func(data interface{}) float64
+
+// 各结点每层到下一个结点的权重和，与 levelNode 一一对应
+type weightTable map[*skipListNode][]float64
+
+// 数据的权重  小于0时按0处理
+func (sl *SkipList) weightOf(data interface{}) float64 {
+	if w := sl.weigher(data); w > 0 {
+		return w
+	}
+	return 0
+}
+
+// 结点在第level层到下一个结点的权重和 (不含本结点，含下一个结点)
+func (sl *SkipList) weightAt(node *skipListNode, level int) float64 {
+	return sl.weights[node][level]
+}
+
+// 叠加局部修正后的权重和  overlay 为nil时即 weightAt
+func (sl *SkipList) overlayWeight(node *skipListNode, level int, overlay map[weightSlot]float64) float64 {
+	weight := sl.weights[node][level]
+	if overlay != nil {
+		weight += overlay[weightSlot{node, level}]
+	}
+	return weight
+}
+
+// 按下一层重新计算结点在第level层的权重和 (level > 0)
+func (sl *SkipList) sumWeight(node *skipListNode, level int) {
+	end := node.level[level].next
+	sum := 0.0
+	if end != nil {
+		for x := node; x != end; x = x.level[level-1].next {
+			sum += sl.weightAt(x, level-1)
+		}
+	}
+	sl.weights[node][level] = sum
+}
+
+// 权重为weight的结点插入到prev之后时，更新两者在第level层的权重和
+func (sl *SkipList) linkWeight(prev, node *skipListNode, level int, weight float64) {
+	if level == 0 {
+		sl.weights[node][0] = sl.weightAt(prev, 0)
+		sl.weights[prev][0] = weight
+		return
+	}
+	sl.sumWeight(prev, level)
+	sl.sumWeight(node, level)
+}
+
+// 摘除prev之后的结点时，更新prev在第level层的权重和
+func (sl *SkipList) unlinkWeight(prev, node *skipListNode, level int) {
+	if level == 0 {
+		sl.weights[prev][0] = sl.weightAt(node, 0)
+		return
+	}
+	sl.sumWeight(prev, level)
+}
+
+// 数据修改后重新计算覆盖该结点的各层权重和
+func (sl *SkipList) reweight(node *skipListNode) {
+	path := sl.scratchPath()
+	sl.seekRankPath(path, sl.rankOfNode(node))
+	sl.setWeight(path, sl.weightOf(node.data))
+}
+
+// 设置路径第0层前置结点的下一个结点的权重，并自底向上更新各层覆盖该结点的权重和
+func (sl *SkipList) setWeight(path *searchPath, weight float64) {
+	sl.weights[path.prev[0]][0] = weight
+	for level := 1; level <= sl.currentMaxLevel; level++ {
+		sl.sumWeight(path.prev[level], level)
+	}
+}
+
+// 所有结点的权重和  每层走到最后一个结点再向下一层
+func (sl *SkipList) totalWeight() float64 {
+	total := 0.0
+	node := sl.head
+	for level := sl.currentMaxLevel; level >= 0; level-- {
+		for next := node.level[level].next; next != nil; next = node.level[level].next {
+			total += sl.weightAt(node, level)
+			node = next
+		}
+	}
+	return total
+}
+
+/*
+按累计权重查找结点  同 searchByRank 按 span 下降，这里按各层的权重和下降
+返回累计权重首次超过target的结点及其rank，并记录其查找路径 (同 seekRankPath)
+overlay 为叠加在各层权重和上的局部修正，用于排除已选中的结点，为nil时不修正
+*/
+func (sl *SkipList) seekWeightPath(path *searchPath, target float64, overlay map[weightSlot]float64) (*skipListNode, int) {
+	node, rank := sl.head, 0
+	for level := sl.currentMaxLevel; level >= 0; level-- {
+		for next := node.level[level].next; next != nil; next = node.level[level].next {
+			weight := sl.overlayWeight(node, level, overlay)
+			if weight > target {
+				break
+			}
+			target -= weight
+			rank += node.level[level].span
+			node = next
+		}
+		path.prev[level], path.rank[level] = node, rank
+	}
+	if node.level[0].next != nil {
+		return node.level[0].next, rank + 1
+	}
+	//浮点误差导致越过了所有结点时，取最后一个权重大于0的结点
+	for node != sl.head && sl.overlayWeight(sl.prevNode(node), 0, overlay) <= 0 {
+		node, rank = sl.prevNode(node), rank-1
+	}
+	if node == sl.head {
+		return nil, -1
+	}
+	sl.resetPath(path)
+	sl.seekRankPath(path, rank)
+	return node, rank
+}
+
+// 第0层的前置结点  第一个结点的前置结点为头结点
+func (sl *SkipList) prevNode(node *skipListNode) *skipListNode {
+	if node.prev == nil {
+		return sl.head
+	}
+	return node.prev
+}
@@ -0,0 +1,275 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func newSampleList(t *testing.T, n int, options ...Option) *SkipList {
+	var cmp *CmpInstanceInt
+	sl, err := New(cmp, append([]Option{WithSampleRandSource(rand.New(rand.NewSource(1)))}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	return sl
+}
+
+// Test_RandomElement 测试均匀随机取样的分布
+func Test_RandomElement(t *testing.T) {
+	var cmp *CmpInstanceInt
+	empty, _ := New(cmp)
+	if data, rank := empty.RandomElement(); data != nil || rank != -1 {
+		t.Fatal("空跳表应返回 nil,-1")
+	}
+	sl := newSampleList(t, 10)
+	counts := make([]int, 11)
+	for i := 0; i < 20000; i++ {
+		data, rank := sl.RandomElement()
+		if data != rank {
+			t.Fatalf("数据与排位不一致 %v %d", data, rank)
+		}
+		counts[rank]++
+	}
+	for rank := 1; rank <= 10; rank++ {
+		if counts[rank] < 1700 || counts[rank] > 2300 {
+			t.Fatalf("排位 %d 的采样次数偏差过大: %d", rank, counts[rank])
+		}
+	}
+}
+
+// Test_SampleN 测试不重复与可重复采样
+func Test_SampleN(t *testing.T) {
+	sl := newSampleList(t, 100)
+	for _, count := range []int{1, 5, 30, 99} {
+		data := sl.SampleN(count)
+		if len(data) != count {
+			t.Fatalf("SampleN(%d) 返回 %d 个", count, len(data))
+		}
+		for k := 1; k < len(data); k++ {
+			if data[k].(int) <= data[k-1].(int) {
+				t.Fatalf("SampleN(%d) 结果应不重复且升序: %v", count, data)
+			}
+		}
+	}
+	if data := sl.SampleN(200); len(data) != 100 || data[0] != 1 || data[99] != 100 {
+		t.Fatal("count 大于结点数时应返回全部数据")
+	}
+	if data := sl.SampleN(-300); len(data) != 300 {
+		t.Fatalf("count 为负数时应返回 300 个, got: %d", len(data))
+	}
+	if len(sl.SampleN(0)) != 0 {
+		t.Fatal("count 为0时应返回空")
+	}
+
+	//每个结点被选中的概率应相同
+	hits := make([]int, 101)
+	for i := 0; i < 5000; i++ {
+		for _, data := range sl.SampleN(10) {
+			hits[data.(int)]++
+		}
+	}
+	for k := 1; k <= 100; k++ {
+		if hits[k] < 380 || hits[k] > 620 {
+			t.Fatalf("结点 %d 的采样次数偏差过大: %d", k, hits[k])
+		}
+	}
+}
+
+// Test_SampleWeighted 测试加权采样
+func Test_SampleWeighted(t *testing.T) {
+	//权重 0,1,2,3
+	weight := func(data interface{}) float64 { return float64(data.(int) - 1) }
+	sl := newSampleList(t, 4, WithWeight(weight))
+	counts := make([]int, 5)
+	for i := 0; i < 60000; i++ {
+		data, rank := sl.RandomWeighted()
+		if data != rank {
+			t.Fatalf("数据与排位不一致 %v %d", data, rank)
+		}
+		counts[rank]++
+	}
+	if counts[1] != 0 {
+		t.Fatal("权重为0的结点不应被选中")
+	}
+	for rank := 2; rank <= 4; rank++ {
+		want := 10000 * (rank - 1)
+		if counts[rank] < want*9/10 || counts[rank] > want*11/10 {
+			t.Fatalf("排位 %d 的采样次数偏差过大: %d want ~%d", rank, counts[rank], want)
+		}
+	}
+	zero := newSampleList(t, 4, WithWeight(func(interface{}) float64 { return 0 }))
+	if data, rank := zero.RandomWeighted(); data != nil || rank != -1 {
+		t.Fatal("权重全为0时应返回 nil,-1")
+	}
+	if data, rank := newSampleList(t, 4).RandomWeighted(); data != nil || rank != -1 {
+		t.Fatal("未设置权重函数时应返回 nil,-1")
+	}
+
+	hits := make([]int, 5)
+	for i := 0; i < 20000; i++ {
+		data := sl.SampleWeightedN(2)
+		if len(data) != 2 || data[0].(int) >= data[1].(int) {
+			t.Fatalf("结果应为 2 个升序且不重复的数据: %v", data)
+		}
+		for k := range data {
+			hits[data[k].(int)]++
+		}
+	}
+	if hits[1] != 0 || !(hits[4] > hits[3] && hits[3] > hits[2]) {
+		t.Fatalf("入选次数应随权重递增: %v", hits)
+	}
+	if data := sl.SampleWeightedN(10); len(data) != 3 {
+		t.Fatalf("只有 3 个结点权重大于0, got: %v", data)
+	}
+	checkWeights(t, sl)
+}
+
+// 校验各层的权重和
+func checkWeights(t *testing.T, sl *SkipList) {
+	t.Helper()
+	prefix := map[*skipListNode]float64{sl.head: 0}
+	sum := 0.0
+	for node := sl.head.level[0].next; node != nil; node = node.level[0].next {
+		sum += sl.weightOf(node.data)
+		prefix[node] = sum
+	}
+	if len(sl.weights) != sl.length+1 {
+		t.Fatalf("权重表结点数 %d != %d", len(sl.weights), sl.length+1)
+	}
+	for node := sl.head; node != nil; node = node.level[0].next {
+		if len(sl.weights[node]) != len(node.level) {
+			t.Fatalf("权重表层数 %d != %d", len(sl.weights[node]), len(node.level))
+		}
+	}
+	for level := range sl.head.level {
+		for node := sl.head; node != nil; node = node.level[level].next {
+			want := 0.0
+			if next := node.level[level].next; next != nil {
+				want = prefix[next] - prefix[node]
+			}
+			if diff := sl.weightAt(node, level) - want; diff > 1e-6 || diff < -1e-6 {
+				t.Fatalf("第 %d 层权重和错误 %v != %v", level, sl.weightAt(node, level), want)
+			}
+		}
+	}
+	if diff := sl.totalWeight() - sum; diff > 1e-6 || diff < -1e-6 {
+		t.Fatalf("总权重错误 %v != %v", sl.totalWeight(), sum)
+	}
+}
+
+// Test_WeightMaintain 测试插入、删除、更新及批量操作后各层权重和保持正确
+func Test_WeightMaintain(t *testing.T) {
+	weight := func(data interface{}) float64 { return float64(data.(int) % 10) }
+	modes := map[string][]Option{
+		"random":        nil,
+		"deterministic": {WithDeterministic(true)},
+		"arena":         {WithArena(16)},
+		"finger":        {WithFingerSearch(true)},
+	}
+	for name, options := range modes {
+		t.Run(name, func(t *testing.T) { testWeightMaintain(t, weight, options) })
+	}
+}
+
+func testWeightMaintain(t *testing.T, weight func(data interface{}) float64, options []Option) {
+	r := rand.New(rand.NewSource(1))
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, append([]Option{WithWeight(weight)}, options...)...)
+	for i := 0; i < 3000; i++ {
+		key := CmpInstanceInt(r.Intn(300))
+		switch r.Intn(6) {
+		case 0, 1, 2:
+			sl.Insert(key, r.Intn(100))
+		case 3:
+			sl.DeleteBatchByKey(key)
+		case 4:
+			sl.UpdateBatchByKey(key, r.Intn(100))
+		case 5:
+			if sl.GetLength() > 0 {
+				sl.DeleteByRank(r.Intn(sl.GetLength()) + 1)
+			}
+		}
+		if i%100 == 0 {
+			b := NewBatch()
+			b.Insert(CmpInstanceInt(r.Intn(300)), r.Intn(100))
+			b.Update(key, r.Intn(100))
+			sl.Apply(b)
+			sl.SampleWeightedN(5)
+			checkWeights(t, sl)
+		}
+	}
+	checkWeights(t, sl)
+	if data := sl.SampleWeightedN(sl.GetLength()); len(data) == 0 {
+		t.Fatal("加权采样结果为空")
+	}
+	checkWeights(t, sl)
+}
+
+// Test_SampleWeightedReadOnly 不重复加权采样不修改跳表的权重，未设置权重函数时不分配权重表
+func Test_SampleWeightedReadOnly(t *testing.T) {
+	if sl := newSampleList(t, 10); sl.weights != nil {
+		t.Fatal("未设置权重函数时不应分配权重表")
+	}
+	sl := newSampleList(t, 500, WithWeight(func(data interface{}) float64 { return float64(data.(int) % 7) }))
+	before := weightTable{}
+	for node, list := range sl.weights {
+		before[node] = append([]float64{}, list...)
+	}
+	for i := 0; i < 50; i++ {
+		data := sl.SampleWeightedN(100)
+		for k := 1; k < len(data); k++ {
+			if data[k-1].(int) >= data[k].(int) {
+				t.Fatalf("结果应升序且不重复: %v", data)
+			}
+		}
+	}
+	//权重大于0的结点全部选中
+	if data := sl.SampleWeightedN(500); len(data) != 500-500/7 {
+		t.Fatalf("权重大于0的结点数错误: %d", len(data))
+	}
+	for node, list := range sl.weights {
+		for level := range list {
+			if list[level] != before[node][level] {
+				t.Fatalf("采样修改了第 %d 层的权重和 %v != %v", level, list[level], before[node][level])
+			}
+		}
+	}
+	checkWeights(t, sl)
+}
+
+// Test_WeightPanic 权重函数出现异常时跳表保持不变
+func Test_WeightPanic(t *testing.T) {
+	sl := newSampleList(t, 0, WithWeight(func(data interface{}) float64 {
+		if data == "boom" {
+			panic("bad weight")
+		}
+		return 1
+	}))
+	for i := 0; i < 20; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	func() {
+		defer func() { recover() }()
+		sl.Insert(CmpInstanceInt(5), "boom")
+	}()
+	checkStructure(t, sl)
+	checkWeights(t, sl)
+	if sl.GetLength() != 20 {
+		t.Fatalf("长度错误: %d", sl.GetLength())
+	}
+}
+
+func Benchmark_RandomWeighted(b *testing.B) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, WithWeight(func(data interface{}) float64 { return float64(data.(int) % 100) }))
+	for i := 0; i < 100000; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sl.RandomWeighted()
+	}
+}
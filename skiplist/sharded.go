@@ -0,0 +1,512 @@
+package skiplist
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	shardBoundaryErr = errors.New("shard boundaries must be strictly ascending")
+	shardSizeErr     = errors.New("shard min size must less than half of max size")
+	shardBalanceErr  = errors.New("load balance window must grater than 0 and factor must grater than 1")
+)
+
+const defaultShardMaxSize = 1 << 16 //默认分片最大结点数
+
+// 分片  保存 [lower, 下一个分片的lower) 区间的key
+type shard struct {
+	mu     sync.RWMutex
+	sl     *SkipList
+	lower  interface{}  //区间下界 (包含)，第一个分片为nil表示无下界
+	length atomic.Int64 //结点数量，读取时不需要加分片锁
+	ops    atomic.Int64 //上次按访问量调整以来的访问次数
+}
+
+/*
+按key区间分片的跳表
+key空间被划分为若干连续区间，每个区间由一个独立加锁的 SkipList 保存，不同分片的写入可以并行。
+分片结点数超过上限时自动从中位key处拆分，低于下限时与相邻分片合并;
+开启 WithShardLoadBalance 后还会按访问量拆分热点分片、合并冷分片。拆分与合并期间阻塞所有操作，搬移结点不触发变更通知。
+通过各分片的结点数计算全局排名，并按分片顺序进行全局有序遍历。
+跨分片的查询 (排名、区间、遍历) 依次对各分片加锁，并发写入时不是全局快照
+*/
+type ShardedSkipList struct {
+	mu          sync.RWMutex //保护分片表，拆分与合并时加写锁
+	shards      []*shard
+	compareAble CompareAble
+	options     []Option     //创建分片跳表的参数
+	maxSize     int          //分片最大结点数，为0时不自动拆分
+	minSize     int          //分片最小结点数，为0时不自动合并
+	window      int64        //每隔多少次访问按访问量拆分与合并，为0时不按访问量调整
+	factor      float64      //访问次数超过平均值的factor倍时拆分，低于平均值的1/factor时合并
+	accesses    atomic.Int64 //按key读写的总次数
+	closed      bool         //关闭后拆分出的分片不再启动层数生成协程
+}
+
+type ShardOption func(*ShardedSkipList) error
+
+// 设置初始分片边界  n个严格升序的key划分出n+1个分片
+func WithShardBoundaries(keys ...interface{}) ShardOption {
+	return func(s *ShardedSkipList) error {
+		for k := 1; k < len(keys); k++ {
+			if s.compareAble.Compare(keys[k-1], keys[k]) >= 0 {
+				return shardBoundaryErr
+			}
+		}
+		s.shards = s.shards[:1]
+		for _, key := range keys {
+			s.shards = append(s.shards, &shard{lower: key})
+		}
+		return nil
+	}
+}
+
+// 设置自动拆分与合并的阈值  maxSize为0时不拆分，minSize为0时不合并
+func WithShardSize(minSize, maxSize int) ShardOption {
+	return func(s *ShardedSkipList) error {
+		if minSize < 0 || maxSize < 0 || (maxSize > 0 && minSize*2 >= maxSize) {
+			return shardSizeErr
+		}
+		s.minSize, s.maxSize = minSize, maxSize
+		return nil
+	}
+}
+
+/*
+开启按访问量拆分与合并
+每 window 次按key的读写统计一次各分片的访问次数: 超过平均值 factor 倍的热点分片从中位key处拆分，
+低于平均值 1/factor 的冷分片与访问较少的相邻分片合并 (合并后的结点数不超过上限，访问次数不超过平均值)。
+开启后不再按最小结点数合并，结点数上限仍然生效
+*/
+func WithShardLoadBalance(window int, factor float64) ShardOption {
+	return func(s *ShardedSkipList) error {
+		if window <= 0 || factor <= 1 {
+			return shardBalanceErr
+		}
+		s.window, s.factor = int64(window), factor
+		return nil
+	}
+}
+
+// 设置创建分片跳表的参数
+func WithShardListOptions(options ...Option) ShardOption {
+	return func(s *ShardedSkipList) error {
+		s.options = options
+		return nil
+	}
+}
+
+// 创建分片跳表
+func NewShardedSkipList(compareAble CompareAble, options ...ShardOption) (*ShardedSkipList, error) {
+	s := &ShardedSkipList{
+		shards:      []*shard{{}},
+		compareAble: compareAble,
+		maxSize:     defaultShardMaxSize,
+		minSize:     defaultShardMaxSize / 4,
+	}
+	for k := range options {
+		if err := options[k](s); err != nil {
+			return nil, err
+		}
+	}
+	for k, sd := range s.shards {
+		sl, err := New(compareAble, s.options...)
+		if err != nil {
+			for _, created := range s.shards[:k] {
+				created.sl.Close()
+			}
+			return nil, err
+		}
+		sd.sl = sl
+	}
+	return s, nil
+}
+
+// 关闭分片跳表  结束所有分片的层数生成协程，不再使用时需要调用; 关闭后仍可读写，之后拆分出的分片不启动协程
+func (s *ShardedSkipList) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, sd := range s.shards {
+		sd.sl.Close()
+	}
+}
+
+// key所在分片的下标  需要持有分片表的锁
+func (s *ShardedSkipList) shardIndex(key interface{}) int {
+	return sort.Search(len(s.shards)-1, func(i int) bool {
+		return s.compareAble.Compare(s.shards[i+1].lower, key) > 0
+	})
+}
+
+// 分片之前的结点数量  需要持有分片表的锁
+func (s *ShardedSkipList) offset(index int) int {
+	n := 0
+	for k := 0; k < index; k++ {
+		n += int(s.shards[k].length.Load())
+	}
+	return n
+}
+
+// 对key所在的分片加写锁执行fn，执行后按分片的结点数量拆分或合并
+func (s *ShardedSkipList) write(key interface{}, fn func(sd *shard, offset int)) {
+	s.mu.RLock()
+	index := s.shardIndex(key)
+	sd := s.shards[index]
+	sd.mu.Lock()
+	fn(sd, s.offset(index))
+	n := sd.sl.GetLength()
+	sd.length.Store(int64(n))
+	sd.mu.Unlock()
+	sd.ops.Add(1)
+	canMerge := s.window == 0 && n < s.minSize && s.mergeTarget(index) >= 0
+	s.mu.RUnlock()
+	if s.maxSize > 0 && n > s.maxSize {
+		s.split(sd)
+	} else if canMerge {
+		s.merge(sd)
+	}
+	s.access()
+}
+
+// 对key所在的分片加读锁执行fn
+func (s *ShardedSkipList) read(key interface{}, fn func(sd *shard, offset int)) {
+	defer s.access() //释放锁之后执行
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	index := s.shardIndex(key)
+	sd := s.shards[index]
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	sd.ops.Add(1)
+	fn(sd, s.offset(index))
+}
+
+// 记录一次访问，每 window 次按访问量拆分与合并
+func (s *ShardedSkipList) access() {
+	if s.window > 0 && s.accesses.Add(1)%s.window == 0 {
+		s.rebalance()
+	}
+}
+
+// 在分片表中查找分片  不存在时返回-1
+func (s *ShardedSkipList) indexOf(sd *shard) int {
+	for k := range s.shards {
+		if s.shards[k] == sd {
+			return k
+		}
+	}
+	return -1
+}
+
+// 结点数超过上限时拆分分片
+func (s *ShardedSkipList) split(sd *shard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index := s.indexOf(sd); index >= 0 && sd.sl.GetLength() > s.maxSize {
+		s.splitAt(index)
+	}
+}
+
+// 从中位key处拆分第index个分片  key相同的结点总是在同一个分片中，全部相同时不拆分; 需要持有分片表的写锁
+func (s *ShardedSkipList) splitAt(index int) bool {
+	sd := s.shards[index]
+	if sd.sl.GetLength() < 2 {
+		return false
+	}
+	mid := sd.sl.searchByRank(sd.sl.GetLength()/2 + 1)
+	first := sd.sl.searchFirstOneByKey(mid.key)
+	if first.prev == nil {
+		if last := sd.sl.searchTailOneByKey(mid.key); last.level[0].next != nil {
+			first = last.level[0].next
+		} else {
+			return false
+		}
+	}
+	sl, err := New(s.compareAble, s.options...)
+	if err != nil {
+		return false
+	}
+	if s.closed {
+		sl.Close()
+	}
+	right := &shard{sl: sl, lower: first.key}
+	var hint Hint
+	for node := first; node != nil; node = node.level[0].next {
+		right.sl.InsertAfterHint(&hint, node.key, node.data)
+	}
+	//从尾部删除移动到新分片的结点  结点只是搬移，不通知观察者
+	sd.sl.muted = true
+	for n := right.sl.GetLength(); n > 0; n-- {
+		sd.sl.deleteByRank(sd.sl.GetLength())
+	}
+	sd.sl.muted = false
+	sd.length.Store(int64(sd.sl.GetLength()))
+	right.length.Store(int64(right.sl.GetLength()))
+	//访问次数按结点数平分，避免拆分后立即被当作冷分片合并
+	right.ops.Store(sd.ops.Load() / 2)
+	sd.ops.Add(-right.ops.Load())
+	s.shards = append(s.shards, nil)
+	copy(s.shards[index+2:], s.shards[index+1:])
+	s.shards[index+1] = right
+	return true
+}
+
+/*
+选择与第index个分片合并的相邻分片，返回合并的两个分片中左侧分片的下标，不能合并时返回-1
+优先选择较小的相邻分片，合并后的结点数不能超过上限
+*/
+func (s *ShardedSkipList) mergeTarget(index int) int {
+	left := index - 1
+	if left < 0 || (index+1 < len(s.shards) && s.shards[index+1].length.Load() < s.shards[left].length.Load()) {
+		left = index
+	}
+	if left+1 >= len(s.shards) {
+		return -1
+	}
+	if s.maxSize > 0 && s.shards[left].length.Load()+s.shards[left+1].length.Load() > int64(s.maxSize) {
+		return -1
+	}
+	return left
+}
+
+// 结点数低于下限时与相邻分片合并
+func (s *ShardedSkipList) merge(sd *shard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.indexOf(sd)
+	if index < 0 || sd.sl.GetLength() >= s.minSize {
+		return
+	}
+	if left := s.mergeTarget(index); left >= 0 {
+		s.mergeAt(left)
+	}
+}
+
+// 将第left+1个分片合并到第left个分片  需要持有分片表的写锁
+func (s *ShardedSkipList) mergeAt(left int) {
+	dst, src := s.shards[left], s.shards[left+1]
+	//右侧分片的key都不小于左侧分片，按句柄追加; 结点只是搬移，不通知观察者
+	var hint Hint
+	dst.sl.muted = true
+	for node := src.sl.head.level[0].next; node != nil; node = node.level[0].next {
+		dst.sl.InsertAfterHint(&hint, node.key, node.data)
+	}
+	dst.sl.muted = false
+	dst.length.Store(int64(dst.sl.GetLength()))
+	dst.ops.Add(src.ops.Load())
+	//丢弃右侧分片的跳表，结束其层数生成协程
+	src.sl.Close()
+	s.shards = append(s.shards[:left+1], s.shards[left+2:]...)
+}
+
+// 按访问量拆分热点分片、合并冷分片，并重新开始统计
+func (s *ShardedSkipList) rebalance() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := int64(0)
+	for _, sd := range s.shards {
+		total += sd.ops.Load()
+	}
+	avg := float64(total) / float64(len(s.shards))
+	if cold := s.coldest(); float64(s.shards[cold].ops.Load())*s.factor < avg {
+		if left := s.coldMergeTarget(cold, avg); left >= 0 {
+			s.mergeAt(left)
+		}
+	}
+	if hot := s.hottest(); float64(s.shards[hot].ops.Load()) > avg*s.factor {
+		s.splitAt(hot)
+	}
+	for _, sd := range s.shards {
+		sd.ops.Store(0)
+	}
+}
+
+// 访问次数最少的分片
+func (s *ShardedSkipList) coldest() int {
+	index := 0
+	for k := range s.shards {
+		if s.shards[k].ops.Load() < s.shards[index].ops.Load() {
+			index = k
+		}
+	}
+	return index
+}
+
+// 访问次数最多的分片
+func (s *ShardedSkipList) hottest() int {
+	index := 0
+	for k := range s.shards {
+		if s.shards[k].ops.Load() > s.shards[index].ops.Load() {
+			index = k
+		}
+	}
+	return index
+}
+
+/*
+选择与冷分片合并的相邻分片，返回合并的两个分片中左侧分片的下标，不能合并时返回-1
+优先选择访问较少的相邻分片，合并后的结点数不能超过上限，访问次数不能超过平均值
+*/
+func (s *ShardedSkipList) coldMergeTarget(index int, avg float64) int {
+	left := index - 1
+	if left < 0 || (index+1 < len(s.shards) && s.shards[index+1].ops.Load() < s.shards[left].ops.Load()) {
+		left = index
+	}
+	if left+1 >= len(s.shards) {
+		return -1
+	}
+	a, b := s.shards[left], s.shards[left+1]
+	if s.maxSize > 0 && a.length.Load()+b.length.Load() > int64(s.maxSize) {
+		return -1
+	}
+	if float64(a.ops.Load()+b.ops.Load()) > avg {
+		return -1
+	}
+	return left
+}
+
+/*
+插入数据
+返回插入时的全局排名和插入结果
+*/
+func (s *ShardedSkipList) Insert(key, data interface{}) (int, bool) {
+	var rank int
+	var ok bool
+	s.write(key, func(sd *shard, offset int) {
+		rank, ok = sd.sl.Insert(key, data)
+		rank += offset
+	})
+	return rank, ok
+}
+
+// 删除所有和key相同的数据
+func (s *ShardedSkipList) DeleteBatchByKey(key interface{}) bool {
+	var ok bool
+	s.write(key, func(sd *shard, offset int) {
+		ok = sd.sl.DeleteBatchByKey(key)
+	})
+	return ok
+}
+
+// 删除和key相同的数据  当只有一个相同key的结点数据时能删除成功
+func (s *ShardedSkipList) DeleteByKey(key interface{}) bool {
+	var ok bool
+	s.write(key, func(sd *shard, offset int) {
+		ok = sd.sl.DeleteByKey(key)
+	})
+	return ok
+}
+
+// 更新和key相同的数据  当只有一个相同key的结点数据时能更新成功
+func (s *ShardedSkipList) UpdateByKey(key, data interface{}) bool {
+	var ok bool
+	s.write(key, func(sd *shard, offset int) {
+		ok = sd.sl.UpdateByKey(key, data)
+	})
+	return ok
+}
+
+// 获取和key相等的第一个数据
+func (s *ShardedSkipList) GetFirstByKey(key interface{}) interface{} {
+	var data interface{}
+	s.read(key, func(sd *shard, offset int) {
+		data = sd.sl.GetFirstByKey(key)
+	})
+	return data
+}
+
+// 获取所有和key相等的数据
+func (s *ShardedSkipList) GetAllByKey(key interface{}) []interface{} {
+	var data []interface{}
+	s.read(key, func(sd *shard, offset int) {
+		data = sd.sl.GetAllByKey(key)
+	})
+	return data
+}
+
+// 获取和key相等的第一个数据及其全局排名  不存在时排名为-1
+func (s *ShardedSkipList) GetFirstWithRankByKey(key interface{}) (interface{}, int) {
+	var data interface{}
+	rank := -1
+	s.read(key, func(sd *shard, offset int) {
+		if data, rank = sd.sl.GetFirstWithRankByKey(key); rank > 0 {
+			rank += offset
+		}
+	})
+	return data, rank
+}
+
+// 结点总数
+func (s *ShardedSkipList) GetLength() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.offset(len(s.shards))
+}
+
+// 分片数量
+func (s *ShardedSkipList) Shards() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.shards)
+}
+
+// 获取全局排名的数据
+func (s *ShardedSkipList) GetByRank(rk int) interface{} {
+	list := s.GetByRankRange(rk, rk)
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
+
+// 获取全局排名区间的数据
+func (s *ShardedSkipList) GetByRankRange(start, end int) []interface{} {
+	if start < 1 {
+		start = 1
+	}
+	data := []interface{}{}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	offset := 0
+	for _, sd := range s.shards {
+		if offset >= end {
+			break
+		}
+		sd.mu.RLock()
+		n := sd.sl.GetLength()
+		if offset+n >= start {
+			data = append(data, sd.sl.GetByRankRange(start-offset, end-offset)...)
+		}
+		sd.mu.RUnlock()
+		offset += n
+	}
+	return data
+}
+
+/*
+按key顺序遍历所有结点  fn 返回false时停止
+遍历期间持有当前分片的读锁，fn 中不能修改跳表
+*/
+func (s *ShardedSkipList) Range(fn func(key, data interface{}) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sd := range s.shards {
+		if !s.rangeShard(sd, fn) {
+			return
+		}
+	}
+}
+
+func (s *ShardedSkipList) rangeShard(sd *shard, fn func(key, data interface{}) bool) bool {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	for node := sd.sl.head.level[0].next; node != nil; node = node.level[0].next {
+		if !fn(node.key, node.data) {
+			return false
+		}
+	}
+	return true
+}
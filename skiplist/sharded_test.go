@@ -0,0 +1,259 @@
+package skiplist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 校验分片边界及各分片结构
+func checkShards(t *testing.T, s *ShardedSkipList) {
+	t.Helper()
+	for k, sd := range s.shards {
+		checkStructure(t, sd.sl)
+		if int(sd.length.Load()) != sd.sl.GetLength() {
+			t.Fatalf("分片 %d 长度 %d != %d", k, sd.length.Load(), sd.sl.GetLength())
+		}
+		for node := sd.sl.head.level[0].next; node != nil; node = node.level[0].next {
+			if k > 0 && s.compareAble.Compare(node.key, sd.lower) < 0 {
+				t.Fatalf("分片 %d 的key %v 小于下界 %v", k, node.key, sd.lower)
+			}
+			if k+1 < len(s.shards) && s.compareAble.Compare(node.key, s.shards[k+1].lower) >= 0 {
+				t.Fatalf("分片 %d 的key %v 不小于上界 %v", k, node.key, s.shards[k+1].lower)
+			}
+		}
+	}
+}
+
+func Test_ShardedOptions(t *testing.T) {
+	var cmp *CmpInstanceInt
+	if _, err := NewShardedSkipList(cmp, WithShardBoundaries(CmpInstanceInt(2), CmpInstanceInt(1))); err != shardBoundaryErr {
+		t.Fatalf("want shardBoundaryErr, got %v", err)
+	}
+	if _, err := NewShardedSkipList(cmp, WithShardSize(5, 10)); err != shardSizeErr {
+		t.Fatalf("want shardSizeErr, got %v", err)
+	}
+	s, err := NewShardedSkipList(cmp, WithShardBoundaries(CmpInstanceInt(10), CmpInstanceInt(20)), WithShardSize(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		s.Insert(CmpInstanceInt(i), i)
+	}
+	if s.Shards() != 3 {
+		t.Fatalf("Shards = %d want 3", s.Shards())
+	}
+	checkShards(t, s)
+}
+
+// Test_ShardedRandom 与单个跳表对比，阈值较小以触发拆分与合并
+func Test_ShardedRandom(t *testing.T) {
+	var cmp *CmpInstanceInt
+	r := rand.New(rand.NewSource(1))
+	s, _ := NewShardedSkipList(cmp, WithShardSize(4, 16))
+	want, _ := New(cmp)
+	maxShards := 0
+	for i := 0; i < 20000; i++ {
+		key := CmpInstanceInt(r.Intn(500))
+		switch op := r.Intn(10); {
+		case op < 5 || i < 2000:
+			rk, ok := s.Insert(key, i)
+			wrk, wok := want.Insert(key, i)
+			if rk != wrk || ok != wok {
+				t.Fatalf("插入 %d = %d,%v want %d,%v", key, rk, ok, wrk, wok)
+			}
+		case op < 9:
+			if s.DeleteBatchByKey(key) != want.DeleteBatchByKey(key) {
+				t.Fatalf("删除 %d 结果不一致", key)
+			}
+		default:
+			if s.UpdateByKey(key, -i) != want.UpdateByKey(key, -i) {
+				t.Fatalf("更新 %d 结果不一致", key)
+			}
+		}
+		if n := s.Shards(); n > maxShards {
+			maxShards = n
+		}
+		if i%500 != 0 {
+			continue
+		}
+		checkShards(t, s)
+		if s.GetLength() != want.GetLength() {
+			t.Fatalf("长度 %d want %d", s.GetLength(), want.GetLength())
+		}
+		if got, exp := s.GetByRankRange(1, s.GetLength()), want.GetByRankRange(1, want.GetLength()); !equalSlice(got, exp) {
+			t.Fatalf("step %d 结果不一致", i)
+		}
+		start := r.Intn(want.GetLength() + 1)
+		if got, exp := s.GetByRankRange(start, start+20), want.GetByRankRange(start, start+20); !equalSlice(got, exp) {
+			t.Fatalf("step %d 区间 %d 结果不一致", i, start)
+		}
+		if got, exp := s.GetByRank(start), want.GetByRank(start); got != exp {
+			t.Fatalf("step %d rank %d = %v want %v", i, start, got, exp)
+		}
+		data, rk := s.GetFirstWithRankByKey(key)
+		if wdata, wrk := want.GetFirstWithRankByKey(key); data != wdata || rk != wrk {
+			t.Fatalf("step %d key %d = %v,%d want %v,%d", i, key, data, rk, wdata, wrk)
+		}
+		var keys []interface{}
+		s.Range(func(key, data interface{}) bool {
+			keys = append(keys, data)
+			return true
+		})
+		if !equalSlice(keys, want.GetByRankRange(1, want.GetLength())) {
+			t.Fatalf("step %d 遍历结果不一致", i)
+		}
+	}
+	if maxShards < 10 {
+		t.Fatalf("分片没有拆分, 最多 %d 个分片", maxShards)
+	}
+	//全部删除后合并为一个分片
+	for i := 0; i < 500; i++ {
+		s.DeleteBatchByKey(CmpInstanceInt(i))
+	}
+	if s.GetLength() != 0 || s.Shards() != 1 {
+		t.Fatalf("全部删除后 长度 %d 分片 %d", s.GetLength(), s.Shards())
+	}
+}
+
+// Test_ShardedConcurrent 多个写入者并发写入
+func Test_ShardedConcurrent(t *testing.T) {
+	var cmp *CmpInstanceInt
+	s, _ := NewShardedSkipList(cmp, WithShardSize(16, 64))
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := CmpInstanceInt(i*8 + w)
+				s.Insert(key, int(key))
+				if i%3 == 0 {
+					s.DeleteByKey(key)
+				}
+				s.GetFirstByKey(key)
+			}
+		}(w)
+	}
+	wg.Wait()
+	checkShards(t, s)
+	prev := -1
+	n := 0
+	s.Range(func(key, data interface{}) bool {
+		if data.(int) <= prev || data.(int)/8%3 == 0 {
+			t.Fatalf("遍历结果错误 %v", data)
+		}
+		prev = data.(int)
+		n++
+		return true
+	})
+	if n != s.GetLength() || n != 8*2000-8*667 {
+		t.Fatalf("长度 %d %d", n, s.GetLength())
+	}
+}
+
+// Test_ShardedMove 拆分与合并搬移结点时不通知观察者，丢弃的跳表不残留层数生成协程
+func Test_ShardedMove(t *testing.T) {
+	var cmp *CmpInstanceInt
+	s, _ := NewShardedSkipList(cmp, WithShardSize(4, 16))
+	inserts, deletes := 0, 0
+	s.shards[0].sl.AddObserver(Observer{
+		OnInsert: func(key, data interface{}, rank int) { inserts++ },
+		OnDelete: func(key, data interface{}, rank int) { deletes++ },
+	})
+	for i := 0; i <= 16; i++ {
+		s.Insert(CmpInstanceInt(i), i)
+	}
+	if s.Shards() != 2 || inserts != 17 || deletes != 0 {
+		t.Fatalf("拆分后 分片 %d 插入通知 %d 删除通知 %d", s.Shards(), inserts, deletes)
+	}
+	//右侧分片剩余 3 个结点时与左侧分片合并
+	for i := 8; i <= 13; i++ {
+		s.DeleteByKey(CmpInstanceInt(i))
+	}
+	if s.Shards() != 1 || inserts != 17 || deletes != 0 {
+		t.Fatalf("合并后 分片 %d 插入通知 %d 删除通知 %d", s.Shards(), inserts, deletes)
+	}
+	checkShards(t, s)
+
+	before := runtime.NumGoroutine()
+	for round := 0; round < 200; round++ {
+		//key与已有结点不重复，保证每轮都能全部删除
+		for i := 100; i < 140; i++ {
+			s.Insert(CmpInstanceInt(i), i)
+		}
+		for i := 100; i < 140; i++ {
+			s.DeleteByKey(CmpInstanceInt(i))
+		}
+	}
+	//等待被丢弃的跳表的协程退出
+	for i := 0; i < 100 && runtime.NumGoroutine() > before+2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before+2 {
+		t.Fatalf("反复拆分合并后协程数 %d -> %d 分片 %d", before, n, s.Shards())
+	}
+}
+
+// Test_ShardedClose 关闭后所有分片的协程退出，之后拆分出的分片也不启动协程
+func Test_ShardedClose(t *testing.T) {
+	var cmp *CmpInstanceInt
+	before := runtime.NumGoroutine()
+	s, _ := NewShardedSkipList(cmp, WithShardBoundaries(CmpInstanceInt(100), CmpInstanceInt(200)), WithShardSize(4, 16))
+	for i := 0; i < 300; i += 10 {
+		s.Insert(CmpInstanceInt(i), i)
+	}
+	s.Close()
+	for i := 0; i < 300; i++ {
+		if i%10 != 0 {
+			s.Insert(CmpInstanceInt(i), i)
+		}
+	}
+	if s.GetLength() != 300 || s.Shards() <= 3 {
+		t.Fatalf("关闭后插入 长度 %d 分片 %d", s.GetLength(), s.Shards())
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	checkShards(t, s)
+}
+
+// Test_ShardedLoadBalance 按访问量拆分热点分片、合并冷分片
+func Test_ShardedLoadBalance(t *testing.T) {
+	var cmp *CmpInstanceInt
+	if _, err := NewShardedSkipList(cmp, WithShardLoadBalance(100, 1)); err != shardBalanceErr {
+		t.Fatalf("参数校验错误: %v", err)
+	}
+	s, _ := NewShardedSkipList(cmp, WithShardSize(0, 1000), WithShardLoadBalance(200, 2),
+		WithShardBoundaries(CmpInstanceInt(250), CmpInstanceInt(500), CmpInstanceInt(750)))
+	for i := 0; i < 1000; i++ {
+		s.Insert(CmpInstanceInt(i), i)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		key := r.Intn(1000)
+		if r.Intn(10) > 0 {
+			key = r.Intn(250)
+		}
+		if data := s.GetFirstByKey(CmpInstanceInt(key)); data != key {
+			t.Fatalf("GetFirstByKey(%d) = %v", key, data)
+		}
+	}
+	checkShards(t, s)
+	hot, cold := 0, 0
+	for _, sd := range s.shards {
+		if sd.lower == nil || s.compareAble.Compare(sd.lower, CmpInstanceInt(250)) < 0 {
+			hot++
+		} else {
+			cold++
+		}
+	}
+	if hot < 2 || cold > 2 {
+		t.Fatalf("热点区间应被拆分、冷区间应被合并, 热点分片 %d 冷分片 %d", hot, cold)
+	}
+	if got := s.GetByRankRange(1, 1000); len(got) != 1000 {
+		t.Fatalf("长度错误 %d", len(got))
+	}
+}
@@ -2,6 +2,8 @@ package skiplist
 
 import (
 	"math/rand"
+	"reflect"
+	"sync"
 	"time"
 )
 
@@ -13,19 +15,39 @@ const (
 	defaultMaxLevel       = 32  //默认最大层数
 	defaultProbability    = 0.5 //默认层数生成概率
 	defaultLevelCacheSize = 8   //默认层数生成缓冲区大小
+	deterministicMaxLevel = 64  //确定性跳表的最大层数  层数不超过 log2(n)+1，可以容纳任意数量的结点
 )
 
 // 跳表
 type SkipList struct {
 	rd              *rand.Rand
-	allowSameKey    bool          //是否允许存在相同的key  默认允许
-	levelCh         chan int      //创建结点时获取已经创建好的层数序列
-	length          int           //结点数量，不包含头结点
-	constMaxLevel   int           //能生成的最大层数
-	currentMaxLevel int           //当前的最大层数
-	probability     float64       //层数生成概率
-	compareAble     CompareAble   //需要实现比较接口
-	head, tail      *skipListNode //头尾结点
+	sampleRd        *rand.Rand      //随机采样使用的随机数，为nil时使用全局随机数
+	weigher         weightFunc      //加权采样的权重函数，为nil时不维护权重
+	weights         weightTable     //各结点每层到下一个结点的权重和，设置权重函数时才分配
+	allowSameKey    bool            //是否允许存在相同的key  默认允许
+	levelCh         chan int        //创建结点时获取已经创建好的层数序列
+	done            chan struct{}   //关闭后层数生成协程退出
+	exited          chan struct{}   //层数生成协程退出后关闭
+	closeOnce       sync.Once       //Close 只执行一次
+	muted           bool            //为true时不发送变更通知 (分片间搬移结点时)
+	length          int             //结点数量，不包含头结点
+	constMaxLevel   int             //能生成的最大层数
+	currentMaxLevel int             //当前的最大层数
+	probability     float64         //层数生成概率
+	compareAble     CompareAble     //需要实现比较接口
+	head, tail      *skipListNode   //头尾结点
+	hooks           hookRegistry    //变更通知的观察者与订阅者
+	deferEvents     bool            //是否缓存变更通知 (批量操作执行期间)
+	pending         []Event         //缓存的变更通知
+	counters        *searchCounters //查找统计，为nil时不统计
+	finger          *Hint           //最近一次按key插入或删除的路径，为nil时不使用 finger search
+	version         uint64          //结构版本  每次插入或删除结点时递增，用于判断路径是否失效
+	arena           *arena          //结点分配器，为nil时直接分配
+	path            *searchPath     //写操作复用的查找路径
+	deterministic   bool            //是否为确定性跳表 (1-2-3 skip list)，不使用随机层数
+	keyType         reflect.Type    //JSON 解码时key的类型，为nil时使用 encoding/json 的默认类型
+	dataType        reflect.Type    //JSON 解码时数据的类型
+	lex             lexKind         //前缀及字典序区间查找的key类型，由 WithLexKey 设置
 }
 
 // 跳表结点
@@ -42,25 +64,56 @@ type levelNode struct {
 	span int           //到下一个结点的跨度
 }
 
+// 随机生成层数
+func (sl *SkipList) randomLevel() int {
+	level := 1
+	for level < sl.constMaxLevel &&
+		sl.probability <= sl.rd.Float64() {
+		level++
+	}
+	return level
+}
+
 // 生成层数
 func (sl *SkipList) levelGenerate() {
+	defer close(sl.exited)
 	for {
-		level := 1
-		for level < sl.constMaxLevel &&
-			sl.probability <= sl.rd.Float64() {
-			level++
+		select {
+		case sl.levelCh <- sl.randomLevel():
+		case <-sl.done:
+			return
 		}
-		sl.levelCh <- level
 	}
 }
 
-// 生成新结点
+/*
+关闭跳表  结束层数生成协程，不再使用的跳表需要关闭，否则协程及其引用的全部结点不会被回收
+关闭后仍可以读写，插入时在调用侧生成层数; 重复调用没有影响
+*/
+func (sl *SkipList) Close() {
+	sl.closeOnce.Do(func() {
+		close(sl.done)
+		if !sl.deterministic {
+			<-sl.exited
+		}
+	})
+}
+
+// 生成新结点  (结点数量及当前最大层数在结点插入时更新)
 func (sl *SkipList) nodeGenerate(key, data interface{}) *skipListNode {
-	level := <-sl.levelCh
-	if level-1 > sl.currentMaxLevel {
-		sl.currentMaxLevel = level - 1
+	level := 1
+	if !sl.deterministic {
+		select {
+		case level = <-sl.levelCh:
+		case <-sl.done:
+			level = sl.randomLevel()
+		}
+	}
+	if sl.arena != nil {
+		node := sl.arena.alloc(level)
+		node.key, node.data = key, data
+		return node
 	}
-	sl.length++
 	return &skipListNode{
 		prev:  nil,
 		level: make([]levelNode, level),
@@ -75,6 +128,8 @@ func New(compareAble CompareAble, options ...Option) (*SkipList, error) {
 		rd:              rand.New(rand.NewSource(time.Now().UnixNano())),
 		allowSameKey:    true,
 		levelCh:         make(chan int, defaultLevelCacheSize),
+		done:            make(chan struct{}),
+		exited:          make(chan struct{}),
 		length:          0,
 		constMaxLevel:   defaultMaxLevel,
 		currentMaxLevel: 0,
@@ -85,11 +140,18 @@ func New(compareAble CompareAble, options ...Option) (*SkipList, error) {
 	}
 	for k := range options {
 		if err := options[k](sl); err != nil {
+			close(sl.exited) //层数生成协程没有启动
 			return sl, err
 		}
 	}
+	if sl.deterministic {
+		//层数由结点数决定，受 WithMaxLevel 限制时最高层的间隔没有上限，退化为 O(n)
+		sl.constMaxLevel = deterministicMaxLevel
+	}
 	sl.headNodeInit()
-	go sl.levelGenerate()
+	if !sl.deterministic {
+		go sl.levelGenerate()
+	}
 	return sl, nil
 }
 
@@ -101,6 +163,16 @@ func (sl *SkipList) headNodeInit() {
 		key:   nil,
 		data:  nil,
 	}
+	if sl.weigher != nil {
+		sl.weights = weightTable{sl.head: make([]float64, sl.constMaxLevel)}
+	}
+}
+
+// 删除所有结点  从尾部逐个删除，与其他删除一样发送变更通知并回收结点，保留参数及层数生成协程，便于复用跳表
+func (sl *SkipList) deleteAll() {
+	for sl.length > 0 {
+		sl.deleteByRank(sl.length)
+	}
 }
 
 // 更新当前最大层数
@@ -156,10 +228,11 @@ func (sl *SkipList) searchTailOneByKey(key interface{}) *skipListNode {
 
 // 获取任意一个,只要找到相等的就返回
 func (sl *SkipList) searchRandOneByKey(key interface{}) *skipListNode {
+	sl.countSearch()
 	if sl.length > 0 {
-		preNode := sl.head
-		for level := sl.currentMaxLevel; level >= 0; level-- {
-			for ; ; preNode = preNode.level[level].next {
+		preNode, _, top := sl.searchStart(key, true)
+		for level := top; level >= 0; level-- {
+			for ; ; preNode = sl.hop(preNode.level[level].next) {
 				if preNode.level[level].next == nil || sl.greaterThan(preNode.level[level].next.key, key) {
 					if preNode != sl.head && sl.equals(preNode.key, key) {
 						return preNode
@@ -174,11 +247,11 @@ func (sl *SkipList) searchRandOneByKey(key interface{}) *skipListNode {
 
 // 获取相同key的rank值，
 func (sl *SkipList) searchRandNodeAndRankByKey(key interface{}) (*skipListNode, int) {
+	sl.countSearch()
 	if sl.length > 0 {
-		currentRank := 0
-		preNode := sl.head
-		for level := sl.currentMaxLevel; level >= 0; level-- {
-			for ; ; preNode = preNode.level[level].next {
+		preNode, currentRank, top := sl.searchStart(key, true)
+		for level := top; level >= 0; level-- {
+			for ; ; preNode = sl.hop(preNode.level[level].next) {
 				if preNode.level[level].next == nil || sl.greaterThan(preNode.level[level].next.key, key) {
 					if preNode != sl.head && sl.equals(preNode.key, key) {
 						return preNode, currentRank
@@ -216,6 +289,55 @@ func (sl *SkipList) searchTailNodeAndRankByKey(key interface{}) (*skipListNode,
 	return node, rank
 }
 
+// 获取指定结点的rank  相同key时从第一个相等结点开始向后查找
+func (sl *SkipList) rankOfNode(target *skipListNode) int {
+	node, rank := sl.searchFirstNodeAndRankByKey(target.key)
+	for ; node != nil && node != target; node = node.level[0].next {
+		rank++
+	}
+	if node == nil {
+		return -1
+	}
+	return rank
+}
+
+// 获取第一个大于等于key的结点及其rank
+func (sl *SkipList) searchCeilingNodeAndRankByKey(key interface{}) (*skipListNode, int) {
+	sl.countSearch()
+	if sl.length > 0 {
+		preNode, currentRank, top := sl.searchStart(key, false)
+		for level := top; level >= 0; level-- {
+			for preNode.level[level].next != nil && sl.lessThan(preNode.level[level].next.key, key) {
+				currentRank += preNode.level[level].span
+				preNode = sl.hop(preNode.level[level].next)
+			}
+		}
+		if preNode.level[0].next != nil {
+			return preNode.level[0].next, currentRank + 1
+		}
+	}
+	return nil, -1
+}
+
+/*
+获取key之前的最后一个结点及其rank
+inclusive 为 true 时为最后一个小于等于key的结点，否则为最后一个小于key的结点  不存在时返回头结点及0
+*/
+func (sl *SkipList) searchPrevNodeAndRank(key interface{}, inclusive bool) (*skipListNode, int) {
+	sl.countSearch()
+	preNode, currentRank, top := sl.searchStart(key, inclusive)
+	for level := top; level >= 0; level-- {
+		for next := preNode.level[level].next; next != nil; next = preNode.level[level].next {
+			if c := sl.compare(next.key, key); c > 0 || (c == 0 && !inclusive) {
+				break
+			}
+			currentRank += preNode.level[level].span
+			preNode = sl.hop(next)
+		}
+	}
+	return preNode, currentRank
+}
+
 // 通过顺位排序搜索   顺位 1~n
 func (sl *SkipList) searchByRankRange(start, end int) []*skipListNode {
 	list := []*skipListNode{}
@@ -250,6 +372,7 @@ func (sl *SkipList) searchByRankRange(start, end int) []*skipListNode {
 
 // 通过精确rank搜索
 func (sl *SkipList) searchByRank(rk int) *skipListNode {
+	sl.countSearch()
 	if rk > 0 && rk <= sl.length {
 		if rk == 1 {
 			return sl.head.level[0].next
@@ -259,7 +382,7 @@ func (sl *SkipList) searchByRank(rk int) *skipListNode {
 		currentRank := 0
 		preNode := sl.head
 		for level := sl.currentMaxLevel; level >= 0; level-- {
-			for ; ; preNode = preNode.level[level].next {
+			for ; ; preNode = sl.hop(preNode.level[level].next) {
 				if preNode.level[level].next == nil || preNode.level[level].span+currentRank > rk {
 					if currentRank == rk {
 						return preNode
@@ -296,168 +419,289 @@ func (sl *SkipList) updateByKey(key, data interface{}) bool {
 
 // 通过key删除  无重复key时删除成功
 func (sl *SkipList) deleteByKey(key interface{}) bool {
-	if node := sl.searchRandOneByKey(key); node != nil {
-		if (node.prev == nil || !sl.equals(node.key, node.prev.key)) &&
-			(node.level[0].next == nil || !sl.equals(node.key, node.level[0].next.key)) {
-			sl.delNode(node)
+	path := sl.writePath(key, false)
+	if node := path.prev[0].level[0].next; node != nil && sl.equals(node.key, key) {
+		if node.level[0].next == nil || !sl.equals(node.key, node.level[0].next.key) {
+			sl.freeNode(sl.unlinkNext(path))
+			sl.fingerSync()
 			return true
 		}
 	}
 	return false
 }
 
+// 通过rank删除
+func (sl *SkipList) deleteByRank(rank int) bool {
+	if rank < 1 || rank > sl.length {
+		return false
+	}
+	path := sl.scratchPath()
+	sl.seekRankPath(path, rank)
+	sl.freeNode(sl.unlinkNext(path))
+	return true
+}
+
 // 通过结点更新
 func (sl *SkipList) updateByNode(node *skipListNode, data interface{}) {
+	oldData := node.data
 	node.data = data
+	if sl.weigher != nil {
+		sl.reweight(node)
+	}
+	if sl.hasObservers() {
+		sl.notify(EventUpdate, node.key, data, oldData, sl.rankOfNode(node))
+	}
 }
 
 // 添加结点   如果不允许有相同结点的话，重复添加时会失败
 func (sl *SkipList) addNode(key, data interface{}) (int, bool) {
-	if !sl.allowSameKey && sl.searchRandOneByKey(key) != nil {
+	if sl.finger != nil {
+		return sl.insertAfterHint(sl.finger, key, data)
+	}
+	path := sl.scratchPath()
+	sl.seekPath(path, key, true)
+	if !sl.allowSameKey && path.prev[0] != sl.head && sl.equals(path.prev[0].key, key) {
 		return 0, false
 	}
-	addNode := sl.nodeGenerate(key, data)
-	if sl.length == 1 { //generate +1 了
-		for level := sl.currentMaxLevel; level >= 0; level-- {
-			sl.head.level[level].next = addNode
-			sl.head.level[level].span = 1
-		}
-		sl.tail = addNode
-		return 1, true
-	}
-	prevL := make([]*skipListNode, len(addNode.level)) // [层数]前置结点
-	nextL := make([]*skipListNode, len(addNode.level)) // [层数]后置结点
-	nrm := map[*skipListNode]int{}                     // [结点:rank]
-	nodeRank := 0                                      //当前结点rank
-	var preNode *skipListNode = sl.head
-	//找前置与后置结点，并记录rank
-	for level := sl.currentMaxLevel; level >= 0; level-- {
-		for {
-			if preNode.level[level].next == nil || sl.greaterThan(preNode.level[level].next.key, key) {
-				if len(addNode.level) <= level {
-					if preNode.level[level].next != nil {
-						preNode.level[level].span++
-					}
-				} else {
-					prevL[level] = preNode
-					nrm[preNode] = nodeRank
-					if preNode.level[level].next != nil {
-						nextL[level] = preNode.level[level].next
-						nrm[preNode.level[level].next] = preNode.level[level].span + nodeRank + 1
-					}
-				}
-				break
-			} else {
-				nodeRank += preNode.level[level].span
-				preNode = preNode.level[level].next
-			}
-		}
+	return sl.linkNode(path, sl.nodeGenerate(key, data)), true
+}
+
+// 通过key删除结点 所有key相等的结点
+func (sl *SkipList) delByKey(key interface{}) bool {
+	path := sl.writePath(key, false)
+	deleted := false
+	for next := path.prev[0].level[0].next; next != nil && sl.equals(next.key, key); next = path.prev[0].level[0].next {
+		sl.freeNode(sl.unlinkNext(path))
+		deleted = true
 	}
-	//当前结点的rank
-	nodeRank++
-	//更新前后置指向结点及本结点span
-	for level := len(addNode.level) - 1; level >= 0; level-- {
-		prevL[level].level[level].span = nodeRank - nrm[prevL[level]]
-		addNode.level[level].next = prevL[level].level[level].next
-		prevL[level].level[level].next = addNode
-		addNode.prev = prevL[level]
-		if nextL[level] != nil {
-			addNode.level[level].span = nrm[nextL[level]] - nodeRank
-			nextL[level].prev = addNode
-		}
+	sl.fingerSync()
+	return deleted
+}
+
+// 通过node删除结点
+func (sl *SkipList) delNode(delNode *skipListNode) {
+	path := sl.scratchPath()
+	sl.seekRankPath(path, sl.rankOfNode(delNode))
+	sl.freeNode(sl.unlinkNext(path))
+}
+
+/*
+查找路径
+prev[level] 为第level层的前置结点，rank[level] 为其rank。
+路径可以在 key 升序的连续操作之间复用: 下一次查找从上一次的前置结点继续，而不是从头结点开始
+*/
+type searchPath struct {
+	prev []*skipListNode
+	rank []int
+}
+
+// 生成从头结点开始的查找路径
+func (sl *SkipList) newSearchPath() *searchPath {
+	path := &searchPath{
+		prev: make([]*skipListNode, sl.constMaxLevel),
+		rank: make([]int, sl.constMaxLevel),
 	}
-	//更新tail
-	if sl.tail == nil || sl.tail.level[0].next != nil {
-		sl.tail = addNode
+	for level := range path.prev {
+		path.prev[level] = sl.head
 	}
-	return nodeRank, true
+	return path
 }
 
-// 通过key删除结点 所有key相等的结点
-func (sl *SkipList) delByKey(key interface{}) bool {
-	if sl.allowSameKey {
-		list := sl.searchAllByKey(key)
-		if len(list) > 0 {
-			for k := range list {
-				sl.delNode(list[k])
+/*
+沿路径继续查找key的前置结点   路径中各层前置结点的key不能大于key
+inclusive 为 true 时前置结点为最后一个小于等于key的结点 (插入位置)，否则为最后一个小于key的结点
+*/
+func (sl *SkipList) seekPath(path *searchPath, key interface{}, inclusive bool) {
+	sl.countSearch()
+	sl.seekPathFrom(path, key, inclusive, sl.currentMaxLevel)
+}
+
+// 从第top层开始沿路径向下查找  高于top的层保持不变，调用侧需要保证这些层的下一个结点大于key
+func (sl *SkipList) seekPathFrom(path *searchPath, key interface{}, inclusive bool, top int) {
+	for level := top; level >= 0; level-- {
+		preNode, rank := path.prev[level], path.rank[level]
+		//上一层已经走得更远时从上一层的位置继续
+		if level < sl.currentMaxLevel && path.rank[level+1] > rank {
+			preNode, rank = path.prev[level+1], path.rank[level+1]
+		}
+		for next := preNode.level[level].next; next != nil; next = preNode.level[level].next {
+			if c := sl.compare(next.key, key); c > 0 || (c == 0 && !inclusive) {
+				break
 			}
-			return true
+			rank += preNode.level[level].span
+			preNode = sl.hop(next)
 		}
-	} else {
-		node := sl.searchRandOneByKey(key)
-		if node != nil {
-			sl.delNode(node)
-			return true
+		path.prev[level], path.rank[level] = preNode, rank
+	}
+}
+
+// 沿路径继续查找rank的前置结点 (每层最后一个rank小于rk的结点)
+func (sl *SkipList) seekRankPath(path *searchPath, rk int) {
+	sl.countSearch()
+	for level := sl.currentMaxLevel; level >= 0; level-- {
+		preNode, rank := path.prev[level], path.rank[level]
+		if level < sl.currentMaxLevel && path.rank[level+1] > rank {
+			preNode, rank = path.prev[level+1], path.rank[level+1]
 		}
+		for preNode.level[level].next != nil && rank+preNode.level[level].span < rk {
+			rank += preNode.level[level].span
+			preNode = sl.hop(preNode.level[level].next)
+		}
+		path.prev[level], path.rank[level] = preNode, rank
 	}
-	return false
 }
 
-// 通过node删除结点
-func (sl *SkipList) delNode(delNode *skipListNode) {
-	defer func(sl *SkipList) {
-		sl.length--
-		if len(delNode.level)-1 >= sl.currentMaxLevel {
-			sl.updateCurrentMaxLevel(sl.currentMaxLevel)
+/*
+将结点插入到路径的第0层前置结点之后，返回结点的rank
+插入后路径中被新结点覆盖的层会指向新结点，可继续用于更大key的查找
+*/
+func (sl *SkipList) linkNode(path *searchPath, node *skipListNode) int {
+	if sl.deterministic {
+		//确定性跳表的新结点层高为1 (回滚时重新插入的结点同样按层高1插入)
+		node.level = node.level[:1]
+	}
+	height := len(node.level)
+	//权重函数在修改结构之前调用，出现异常时跳表保持不变
+	weight := 0.0
+	if sl.weigher != nil {
+		weight = sl.weightOf(node.data)
+	}
+	sl.version++
+	if sl.weigher != nil {
+		sl.weights[node] = make([]float64, height)
+	}
+	//新增的层从头结点开始
+	for level := sl.currentMaxLevel + 1; level < height; level++ {
+		path.prev[level], path.rank[level] = sl.head, 0
+	}
+	if sl.length == 0 || height-1 > sl.currentMaxLevel {
+		sl.currentMaxLevel = height - 1
+	}
+	sl.length++
+	prev0, rank0 := path.prev[0], path.rank[0]
+	//更新前后置指向结点及本结点span
+	for level := 0; level < height; level++ {
+		preNode := path.prev[level]
+		node.level[level].next = preNode.level[level].next
+		node.level[level].span = 0
+		if node.level[level].next != nil {
+			node.level[level].span = preNode.level[level].span - (rank0 - path.rank[level])
+		}
+		preNode.level[level].next = node
+		preNode.level[level].span = rank0 - path.rank[level] + 1
+		path.prev[level], path.rank[level] = node, rank0+1
+		if sl.weigher != nil {
+			sl.linkWeight(preNode, node, level, weight)
 		}
-	}(sl)
-	if sl.tail == delNode {
-		sl.tail = delNode.prev
 	}
+	//高于新结点的层只需要增加跨度
+	for level := height; level <= sl.currentMaxLevel; level++ {
+		if path.prev[level].level[level].next != nil {
+			path.prev[level].level[level].span++
+		}
+		if sl.weigher != nil {
+			sl.sumWeight(path.prev[level], level)
+		}
+	}
+	//前置指针只维护第0层，头结点不作为前置结点
+	node.prev = nil
+	if prev0 != sl.head {
+		node.prev = prev0
+	}
+	if node.level[0].next != nil {
+		node.level[0].next.prev = node
+	} else {
+		sl.tail = node
+	}
+	if sl.deterministic {
+		sl.balanceInsert(path, rank0+1)
+	}
+	sl.notify(EventInsert, node.key, node.data, nil, rank0+1)
+	return rank0 + 1
+}
 
-	//与当前key相等，但处于delNode的后面
-	equalsNextKeyMap := map[*skipListNode]bool{}
-	for delNodeNext := delNode.level[0].next; delNodeNext != nil && sl.equals(delNode.key, delNodeNext.key); delNodeNext = delNodeNext.level[0].next {
-		equalsNextKeyMap[delNodeNext] = true
+/*
+删除路径第0层前置结点的下一个结点，并返回被删除的结点
+路径的各层前置结点需要是被删除结点在该层的前置结点 (seekPath inclusive 为 false 或 seekRankPath 得到的路径)，
+删除后路径仍然有效，可继续删除下一个结点
+*/
+func (sl *SkipList) unlinkNext(path *searchPath) *skipListNode {
+	rank := path.rank[0] + 1
+	var node *skipListNode
+	if sl.deterministic {
+		node = sl.unlinkBalanced(path)
+	} else {
+		node = sl.unlink(path)
 	}
+	sl.notify(EventDelete, node.key, nil, node.data, rank)
+	return node
+}
 
-	preNode := sl.head
-	for level := sl.currentMaxLevel; level >= 0; level-- {
-		for ; ; preNode = preNode.level[level].next {
-			if preNode.level[level].next == nil || sl.greaterThan(preNode.level[level].next.key, delNode.key) ||
-				(equalsNextKeyMap[preNode.level[level].next] && sl.equals(preNode.level[level].next.key, delNode.key)) {
-				if preNode.level[level].next != nil {
-					preNode.level[level].span--
-				}
-				break
-			} else if preNode.level[level].next == delNode {
-				preNode.level[level].next = delNode.level[level].next
-				preNode.level[level].span += delNode.level[level].span - 1
-				if delNode.level[level].next == nil {
-					preNode.level[level].span = 0
-				}
-				if level == 0 && delNode.level[level].next != nil {
-					delNode.level[level].next.prev = delNode.prev
-				}
-				break
+// 从各层摘除路径第0层前置结点的下一个结点
+func (sl *SkipList) unlink(path *searchPath) *skipListNode {
+	node := path.prev[0].level[0].next
+	sl.version++
+	for level := 0; level <= sl.currentMaxLevel; level++ {
+		preNode := path.prev[level]
+		if level < len(node.level) {
+			preNode.level[level].next = node.level[level].next
+			preNode.level[level].span += node.level[level].span - 1
+			if node.level[level].next == nil {
+				preNode.level[level].span = 0
 			}
+		} else if preNode.level[level].next != nil {
+			preNode.level[level].span--
+		}
+		if sl.weigher != nil {
+			sl.unlinkWeight(preNode, node, level)
 		}
 	}
+	if sl.weigher != nil {
+		delete(sl.weights, node)
+	}
+	if node.level[0].next != nil {
+		node.level[0].next.prev = node.prev
+	} else {
+		sl.tail = node.prev
+	}
+	sl.length--
+	if len(node.level)-1 >= sl.currentMaxLevel {
+		sl.updateCurrentMaxLevel(sl.currentMaxLevel)
+	}
+	return node
+}
+
+// 比较a,b  开启统计时记录比较次数
+func (sl *SkipList) compare(a, b interface{}) int {
+	if sl.counters != nil {
+		sl.counters.comparisons.Add(1)
+	}
+	return sl.compareAble.Compare(a, b)
 }
 
 // a,b相同
 func (sl *SkipList) equals(a, b interface{}) bool {
-	return sl.compareAble.Compare(a, b) == 0
+	return sl.compare(a, b) == 0
 }
 
 // a小于b
 func (sl *SkipList) lessThan(a, b interface{}) bool {
-	return sl.compareAble.Compare(a, b) == -1
+	return sl.compare(a, b) == -1
 }
 
 // a大于b
 func (sl *SkipList) greaterThan(a, b interface{}) bool {
-	return sl.compareAble.Compare(a, b) == 1
+	return sl.compare(a, b) == 1
 }
 
 // a小于等于b
 func (sl *SkipList) lessOrEquals(a, b interface{}) bool {
-	return sl.compareAble.Compare(a, b) != 1
+	return sl.compare(a, b) != 1
 }
 
 // a大于等于b
 func (sl *SkipList) greaterOrEquals(a, b interface{}) bool {
-	return sl.compareAble.Compare(a, b) != -1
+	return sl.compare(a, b) != -1
 }
 
 // 翻转node
@@ -556,6 +800,15 @@ func (sl *SkipList) GetTailWithRankByKey(key interface{}) (interface{}, int) {
 	return nil, rk
 }
 
+// 获取第一个大于等于key的结点数据及所在的排位  不存在时返回 nil,-1
+func (sl *SkipList) GetCeilingWithRankByKey(key interface{}) (interface{}, int) {
+	node, rk := sl.searchCeilingNodeAndRankByKey(key)
+	if node != nil {
+		return node.data, rk
+	}
+	return nil, rk
+}
+
 // 获取指定排位的数据
 func (sl *SkipList) GetByRank(rk int) interface{} {
 	node := sl.searchByRank(rk)
@@ -607,12 +860,7 @@ func (sl *SkipList) DeleteByKey(key interface{}) bool {
 
 // 删除指定排位的结点
 func (sl *SkipList) DeleteByRank(rank int) bool {
-	node := sl.searchByRank(rank)
-	if node != nil {
-		sl.delNode(node)
-		return true
-	}
-	return false
+	return sl.deleteByRank(rank)
 }
 
 /*
@@ -2,6 +2,9 @@ package skiplist
 
 import (
 	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
 	"testing"
 )
 
@@ -94,3 +97,169 @@ func Benchmark_SearchRankRange(b *testing.B) {
 		}
 	}
 }
+
+// 校验跳表结构: 前置指针、尾结点、长度以及各层span
+func checkStructure(t *testing.T, sl *SkipList) {
+	t.Helper()
+	rank := map[*skipListNode]int{sl.head: 0}
+	var prev *skipListNode
+	rk := 0
+	for node := sl.head.level[0].next; node != nil; node = node.level[0].next {
+		rk++
+		rank[node] = rk
+		if node.prev != prev {
+			t.Fatalf("rank %d 的前置结点错误", rk)
+		}
+		prev = node
+	}
+	if rk != sl.length {
+		t.Fatalf("长度错误 %d != %d", rk, sl.length)
+	}
+	if sl.length > 0 && sl.tail != prev {
+		t.Fatalf("尾结点错误")
+	}
+	for level := range sl.head.level {
+		if level > sl.currentMaxLevel && sl.head.level[level].next != nil {
+			t.Fatalf("第 %d 层高于当前最大层数 %d 但不为空", level, sl.currentMaxLevel)
+		}
+		for node := sl.head; node != nil; node = node.level[level].next {
+			next := node.level[level].next
+			if next != nil && node.level[level].span != rank[next]-rank[node] {
+				t.Fatalf("第 %d 层 span 错误 %d != %d", level, node.level[level].span, rank[next]-rank[node])
+			}
+		}
+	}
+}
+
+// Test_PrevPointer 头部插入时前置结点不应指向头结点
+func Test_PrevPointer(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp)
+	sl.Insert(CmpInstanceInt(2), 2)
+	sl.Insert(CmpInstanceInt(1), 1)
+	if data := sl.GetFirstByKey(CmpInstanceInt(1)); data != 1 {
+		t.Fatalf("GetFirstByKey got: %v", data)
+	}
+	for i := 0; i < 1000; i++ {
+		sl.Insert(CmpInstanceInt(i%37), i)
+	}
+	checkStructure(t, sl)
+	for i := 0; i < 37; i += 2 {
+		sl.DeleteBatchByKey(CmpInstanceInt(i))
+	}
+	checkStructure(t, sl)
+}
+
+// Test_Close 关闭后层数生成协程退出，跳表仍可读写
+func Test_Close(t *testing.T) {
+	var cmp *CmpInstanceInt
+	before := runtime.NumGoroutine()
+	lists := []*SkipList{}
+	for i := 0; i < 50; i++ {
+		sl, _ := New(cmp)
+		sl.Insert(CmpInstanceInt(i), i)
+		lists = append(lists, sl)
+	}
+	for _, sl := range lists {
+		sl.Close()
+		sl.Close()
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	sl := lists[0]
+	for i := 1; i < 100; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	if sl.GetLength() != 100 || sl.GetByRank(100) != 99 {
+		t.Fatalf("关闭后插入错误, 长度 %d", sl.GetLength())
+	}
+	checkStructure(t, sl)
+	//参数错误时没有启动协程，关闭不会阻塞
+	failed, err := New(cmp, WithMaxLevel(0))
+	if err == nil {
+		t.Fatal("want levelErr")
+	}
+	failed.Close()
+}
+
+// Test_RandomOperate 随机插入、删除，与有序切片的结果对比
+func Test_RandomOperate(t *testing.T) {
+	type entry struct {
+		key  int
+		data int
+	}
+	var cmp *CmpInstanceInt
+	for _, allowSameKey := range []bool{true, false} {
+		r := rand.New(rand.NewSource(1))
+		sl, _ := New(cmp, WithAllowTheSameKey(allowSameKey))
+		var want []entry
+		count := func(key int) int {
+			n := 0
+			for k := range want {
+				if want[k].key == key {
+					n++
+				}
+			}
+			return n
+		}
+		remove := func(key int) {
+			list := want[:0:0]
+			for k := range want {
+				if want[k].key != key {
+					list = append(list, want[k])
+				}
+			}
+			want = list
+		}
+		for i := 0; i < 5000; i++ {
+			key := r.Intn(200)
+			switch r.Intn(6) {
+			case 0, 1, 2:
+				rank, ok := sl.Insert(CmpInstanceInt(key), i)
+				if !allowSameKey && count(key) > 0 {
+					if ok {
+						t.Fatalf("重复key %d 不应插入成功", key)
+					}
+					continue
+				}
+				pos := sort.Search(len(want), func(j int) bool { return want[j].key > key })
+				want = append(want[:pos], append([]entry{{key, i}}, want[pos:]...)...)
+				if rank != pos+1 {
+					t.Fatalf("插入 %d 的排位 %d != %d", key, rank, pos+1)
+				}
+			case 3:
+				if sl.DeleteBatchByKey(CmpInstanceInt(key)) != (count(key) > 0) {
+					t.Fatalf("DeleteBatchByKey(%d) 结果错误", key)
+				}
+				remove(key)
+			case 4:
+				if len(want) > 0 {
+					rank := r.Intn(len(want)) + 1
+					sl.DeleteByRank(rank)
+					want = append(want[:rank-1], want[rank:]...)
+				}
+			case 5:
+				n := count(key)
+				if sl.DeleteByKey(CmpInstanceInt(key)) != (n == 1) {
+					t.Fatalf("DeleteByKey(%d) 结果错误", key)
+				}
+				if n == 1 {
+					remove(key)
+				}
+			}
+			if i%100 == 0 {
+				checkStructure(t, sl)
+				got := sl.GetByRankRange(1, sl.GetLength())
+				if len(got) != len(want) {
+					t.Fatalf("长度 %d != %d", len(got), len(want))
+				}
+				for k := range want {
+					if got[k] != want[k].data {
+						t.Fatalf("排位 %d 的数据 %v != %v", k+1, got[k], want[k].data)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package skiplist
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// 查找统计计数
+type searchCounters struct {
+	searches    atomic.Uint64 //查找次数 (包含插入、删除时的查找)
+	comparisons atomic.Uint64 //key比较次数
+	hops        atomic.Uint64 //指针跳转次数
+}
+
+// 跳表统计信息
+type Stats struct {
+	Length         int     //结点数量
+	MaxLevel       int     //当前最高层数 (即 currentMaxLevel+1，空表为0)
+	ConstMaxLevel  int     //能生成的最大层数
+	Probability    float64 //层数生成概率
+	LevelNodes     []int   //每层的结点数，下标为层 (从0开始)
+	AvgHeight      float64 //结点的平均层高
+	EstimatedBytes int     //结点结构占用的估算字节数，不包含 key/data 引用的内存
+
+	Instrumented bool   //是否开启了查找统计
+	Searches     uint64 //查找次数
+	Comparisons  uint64 //比较次数
+	Hops         uint64 //指针跳转次数
+
+	Arena       bool //是否开启了结点分配器
+	ArenaSlabs  int  //已分配的结点块数
+	ArenaReused int  //复用的结点数
+	ArenaFree   int  //空闲链表中的结点数
+}
+
+// 平均每次查找的比较次数
+func (s Stats) ComparisonsPerSearch() float64 {
+	if s.Searches == 0 {
+		return 0
+	}
+	return float64(s.Comparisons) / float64(s.Searches)
+}
+
+// 平均每次查找的指针跳转次数
+func (s Stats) HopsPerSearch() float64 {
+	if s.Searches == 0 {
+		return 0
+	}
+	return float64(s.Hops) / float64(s.Searches)
+}
+
+// 记录一次查找
+func (sl *SkipList) countSearch() {
+	if sl.counters != nil {
+		sl.counters.searches.Add(1)
+	}
+}
+
+// 记录一次指针跳转
+func (sl *SkipList) hop(node *skipListNode) *skipListNode {
+	if sl.counters != nil {
+		sl.counters.hops.Add(1)
+	}
+	return node
+}
+
+/*
+获取统计信息
+需要遍历第0层，为 O(n); 与写操作并发调用时需要由调用侧加锁
+*/
+func (sl *SkipList) Stats() Stats {
+	s := Stats{
+		Length:        sl.length,
+		ConstMaxLevel: sl.constMaxLevel,
+		Probability:   sl.probability,
+		LevelNodes:    []int{},
+	}
+	nodeSize := int(unsafe.Sizeof(skipListNode{}))
+	levelSize := int(unsafe.Sizeof(levelNode{}))
+	s.EstimatedBytes = nodeSize + len(sl.head.level)*levelSize
+	totalHeight := 0
+	for node := sl.head.level[0].next; node != nil; node = node.level[0].next {
+		height := len(node.level)
+		for len(s.LevelNodes) < height {
+			s.LevelNodes = append(s.LevelNodes, 0)
+		}
+		for level := 0; level < height; level++ {
+			s.LevelNodes[level]++
+		}
+		totalHeight += height
+		s.EstimatedBytes += nodeSize + height*levelSize
+	}
+	if sl.length > 0 {
+		s.MaxLevel = sl.currentMaxLevel + 1
+		s.AvgHeight = float64(totalHeight) / float64(sl.length)
+	}
+	if sl.counters != nil {
+		s.Instrumented = true
+		s.Searches = sl.counters.searches.Load()
+		s.Comparisons = sl.counters.comparisons.Load()
+		s.Hops = sl.counters.hops.Load()
+	}
+	if sl.arena != nil {
+		s.Arena = true
+		s.ArenaSlabs = sl.arena.stats.slabs
+		s.ArenaReused = sl.arena.stats.reused
+		s.ArenaFree = sl.arena.stats.freed
+	}
+	return s
+}
+
+// 清零查找统计
+func (sl *SkipList) ResetCounters() {
+	if sl.counters != nil {
+		sl.counters.searches.Store(0)
+		sl.counters.comparisons.Store(0)
+		sl.counters.hops.Store(0)
+	}
+}
+
+/*
+以 Prometheus 文本格式输出统计信息
+prefix 为指标名前缀，例如 "ranking_skiplist"
+*/
+func (s Stats) WritePrometheus(w io.Writer, prefix string) error {
+	ew := &errWriter{w: w}
+	gauge := func(name, help string, value interface{}) {
+		ew.printf("# HELP %s_%s %s\n# TYPE %s_%s gauge\n%s_%s %v\n", prefix, name, help, prefix, name, prefix, name, value)
+	}
+	counter := func(name, help string, value uint64) {
+		ew.printf("# HELP %s_%s %s\n# TYPE %s_%s counter\n%s_%s %d\n", prefix, name, help, prefix, name, prefix, name, value)
+	}
+	gauge("length", "Number of nodes.", s.Length)
+	gauge("max_level", "Current highest level.", s.MaxLevel)
+	gauge("const_max_level", "Highest level that can be generated.", s.ConstMaxLevel)
+	gauge("probability", "Level generation probability.", s.Probability)
+	gauge("avg_height", "Average tower height.", s.AvgHeight)
+	gauge("estimated_bytes", "Estimated bytes used by nodes.", s.EstimatedBytes)
+	ew.printf("# HELP %s_level_nodes Number of nodes on each level.\n# TYPE %s_level_nodes gauge\n", prefix, prefix)
+	for level := range s.LevelNodes {
+		ew.printf("%s_level_nodes{level=\"%d\"} %d\n", prefix, level, s.LevelNodes[level])
+	}
+	if s.Instrumented {
+		counter("searches_total", "Number of searches.", s.Searches)
+		counter("comparisons_total", "Number of key comparisons.", s.Comparisons)
+		counter("hops_total", "Number of pointer hops.", s.Hops)
+	}
+	if s.Arena {
+		gauge("arena_slabs", "Number of allocated node slabs.", s.ArenaSlabs)
+		counter("arena_reused_total", "Number of reused nodes.", uint64(s.ArenaReused))
+		gauge("arena_free_nodes", "Number of nodes on the free lists.", s.ArenaFree)
+	}
+	return ew.err
+}
+
+// 记录第一个写入错误
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+/*
+以 name 发布到 expvar (/debug/vars)，每次读取时调用 Stats
+mu 不为nil时读取统计前加锁，用于与写操作互斥; name 重复时 expvar 会 panic
+*/
+func (sl *SkipList) PublishExpvar(name string, mu sync.Locker) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		if mu != nil {
+			mu.Lock()
+			defer mu.Unlock()
+		}
+		return sl.Stats()
+	}))
+}
@@ -0,0 +1,80 @@
+package skiplist
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Test_Stats 测试层数直方图与统计计数
+func Test_Stats(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, WithLevelCacheSize(5, 1, 3, 2, 1, 1), WithInstrumentation(true))
+	if s := sl.Stats(); s.Length != 0 || s.MaxLevel != 0 || len(s.LevelNodes) != 0 || s.EstimatedBytes <= 0 {
+		t.Fatalf("空跳表统计错误: %+v", s)
+	}
+	for i := 1; i <= 5; i++ {
+		sl.Insert(CmpInstanceInt(i), i)
+	}
+	s := sl.Stats()
+	if s.Length != 5 || s.MaxLevel != 3 || s.AvgHeight != 8.0/5 {
+		t.Fatalf("统计错误: %+v", s)
+	}
+	if want := []int{5, 2, 1}; len(s.LevelNodes) != 3 || s.LevelNodes[0] != want[0] || s.LevelNodes[1] != want[1] || s.LevelNodes[2] != want[2] {
+		t.Fatalf("每层结点数错误: %v", s.LevelNodes)
+	}
+
+	sl.ResetCounters()
+	sl.GetRandByKey(CmpInstanceInt(4))
+	s = sl.Stats()
+	if !s.Instrumented || s.Searches != 1 || s.Comparisons == 0 || s.Hops == 0 {
+		t.Fatalf("查找统计错误: %+v", s)
+	}
+	if s.ComparisonsPerSearch() != float64(s.Comparisons) {
+		t.Fatal("平均比较次数错误")
+	}
+
+	plain, _ := New(cmp)
+	plain.Insert(CmpInstanceInt(1), 1)
+	plain.GetRandByKey(CmpInstanceInt(1))
+	if s := plain.Stats(); s.Instrumented || s.Searches != 0 {
+		t.Fatalf("未开启统计时不应计数: %+v", s)
+	}
+}
+
+// Test_StatsExport 测试 Prometheus 文本与 expvar 输出
+func Test_StatsExport(t *testing.T) {
+	var cmp *CmpInstanceInt
+	sl, _ := New(cmp, WithLevelCacheSize(2, 2, 1), WithInstrumentation(true))
+	sl.Insert(CmpInstanceInt(1), 1)
+	sl.Insert(CmpInstanceInt(2), 2)
+
+	var buf bytes.Buffer
+	if err := sl.Stats().WritePrometheus(&buf, "rank"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE rank_length gauge\nrank_length 2\n",
+		"rank_max_level 2\n",
+		"rank_level_nodes{level=\"0\"} 2\n",
+		"rank_level_nodes{level=\"1\"} 1\n",
+		"# TYPE rank_searches_total counter\n",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("输出中缺少 %q:\n%s", line, buf.String())
+		}
+	}
+
+	var mu sync.Mutex
+	sl.PublishExpvar("skiplist_stats_test", &mu)
+	var s Stats
+	if err := json.Unmarshal([]byte(expvar.Get("skiplist_stats_test").String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Length != 2 || s.MaxLevel != 2 {
+		t.Fatalf("expvar 输出错误: %+v", s)
+	}
+}
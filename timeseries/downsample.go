@@ -0,0 +1,206 @@
+package timeseries
+
+import "math"
+
+// 聚合方式
+type Aggregation int
+
+const (
+	AggMin   Aggregation = iota + 1 //最小值
+	AggMax                          //最大值
+	AggAvg                          //平均值
+	AggSum                          //求和
+	AggCount                        //数据点数量
+	AggLast                         //最后一个值
+)
+
+// 空桶的填充方式
+type Fill int
+
+const (
+	FillNone     Fill = iota //不输出空桶
+	FillNaN                  //以 NaN 填充
+	FillZero                 //以 0 填充
+	FillPrevious             //以前一个非空桶的值填充，之前没有非空桶时不输出
+	FillLinear               //以前后非空桶线性插值填充，两侧缺少非空桶时不输出
+)
+
+// 一个时间桶的聚合结果
+type Bucket struct {
+	Start int64 //桶的起始时间戳 [Start, Start+step)
+	Count int
+	Min   float64
+	Max   float64
+	Sum   float64
+	Last  float64
+}
+
+// 平均值
+func (b Bucket) Avg() float64 {
+	if b.Count == 0 {
+		return math.NaN()
+	}
+	return b.Sum / float64(b.Count)
+}
+
+// 取指定聚合方式的值
+func (b Bucket) Value(agg Aggregation) float64 {
+	switch agg {
+	case AggMin:
+		return b.Min
+	case AggMax:
+		return b.Max
+	case AggAvg:
+		return b.Avg()
+	case AggSum:
+		return b.Sum
+	case AggCount:
+		return float64(b.Count)
+	case AggLast:
+		return b.Last
+	}
+	return math.NaN()
+}
+
+// [start, end) 按 step 划分的桶数量  end-start 可能超出 int64，按 uint64 计算
+func bucketCount(start, end, step int64) uint64 {
+	return (uint64(end-start)-1)/uint64(step) + 1
+}
+
+// 第k个桶的起始时间戳  k*step 可能超出 int64，按补码回绕相加后结果仍在 [start, end) 内
+func bucketStart(start, step int64, k uint64) int64 {
+	return start + int64(k*uint64(step))
+}
+
+// 校验参数并返回桶数量
+func checkBuckets(start, end, step int64) (uint64, error) {
+	if step <= 0 {
+		return 0, stepErr
+	}
+	if end <= start {
+		return 0, rangeErr
+	}
+	return bucketCount(start, end, step), nil
+}
+
+/*
+聚合 [start, end) 内的数据点，只返回非空桶
+桶的数量与数据点数量相关，不受区间宽度影响
+*/
+func (s *Series) sparseBuckets(start, end, step int64) []Bucket {
+	s.mu.RLock()
+	points := s.rangePoints(start, end)
+	s.mu.RUnlock()
+
+	buckets := []Bucket{}
+	for _, p := range points {
+		bs := bucketStart(start, step, uint64(p.Timestamp-start)/uint64(step))
+		if len(buckets) == 0 || buckets[len(buckets)-1].Start != bs {
+			buckets = append(buckets, Bucket{Start: bs})
+		}
+		b := &buckets[len(buckets)-1]
+		if b.Count == 0 || p.Value < b.Min {
+			b.Min = p.Value
+		}
+		if b.Count == 0 || p.Value > b.Max {
+			b.Max = p.Value
+		}
+		b.Sum += p.Value
+		b.Last = p.Value
+		b.Count++
+	}
+	return buckets
+}
+
+// 在非空桶之间补齐空桶  n 为桶的总数量
+func expandBuckets(sparse []Bucket, start, step int64, n uint64) []Bucket {
+	buckets := make([]Bucket, n)
+	j := 0
+	for k := range buckets {
+		bs := bucketStart(start, step, uint64(k))
+		if j < len(sparse) && sparse[j].Start == bs {
+			buckets[k] = sparse[j]
+			j++
+			continue
+		}
+		buckets[k].Start = bs
+	}
+	return buckets
+}
+
+/*
+按固定步长将 [start, end) 内的数据点聚合为桶
+桶的边界为 start + i*step，返回所有桶 (包括空桶，空桶的 Count 为0)，桶的数量超过 WithMaxBuckets 的限制时返回错误
+*/
+func (s *Series) Buckets(start, end, step int64) ([]Bucket, error) {
+	n, err := checkBuckets(start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(s.maxBuckets) {
+		return nil, bucketsErr
+	}
+	return expandBuckets(s.sparseBuckets(start, end, step), start, step, n), nil
+}
+
+/*
+降采样
+将 [start, end) 按 step 分桶并按 agg 聚合，每个桶输出一个数据点 (时间戳为桶的起始时间)，空桶按 fill 处理。
+FillNone 只输出非空桶，不限制区间宽度; 其他填充方式需要输出空桶，桶的数量超过 WithMaxBuckets 的限制时返回错误
+*/
+func (s *Series) Downsample(start, end, step int64, agg Aggregation, fill Fill) ([]Point, error) {
+	if agg < AggMin || agg > AggLast {
+		return nil, aggregationErr
+	}
+	if fill < FillNone || fill > FillLinear {
+		return nil, fillErr
+	}
+	n, err := checkBuckets(start, end, step)
+	if err != nil {
+		return nil, err
+	}
+	if fill != FillNone && n > uint64(s.maxBuckets) {
+		return nil, bucketsErr
+	}
+	sparse := s.sparseBuckets(start, end, step)
+	if fill == FillNone {
+		points := make([]Point, len(sparse))
+		for k := range sparse {
+			points[k] = Point{Timestamp: sparse[k].Start, Value: sparse[k].Value(agg)}
+		}
+		return points, nil
+	}
+	buckets := expandBuckets(sparse, start, step, n)
+	points := make([]Point, 0, len(buckets))
+	prev, next := -1, 0 //前一个、后一个非空桶
+	for k := range buckets {
+		b := buckets[k]
+		if b.Count > 0 {
+			prev = k
+			points = append(points, Point{Timestamp: b.Start, Value: b.Value(agg)})
+			continue
+		}
+		switch fill {
+		case FillNaN:
+			points = append(points, Point{Timestamp: b.Start, Value: math.NaN()})
+		case FillZero:
+			points = append(points, Point{Timestamp: b.Start, Value: 0})
+		case FillPrevious:
+			if prev >= 0 {
+				points = append(points, Point{Timestamp: b.Start, Value: buckets[prev].Value(agg)})
+			}
+		case FillLinear:
+			//每段连续的空桶只查找一次后一个非空桶
+			if next <= k {
+				for next = k + 1; next < len(buckets) && buckets[next].Count == 0; next++ {
+				}
+			}
+			if prev >= 0 && next < len(buckets) {
+				v0, v1 := buckets[prev].Value(agg), buckets[next].Value(agg)
+				ratio := float64(k-prev) / float64(next-prev)
+				points = append(points, Point{Timestamp: b.Start, Value: v0 + (v1-v0)*ratio})
+			}
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,13 @@
+package timeseries
+
+import "errors"
+
+var (
+	retentionErr   = errors.New("retention must grater than 0")
+	stepErr        = errors.New("step must grater than 0")
+	rangeErr       = errors.New("end must grater than start")
+	aggregationErr = errors.New("unknown aggregation")
+	fillErr        = errors.New("unknown fill policy")
+	maxBucketsErr  = errors.New("max buckets must grater than 0")
+	bucketsErr     = errors.New("too many buckets, use a larger step or a narrower range")
+)
@@ -0,0 +1,149 @@
+package timeseries
+
+import (
+	"sync"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+// 数据点  时间戳单位由调用侧决定 (如 unix 毫秒)，降采样的步长使用相同单位
+type Point struct {
+	Timestamp int64
+	Value     float64
+}
+
+/*
+时间序列
+数据点按时间戳存储在 skiplist.SkipList 中，相同时间戳的数据点按写入顺序排列
+*/
+type Series struct {
+	mu         sync.RWMutex
+	sl         *skiplist.SkipList //timestamp -> Point
+	retention  int64              //保留时长，0 表示不过期
+	maxBuckets int                //需要输出空桶时允许的最大桶数量
+}
+
+const defaultMaxBuckets = 1 << 20 //默认最大桶数量
+
+type Option func(*Series) error
+
+// 设置保留时长  写入时删除时间戳早于 (最新时间戳 - retention) 的数据点
+func WithRetention(retention int64) Option {
+	return func(s *Series) error {
+		if retention <= 0 {
+			return retentionErr
+		}
+		s.retention = retention
+		return nil
+	}
+}
+
+// 设置 Buckets 及需要填充空桶的 Downsample 允许的最大桶数量，默认为 1<<20
+func WithMaxBuckets(n int) Option {
+	return func(s *Series) error {
+		if n <= 0 {
+			return maxBucketsErr
+		}
+		s.maxBuckets = n
+		return nil
+	}
+}
+
+func New(options ...Option) (*Series, error) {
+	sl, err := skiplist.New(skiplist.Ordered[int64]())
+	if err != nil {
+		return nil, err
+	}
+	s := &Series{sl: sl, maxBuckets: defaultMaxBuckets}
+	for k := range options {
+		if err := options[k](s); err != nil {
+			sl.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// 关闭序列  结束跳表的层数生成协程，不再使用时需要调用，关闭后仍可读写
+func (s *Series) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sl.Close()
+}
+
+// 写入数据点  早于保留窗口的数据点会被直接丢弃
+func (s *Series) Append(timestamp int64, value float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retention > 0 && s.sl.GetLength() > 0 {
+		newest := s.sl.GetTail().(Point).Timestamp
+		if timestamp < newest-s.retention {
+			return
+		}
+	}
+	s.sl.Insert(timestamp, Point{Timestamp: timestamp, Value: value})
+	if s.retention > 0 {
+		s.truncate(s.sl.GetTail().(Point).Timestamp - s.retention)
+	}
+}
+
+// 数据点数量
+func (s *Series) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sl.GetLength()
+}
+
+// 最早与最新的数据点
+func (s *Series) Bounds() (first, last Point, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.sl.GetLength() == 0 {
+		return Point{}, Point{}, false
+	}
+	return s.sl.GetFirst().(Point), s.sl.GetTail().(Point), true
+}
+
+// 获取 [start, end) 内的数据点
+func (s *Series) Range(start, end int64) []Point {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rangePoints(start, end)
+}
+
+func (s *Series) rangePoints(start, end int64) []Point {
+	points := []Point{}
+	if start >= end {
+		return points
+	}
+	_, startRank := s.sl.GetCeilingWithRankByKey(start)
+	if startRank < 1 {
+		return points
+	}
+	endRank := s.sl.GetLength()
+	if _, rank := s.sl.GetCeilingWithRankByKey(end); rank > 0 {
+		endRank = rank - 1
+	}
+	data := s.sl.GetByRankRange(startRank, endRank)
+	points = make([]Point, len(data))
+	for k := range data {
+		points[k] = data[k].(Point)
+	}
+	return points
+}
+
+// 从头部删除时间戳早于 before 的数据点，返回删除的数量
+func (s *Series) Truncate(before int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.truncate(before)
+}
+
+func (s *Series) truncate(before int64) int {
+	count := 0
+	for s.sl.GetLength() > 0 && s.sl.GetFirst().(Point).Timestamp < before {
+		s.sl.DeleteByRank(1)
+		count++
+	}
+	return count
+}
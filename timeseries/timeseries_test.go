@@ -0,0 +1,171 @@
+package timeseries
+
+import (
+	"math"
+	"runtime"
+	"testing"
+)
+
+func samePoints(a, b []Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k].Timestamp != b[k].Timestamp {
+			return false
+		}
+		if a[k].Value != b[k].Value && !(math.IsNaN(a[k].Value) && math.IsNaN(b[k].Value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestRange 测试范围查询与乱序写入
+func TestRange(t *testing.T) {
+	s, _ := New()
+	for _, ts := range []int64{50, 10, 30, 20, 40, 30} {
+		s.Append(ts, float64(ts))
+	}
+	if got := s.Range(20, 40); !samePoints(got, []Point{{20, 20}, {30, 30}, {30, 30}}) {
+		t.Fatalf("Range(20, 40) = %v", got)
+	}
+	if got := s.Range(0, 100); len(got) != 6 || got[0].Timestamp != 10 || got[5].Timestamp != 50 {
+		t.Fatalf("Range(0, 100) = %v", got)
+	}
+	if got := s.Range(51, 100); len(got) != 0 {
+		t.Fatalf("Range(51, 100) = %v", got)
+	}
+	if got := s.Range(40, 40); len(got) != 0 {
+		t.Fatalf("Range(40, 40) = %v", got)
+	}
+	if n := s.Truncate(30); n != 2 || s.Len() != 4 {
+		t.Fatalf("Truncate 删除 %d 个, 剩余 %d", n, s.Len())
+	}
+	if first, last, ok := s.Bounds(); !ok || first.Timestamp != 30 || last.Timestamp != 50 {
+		t.Fatalf("Bounds = %v %v %v", first, last, ok)
+	}
+}
+
+// TestRetention 写入时按保留时长删除过期数据点
+func TestRetention(t *testing.T) {
+	s, _ := New(WithRetention(100))
+	for ts := int64(0); ts < 1000; ts += 10 {
+		s.Append(ts, 1)
+	}
+	first, last, _ := s.Bounds()
+	if first.Timestamp != 890 || last.Timestamp != 990 || s.Len() != 11 {
+		t.Fatalf("保留窗口错误: %v %v %d", first, last, s.Len())
+	}
+	s.Append(500, 1)
+	if s.Len() != 11 {
+		t.Fatal("早于保留窗口的数据点应被丢弃")
+	}
+	if _, err := New(WithRetention(0)); err != retentionErr {
+		t.Fatalf("保留时长校验错误: %v", err)
+	}
+}
+
+// TestDownsample 测试聚合方式与空桶填充
+func TestDownsample(t *testing.T) {
+	s, _ := New()
+	//桶 [0,10): 1 3 2   桶 [10,20): 空   桶 [20,30): 空   桶 [30,40): 8 4
+	for _, p := range []Point{{0, 1}, {5, 3}, {9, 2}, {31, 8}, {39, 4}, {40, 100}} {
+		s.Append(p.Timestamp, p.Value)
+	}
+	nan := math.NaN()
+	cases := []struct {
+		agg  Aggregation
+		fill Fill
+		want []Point
+	}{
+		{AggMin, FillNone, []Point{{0, 1}, {30, 4}}},
+		{AggMax, FillNone, []Point{{0, 3}, {30, 8}}},
+		{AggAvg, FillNone, []Point{{0, 2}, {30, 6}}},
+		{AggSum, FillNone, []Point{{0, 6}, {30, 12}}},
+		{AggCount, FillZero, []Point{{0, 3}, {10, 0}, {20, 0}, {30, 2}}},
+		{AggLast, FillNaN, []Point{{0, 2}, {10, nan}, {20, nan}, {30, 4}}},
+		{AggLast, FillPrevious, []Point{{0, 2}, {10, 2}, {20, 2}, {30, 4}}},
+		{AggMin, FillLinear, []Point{{0, 1}, {10, 2}, {20, 3}, {30, 4}}},
+	}
+	for k, c := range cases {
+		got, err := s.Downsample(0, 40, 10, c.agg, c.fill)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !samePoints(got, c.want) {
+			t.Fatalf("case %d: got %v want %v", k, got, c.want)
+		}
+	}
+
+	//桶数量向上取整，最后一个桶不完整
+	buckets, _ := s.Buckets(0, 41, 20)
+	if len(buckets) != 3 || buckets[2].Start != 40 || buckets[2].Count != 1 {
+		t.Fatalf("Buckets = %v", buckets)
+	}
+	if _, err := s.Downsample(0, 40, 0, AggMin, FillNone); err != stepErr {
+		t.Fatalf("步长校验错误: %v", err)
+	}
+	if _, err := s.Downsample(0, 40, 10, 0, FillNone); err != aggregationErr {
+		t.Fatalf("聚合方式校验错误: %v", err)
+	}
+}
+
+// TestWideRange 测试超大区间: 不溢出，只按数据点输出非空桶，需要输出空桶时限制桶数量
+func TestWideRange(t *testing.T) {
+	s, _ := New(WithMaxBuckets(100))
+	s.Append(math.MinInt64, 1)
+	s.Append(-5, 2)
+	s.Append(5, 3)
+	s.Append(math.MaxInt64-1, 4)
+
+	got, err := s.Downsample(math.MinInt64, math.MaxInt64, 10, AggSum, FillNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//桶边界为 MinInt64 + i*10
+	want := []Point{{math.MinInt64, 1}, {-8, 2}, {2, 3}, {math.MaxInt64 - 5, 4}}
+	if !samePoints(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if _, err = s.Buckets(math.MinInt64, math.MaxInt64, 1); err != bucketsErr {
+		t.Fatalf("桶数量超过限制时应返回错误, got: %v", err)
+	}
+	if _, err = s.Downsample(0, 1001, 10, AggSum, FillLinear); err != bucketsErr {
+		t.Fatalf("桶数量超过限制时应返回错误, got: %v", err)
+	}
+	if got, _ = s.Downsample(-20, 20, 10, AggSum, FillLinear); len(got) != 2 {
+		t.Fatalf("got %v", got)
+	}
+	if _, err = New(WithMaxBuckets(0)); err != maxBucketsErr {
+		t.Fatalf("got %v", err)
+	}
+}
+
+// TestClose 测试关闭后协程退出且仍可读写
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	list := []*Series{}
+	for i := 0; i < 20; i++ {
+		s, _ := New()
+		s.Append(int64(i), float64(i))
+		list = append(list, s)
+	}
+	for _, s := range list {
+		s.Close()
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	s := list[0]
+	s.Append(1, 1)
+	s.Append(2, 2)
+	points := s.Range(0, 10)
+	want := []Point{{0, 0}, {1, 1}, {2, 2}}
+	if !samePoints(points, want) {
+		t.Fatalf("关闭后读写错误 %v", points)
+	}
+	if _, err := New(WithMaxBuckets(0)); err == nil {
+		t.Fatal("want error")
+	}
+}
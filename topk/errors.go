@@ -0,0 +1,9 @@
+package topk
+
+import "errors"
+
+var (
+	windowErr = errors.New("window must grater than 0")
+	bucketErr = errors.New("bucket count must grater than 0")
+	clockErr  = errors.New("clock is nil")
+)
@@ -0,0 +1,207 @@
+package topk
+
+import (
+	"cmp"
+	"sync"
+	"time"
+
+	"github.com/yytany/ds/queue"
+	"github.com/yytany/ds/skiplist"
+)
+
+// 计数结果
+type Item struct {
+	Key   string
+	Count int
+}
+
+// 时间桶  记录桶内时间段的增量
+type bucket struct {
+	start  time.Time
+	counts map[string]int
+}
+
+// 跳表中的key  计数大的在前，计数相同按key升序
+type countKey struct {
+	count int
+	key   string
+}
+
+func compareCount(a, b interface{}) int {
+	ka, kb := a.(countKey), b.(countKey)
+	if c := cmp.Compare(kb.count, ka.count); c != 0 {
+		return c
+	}
+	return cmp.Compare(ka.key, kb.key)
+}
+
+/*
+滑动窗口 Top-K
+窗口被划分为固定数量的时间桶，按时间顺序存放在 queue.LoopQueue 中; 每个key在窗口内的总计数存放在按计数排序的
+skiplist.SkipList 中。时间桶滑出窗口时扣减其中的计数，Increment 与 Top 均为 O(log n) (不计过期桶的扣减)。
+计数的时间精度为一个桶的宽度
+*/
+type TopK struct {
+	mu      sync.Mutex
+	window  time.Duration
+	width   time.Duration    //桶宽度
+	buckets *queue.LoopQueue //*bucket，队头最旧
+	totals  map[string]int   //窗口内的计数
+	sl      *skiplist.SkipList
+	now     func() time.Time
+}
+
+type Option func(*TopK) error
+
+// 设置时钟，便于测试
+func WithClock(now func() time.Time) Option {
+	return func(tk *TopK) error {
+		if now == nil {
+			return clockErr
+		}
+		tk.now = now
+		return nil
+	}
+}
+
+// 创建窗口为 window、划分为 bucketCount 个时间桶的 Top-K 计数器
+func New(window time.Duration, bucketCount int, options ...Option) (*TopK, error) {
+	if window <= 0 {
+		return nil, windowErr
+	}
+	if bucketCount < 1 || window/time.Duration(bucketCount) <= 0 {
+		return nil, bucketErr
+	}
+	buckets, err := queue.NewLoopQueue(bucketCount)
+	if err != nil {
+		return nil, err
+	}
+	sl, err := skiplist.New(skiplist.CompareFunc(compareCount), skiplist.WithAllowTheSameKey(false))
+	if err != nil {
+		return nil, err
+	}
+	tk := &TopK{
+		window:  window,
+		width:   window / time.Duration(bucketCount),
+		buckets: buckets,
+		totals:  map[string]int{},
+		sl:      sl,
+		now:     time.Now,
+	}
+	for k := range options {
+		if err := options[k](tk); err != nil {
+			sl.Close()
+			return nil, err
+		}
+	}
+	return tk, nil
+}
+
+// 关闭计数器  结束跳表的层数生成协程，不再使用时需要调用，关闭后仍可计数
+func (tk *TopK) Close() {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.sl.Close()
+}
+
+// 计数加一
+func (tk *TopK) Increment(key string) {
+	tk.Add(key, 1)
+}
+
+// 计数增加 n
+func (tk *TopK) Add(key string, n int) {
+	if n == 0 {
+		return
+	}
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	now := tk.now()
+	tk.expire(now)
+	b := tk.current(now)
+	b.counts[key] += n
+	tk.update(key, n)
+}
+
+// 计数最大的 k 个
+func (tk *TopK) Top(k int) []Item {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.expire(tk.now())
+	data := tk.sl.GetByRankRange(1, k)
+	items := make([]Item, len(data))
+	for i := range data {
+		key := data[i].(countKey)
+		items[i] = Item{Key: key.key, Count: key.count}
+	}
+	return items
+}
+
+// key 在窗口内的计数
+func (tk *TopK) Count(key string) int {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.expire(tk.now())
+	return tk.totals[key]
+}
+
+// 窗口内计数不为0的key数量
+func (tk *TopK) Len() int {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.expire(tk.now())
+	return tk.sl.GetLength()
+}
+
+// 当前时间所在的桶，不存在时创建  队列满时先淘汰最旧的桶
+func (tk *TopK) current(now time.Time) *bucket {
+	start := now.Truncate(tk.width)
+	if tail, err := tk.buckets.Tail(); err == nil && !tail.(*bucket).start.Before(start) {
+		//时钟回拨时计入最新的桶
+		return tail.(*bucket)
+	}
+	if tk.buckets.IsFull() {
+		tk.evict()
+	}
+	b := &bucket{start: start, counts: map[string]int{}}
+	tk.buckets.Push(b)
+	return b
+}
+
+// 淘汰滑出窗口的桶  窗口由当前时间所在的桶及之前的 bucketCount-1 个桶组成
+func (tk *TopK) expire(now time.Time) {
+	edge := now.Truncate(tk.width).Add(-tk.window)
+	for {
+		front, err := tk.buckets.Front()
+		if err != nil || front.(*bucket).start.After(edge) {
+			return
+		}
+		tk.evict()
+	}
+}
+
+// 淘汰最旧的桶并扣减其中的计数
+func (tk *TopK) evict() {
+	front, err := tk.buckets.Pop()
+	if err != nil {
+		return
+	}
+	for key, n := range front.(*bucket).counts {
+		tk.update(key, -n)
+	}
+}
+
+// 更新key的总计数及其在跳表中的位置
+func (tk *TopK) update(key string, delta int) {
+	old := tk.totals[key]
+	count := old + delta
+	if old != 0 {
+		tk.sl.DeleteByKey(countKey{count: old, key: key})
+	}
+	if count == 0 {
+		delete(tk.totals, key)
+		return
+	}
+	tk.totals[key] = count
+	tk.sl.Insert(countKey{count: count, key: key}, countKey{count: count, key: key})
+}
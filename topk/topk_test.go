@@ -0,0 +1,175 @@
+package topk
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// 手动推进的时钟
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestTopK(t *testing.T, window time.Duration, buckets int) (*TopK, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	tk, err := New(window, buckets, WithClock(clock.now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tk, clock
+}
+
+func items(list []Item) string {
+	s := ""
+	for _, it := range list {
+		s += fmt.Sprintf("%s:%d ", it.Key, it.Count)
+	}
+	return s
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(0, 1); err != windowErr {
+		t.Fatalf("want windowErr, got %v", err)
+	}
+	if _, err := New(time.Second, 0); err != bucketErr {
+		t.Fatalf("want bucketErr, got %v", err)
+	}
+	if _, err := New(time.Nanosecond, 2); err != bucketErr {
+		t.Fatalf("want bucketErr, got %v", err)
+	}
+	if _, err := New(time.Second, 1, WithClock(nil)); err != clockErr {
+		t.Fatalf("want clockErr, got %v", err)
+	}
+}
+
+func TestTop(t *testing.T) {
+	tk, _ := newTestTopK(t, time.Minute, 6)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		tk.Add(key, i+1)
+	}
+	tk.Increment("a")
+	tk.Increment("a")
+	//计数相同按key升序
+	if got, want := items(tk.Top(3)), "d:4 a:3 c:3 "; got != want {
+		t.Fatalf("Top(3) = %s want %s", got, want)
+	}
+	if got, want := items(tk.Top(10)), "d:4 a:3 c:3 b:2 "; got != want {
+		t.Fatalf("Top(10) = %s want %s", got, want)
+	}
+	if got := tk.Top(0); len(got) != 0 {
+		t.Fatalf("Top(0) = %v", got)
+	}
+	if tk.Count("a") != 3 || tk.Count("x") != 0 || tk.Len() != 4 {
+		t.Fatalf("Count/Len mismatch")
+	}
+	//计数减为0时移除
+	tk.Add("b", -2)
+	if tk.Len() != 3 || tk.Count("b") != 0 {
+		t.Fatalf("b should be removed, len %d", tk.Len())
+	}
+}
+
+// TestExpire 测试时间桶滑出窗口后计数衰减
+func TestExpire(t *testing.T) {
+	tk, clock := newTestTopK(t, 3*time.Second, 3)
+	tk.Add("a", 5) //桶 1000
+	clock.advance(time.Second)
+	tk.Add("b", 3) //桶 1001
+	tk.Add("a", 1)
+	clock.advance(time.Second)
+	tk.Add("c", 2) //桶 1002
+	if got, want := items(tk.Top(3)), "a:6 b:3 c:2 "; got != want {
+		t.Fatalf("Top = %s want %s", got, want)
+	}
+	clock.advance(time.Second) //桶 1000 滑出窗口
+	if got, want := items(tk.Top(3)), "b:3 c:2 a:1 "; got != want {
+		t.Fatalf("Top = %s want %s", got, want)
+	}
+	clock.advance(time.Second)
+	if got, want := items(tk.Top(3)), "c:2 "; got != want {
+		t.Fatalf("Top = %s want %s", got, want)
+	}
+	//长时间无写入后全部过期
+	clock.advance(time.Hour)
+	if tk.Len() != 0 || len(tk.Top(3)) != 0 {
+		t.Fatalf("all counts should expire, len %d", tk.Len())
+	}
+	tk.Increment("d")
+	if got, want := items(tk.Top(3)), "d:1 "; got != want {
+		t.Fatalf("Top = %s want %s", got, want)
+	}
+}
+
+// TestRandom 与按时间戳暴力统计的结果对比
+func TestRandom(t *testing.T) {
+	const window = 10 * time.Second
+	tk, clock := newTestTopK(t, window, 10)
+	rd := rand.New(rand.NewSource(1))
+	type event struct {
+		at  time.Time
+		key string
+	}
+	events := []event{}
+	for i := 0; i < 5000; i++ {
+		clock.advance(time.Duration(rd.Intn(300)) * time.Millisecond)
+		key := fmt.Sprintf("k%d", rd.Intn(20))
+		tk.Increment(key)
+		events = append(events, event{clock.t, key})
+		if i%50 != 0 {
+			continue
+		}
+		//桶精度下，窗口起点为 当前桶起点 - window + 桶宽
+		width := window / 10
+		edge := clock.t.Truncate(width).Add(width - window)
+		want := map[string]int{}
+		for _, e := range events {
+			if !e.at.Before(edge) {
+				want[e.key]++
+			}
+		}
+		top := tk.Top(100)
+		if len(top) != len(want) {
+			t.Fatalf("step %d: len %d want %d", i, len(top), len(want))
+		}
+		for k, it := range top {
+			if want[it.Key] != it.Count {
+				t.Fatalf("step %d: %s count %d want %d", i, it.Key, it.Count, want[it.Key])
+			}
+			if k > 0 && (top[k-1].Count < it.Count || top[k-1].Count == it.Count && top[k-1].Key > it.Key) {
+				t.Fatalf("step %d: order broken at %d", i, k)
+			}
+		}
+	}
+}
+
+// TestClose 测试关闭后协程退出且仍可计数
+func TestClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	list := []*TopK{}
+	for i := 0; i < 20; i++ {
+		tk, _ := New(time.Minute, 6)
+		tk.Increment("a")
+		list = append(list, tk)
+	}
+	for _, tk := range list {
+		tk.Close()
+	}
+	if _, err := New(time.Second, 1, WithClock(nil)); err != clockErr {
+		t.Fatalf("want clockErr, got %v", err)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("关闭后协程数 %d -> %d", before, n)
+	}
+	tk := list[0]
+	tk.Add("b", 2)
+	if got, want := items(tk.Top(2)), "b:2 a:1 "; got != want {
+		t.Fatalf("关闭后 Top(2) = %s want %s", got, want)
+	}
+}
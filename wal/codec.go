@@ -0,0 +1,34 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// key 与 data 的序列化接口
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(b []byte) (interface{}, error)
+}
+
+/*
+默认的 gob 编码
+key/data 的具体类型需要预先通过 gob.Register 注册，例如 gob.Register(skiplist.CmpInstanceInt(0))
+*/
+type GobCodec struct{}
+
+func (GobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,13 @@
+package wal
+
+import "errors"
+
+var (
+	closedErr       = errors.New("wal is closed")
+	syncPolicyErr   = errors.New("unknown sync policy")
+	syncIntervalErr = errors.New("sync interval must grater than 0")
+	codecErr        = errors.New("codec is nil")
+	listErr         = errors.New("skiplist is nil")
+	recordErr       = errors.New("wal record is corrupted")
+	opErr           = errors.New("unknown wal operation")
+)
@@ -0,0 +1,50 @@
+package wal
+
+import "time"
+
+// 刷盘策略
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota //每条记录写入后都 fsync
+	SyncInterval                   //按固定间隔 fsync
+	SyncNever                      //不主动 fsync，交给操作系统
+)
+
+const defaultSyncInterval = time.Second //默认刷盘间隔
+
+type Option func(*Log) error
+
+// 设置刷盘策略
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(l *Log) error {
+		if policy < SyncAlways || policy > SyncNever {
+			return syncPolicyErr
+		}
+		l.policy = policy
+		return nil
+	}
+}
+
+// 设置刷盘间隔，同时将刷盘策略设置为 SyncInterval
+func WithSyncInterval(interval time.Duration) Option {
+	return func(l *Log) error {
+		if interval <= 0 {
+			return syncIntervalErr
+		}
+		l.policy = SyncInterval
+		l.interval = interval
+		return nil
+	}
+}
+
+// 设置 key/data 的序列化方式，默认为 GobCodec
+func WithCodec(codec Codec) Option {
+	return func(l *Log) error {
+		if codec == nil {
+			return codecErr
+		}
+		l.codec = codec
+		return nil
+	}
+}
@@ -0,0 +1,66 @@
+package wal
+
+import (
+	"errors"
+	"io"
+
+	"github.com/yytany/ds/internal/frame"
+)
+
+/*
+记录格式见 internal/frame，帧类型为 op
+日志中每条记录的 seq 依次递增，快照以一条 opSnapshot 记录开头，其 seq 为快照包含的最后一条日志记录
+*/
+
+// 操作类型
+type op byte
+
+const (
+	opInsert op = iota + 1
+	opUpdateByKey
+	opUpdateBatchByKey
+	opUpdateByRank
+	opDeleteByKey
+	opDeleteBatchByKey
+	opDeleteByRank
+	opSnapshot //快照头部
+)
+
+// 一条日志记录  key/data 为编码后的字节
+type record struct {
+	op   op
+	seq  uint64
+	rank int
+	key  []byte
+	data []byte
+}
+
+// 编码记录(含头部)
+func (r *record) marshal() []byte {
+	f := frame.Frame{Type: byte(r.op), Seq: r.seq, Rank: r.rank, Key: r.key, Data: r.data}
+	return f.Marshal()
+}
+
+// 顺序读取记录
+type recordReader struct {
+	*frame.Reader
+}
+
+func newRecordReader(r io.Reader) *recordReader {
+	return &recordReader{frame.NewReader(r)}
+}
+
+/*
+读取下一条记录
+返回 io.EOF 表示正常结束; 返回 io.ErrUnexpectedEOF 或 recordErr 表示尾部存在写了一半或损坏的记录
+*/
+func (rr *recordReader) next() (*record, error) {
+	f, err := rr.Next()
+	if err != nil {
+		if errors.Is(err, frame.CorruptedErr) {
+			err = recordErr
+		}
+		return nil, err
+	}
+	return &record{op: op(f.Type), seq: f.Seq, rank: f.Rank, key: f.Key, data: f.Data}, nil
+}
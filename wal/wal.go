@@ -0,0 +1,456 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yytany/ds/skiplist"
+)
+
+const (
+	logFileName      = "wal.log"  //日志文件
+	snapshotFileName = "snapshot" //快照文件
+)
+
+/*
+跳表预写日志
+所有通过 Log 执行的变更操作会先以带长度前缀及 crc 校验的记录追加到日志文件，再作用到跳表上。
+每条记录带有递增的序列号，快照中记录其包含的最后一个序列号，
+Open 时先加载快照再重放日志中序列号更大的记录，以重建跳表; Snapshot 写入快照后截断日志。
+读操作直接使用 List() 返回的跳表，需要与写操作并发时由调用侧保证同步
+*/
+type Log struct {
+	mu       sync.Mutex
+	dir      string
+	sl       *skiplist.SkipList
+	f        logFile
+	offset   int64  //最后一条完整记录的结束位置
+	seq      uint64 //最后一条已执行记录的序列号
+	err      error  //写入失败后无法截断日志时记录，之后拒绝写入
+	codec    Codec
+	policy   SyncPolicy
+	interval time.Duration
+	dirty    bool //是否有未刷盘的记录
+	closed   bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// 日志文件
+type logFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+/*
+打开 dir 下的日志，并将快照及日志中的数据恢复到 sl 中
+sl 应为新建的空跳表，比较接口与允许重复key的设置需要与写日志时一致
+*/
+func Open(dir string, sl *skiplist.SkipList, options ...Option) (*Log, error) {
+	if sl == nil {
+		return nil, listErr
+	}
+	l := &Log{
+		dir:      dir,
+		sl:       sl,
+		codec:    GobCodec{},
+		policy:   SyncAlways,
+		interval: defaultSyncInterval,
+		done:     make(chan struct{}),
+	}
+	for k := range options {
+		if err := options[k](l); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := l.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := l.replay(); err != nil {
+		return nil, err
+	}
+	if l.policy == SyncInterval {
+		l.wg.Add(1)
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+// 加载快照
+func (l *Log) loadSnapshot() error {
+	f, err := os.Open(filepath.Join(l.dir, snapshotFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	rr := newRecordReader(f)
+	for {
+		rec, err := rr.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			//快照是完整写入后才重命名的，不允许损坏
+			return err
+		}
+		if rec.op == opSnapshot {
+			l.seq = rec.seq
+			continue
+		}
+		if err = l.apply(rec); err != nil {
+			return err
+		}
+	}
+}
+
+/*
+重放日志，跳过已包含在快照中的记录
+尾部写了一半或损坏的记录会被截断; 损坏的记录之后还有数据时不是崩溃造成的，返回错误而不丢弃之后的记录
+*/
+func (l *Log) replay() error {
+	f, err := os.OpenFile(filepath.Join(l.dir, logFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rr := newRecordReader(f)
+	for {
+		rec, err := rr.next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, recordErr) && rr.End < stat.Size() {
+			f.Close()
+			return fmt.Errorf("wal: record at offset %d: %w", rr.Offset, recordErr)
+		}
+		if err == io.ErrUnexpectedEOF || errors.Is(err, recordErr) {
+			//崩溃时写了一半的记录，截断后继续追加
+			if err = f.Truncate(rr.Offset); err != nil {
+				f.Close()
+				return err
+			}
+			break
+		}
+		if err == nil && rec.seq > l.seq {
+			//快照重命名后、截断日志前崩溃时，日志中的记录已包含在快照中
+			err = l.apply(rec)
+			l.seq = rec.seq
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err = f.Seek(rr.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.offset = rr.Offset
+	return nil
+}
+
+// 将记录作用到跳表
+func (l *Log) apply(rec *record) error {
+	var key, data interface{}
+	var err error
+	switch rec.op {
+	case opInsert, opUpdateByKey, opUpdateBatchByKey, opDeleteByKey, opDeleteBatchByKey:
+		if key, err = l.codec.Decode(rec.key); err != nil {
+			return err
+		}
+	}
+	switch rec.op {
+	case opInsert, opUpdateByKey, opUpdateBatchByKey, opUpdateByRank:
+		if data, err = l.codec.Decode(rec.data); err != nil {
+			return err
+		}
+	}
+	switch rec.op {
+	case opInsert:
+		l.sl.Insert(key, data)
+	case opUpdateByKey:
+		l.sl.UpdateByKey(key, data)
+	case opUpdateBatchByKey:
+		l.sl.UpdateBatchByKey(key, data)
+	case opUpdateByRank:
+		l.sl.UpdateByRank(rec.rank, data)
+	case opDeleteByKey:
+		l.sl.DeleteByKey(key)
+	case opDeleteBatchByKey:
+		l.sl.DeleteBatchByKey(key)
+	case opDeleteByRank:
+		l.sl.DeleteByRank(rec.rank)
+	default:
+		return opErr
+	}
+	return nil
+}
+
+// 编码并追加一条记录
+func (l *Log) append(o op, rank int, key, data interface{}, withKey, withData bool) error {
+	if l.closed {
+		return closedErr
+	}
+	if l.err != nil {
+		return l.err
+	}
+	rec := &record{op: o, seq: l.seq + 1, rank: rank}
+	var err error
+	if withKey {
+		if rec.key, err = l.codec.Encode(key); err != nil {
+			return err
+		}
+	}
+	if withData {
+		if rec.data, err = l.codec.Encode(data); err != nil {
+			return err
+		}
+	}
+	buf := rec.marshal()
+	if _, err = l.f.Write(buf); err != nil {
+		//写了一半的记录会使之后追加的记录在重放时被丢弃，截断到最后一条完整记录
+		if terr := l.rewind(); terr != nil {
+			l.err = terr
+		}
+		return err
+	}
+	l.offset += int64(len(buf))
+	l.seq = rec.seq
+	if l.policy == SyncAlways {
+		return l.f.Sync()
+	}
+	l.dirty = true
+	return nil
+}
+
+// 截断到最后一条完整记录的结束位置
+func (l *Log) rewind() error {
+	if err := l.f.Truncate(l.offset); err != nil {
+		return err
+	}
+	_, err := l.f.Seek(l.offset, io.SeekStart)
+	return err
+}
+
+// 定时刷盘
+func (l *Log) syncLoop() {
+	defer l.wg.Done()
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			l.Sync()
+		}
+	}
+}
+
+// 获取被记录的跳表
+func (l *Log) List() *skiplist.SkipList {
+	return l.sl
+}
+
+// 记录并插入数据，返回值同 SkipList.Insert
+func (l *Log) Insert(key, data interface{}) (int, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(opInsert, 0, key, data, true, true); err != nil {
+		return 0, false, err
+	}
+	rank, ok := l.sl.Insert(key, data)
+	return rank, ok, nil
+}
+
+// 记录并执行 SkipList.UpdateByKey
+func (l *Log) UpdateByKey(key, data interface{}) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(opUpdateByKey, 0, key, data, true, true); err != nil {
+		return false, err
+	}
+	return l.sl.UpdateByKey(key, data), nil
+}
+
+// 记录并执行 SkipList.UpdateBatchByKey
+func (l *Log) UpdateBatchByKey(key, data interface{}) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(opUpdateBatchByKey, 0, key, data, true, true); err != nil {
+		return false, err
+	}
+	return l.sl.UpdateBatchByKey(key, data), nil
+}
+
+// 记录并执行 SkipList.UpdateByRank
+func (l *Log) UpdateByRank(rank int, data interface{}) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rank < 1 || rank > l.sl.GetLength() {
+		return false, nil
+	}
+	if err := l.append(opUpdateByRank, rank, nil, data, false, true); err != nil {
+		return false, err
+	}
+	return l.sl.UpdateByRank(rank, data), nil
+}
+
+// 记录并执行 SkipList.DeleteByKey
+func (l *Log) DeleteByKey(key interface{}) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(opDeleteByKey, 0, key, nil, true, false); err != nil {
+		return false, err
+	}
+	return l.sl.DeleteByKey(key), nil
+}
+
+// 记录并执行 SkipList.DeleteBatchByKey
+func (l *Log) DeleteBatchByKey(key interface{}) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.append(opDeleteBatchByKey, 0, key, nil, true, false); err != nil {
+		return false, err
+	}
+	return l.sl.DeleteBatchByKey(key), nil
+}
+
+// 记录并执行 SkipList.DeleteByRank
+func (l *Log) DeleteByRank(rank int) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rank < 1 || rank > l.sl.GetLength() {
+		return false, nil
+	}
+	if err := l.append(opDeleteByRank, rank, nil, nil, false, false); err != nil {
+		return false, err
+	}
+	return l.sl.DeleteByRank(rank), nil
+}
+
+// 将未刷盘的记录 fsync 到磁盘
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return closedErr
+	}
+	if !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.f.Sync()
+}
+
+/*
+写入快照并截断日志
+快照先写入临时文件, fsync 后重命名，之后再截断日志; 快照头部记录了最后一条日志记录的序列号，
+两步之间崩溃时重放会跳过日志中已包含在快照里的记录
+*/
+func (l *Log) Snapshot() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return closedErr
+	}
+	path := filepath.Join(l.dir, snapshotFileName)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	head := &record{op: opSnapshot, seq: l.seq}
+	if _, err = f.Write(head.marshal()); err != nil {
+		return err
+	}
+	it := skiplist.NewIterator(l.sl)
+	for it.Next(0); it.Valid(); it.Next(0) {
+		rec := &record{op: opInsert}
+		if rec.key, err = l.codec.Encode(it.Key()); err != nil {
+			return err
+		}
+		if rec.data, err = l.codec.Encode(it.Data()); err != nil {
+			return err
+		}
+		if _, err = f.Write(rec.marshal()); err != nil {
+			return err
+		}
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if err = syncDir(l.dir); err != nil {
+		return err
+	}
+	//快照已生效，截断失败时日志中的旧记录在重放时也会被跳过
+	if err = l.f.Truncate(0); err != nil {
+		return err
+	}
+	l.offset = 0
+	if _, err = l.f.Seek(0, io.SeekStart); err != nil {
+		l.err = err
+		return err
+	}
+	l.dirty = false
+	return l.f.Sync()
+}
+
+// 刷盘并关闭日志
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return closedErr
+	}
+	l.closed = true
+	l.mu.Unlock()
+	close(l.done)
+	l.wg.Wait()
+	if err := l.f.Sync(); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// 同步目录，保证重命名落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
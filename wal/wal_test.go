@@ -0,0 +1,252 @@
+package wal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yytany/ds/internal/frame"
+	"github.com/yytany/ds/skiplist"
+)
+
+func init() {
+	gob.Register(skiplist.CmpInstanceInt(0))
+}
+
+func newList(t *testing.T) *skiplist.SkipList {
+	var cmp *skiplist.CmpInstanceInt
+	sl, err := skiplist.New(cmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sl
+}
+
+// 按顺序导出跳表内容
+func dump(sl *skiplist.SkipList) []interface{} {
+	return sl.GetByRankRange(1, sl.GetLength())
+}
+
+func assertSame(t *testing.T, got, want *skiplist.SkipList) {
+	t.Helper()
+	g, w := dump(got), dump(want)
+	if len(g) != len(w) {
+		t.Fatalf("长度不一致 got %d want %d", len(g), len(w))
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			t.Fatalf("第 %d 个数据不一致 got %v want %v", i+1, g[i], w[i])
+		}
+	}
+}
+
+// 执行一组变更，同时作用到期望的跳表上
+func mutate(t *testing.T, l *Log, want *skiplist.SkipList, from, to int) {
+	for i := from; i < to; i++ {
+		key := skiplist.CmpInstanceInt(i % 50)
+		if _, _, err := l.Insert(key, i); err != nil {
+			t.Fatal(err)
+		}
+		want.Insert(key, i)
+		switch i % 7 {
+		case 1:
+			l.UpdateBatchByKey(key, -i)
+			want.UpdateBatchByKey(key, -i)
+		case 2:
+			l.DeleteBatchByKey(skiplist.CmpInstanceInt(i % 13))
+			want.DeleteBatchByKey(skiplist.CmpInstanceInt(i % 13))
+		case 3:
+			l.UpdateByRank(i%5+1, i*10)
+			want.UpdateByRank(i%5+1, i*10)
+		case 4:
+			l.DeleteByRank(i%3 + 1)
+			want.DeleteByRank(i%3 + 1)
+		case 5:
+			l.UpdateByKey(key, i*100)
+			want.UpdateByKey(key, i*100)
+		case 6:
+			l.DeleteByKey(key)
+			want.DeleteByKey(key)
+		}
+	}
+}
+
+// TestReplay 测试重启后通过快照与日志重建跳表
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	want := newList(t)
+	l, err := Open(dir, newList(t), WithSyncPolicy(SyncNever))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutate(t, l, want, 0, 300)
+	if err = l.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if stat, _ := os.Stat(filepath.Join(dir, logFileName)); stat.Size() != 0 {
+		t.Fatalf("快照后日志应被截断, size: %d", stat.Size())
+	}
+	mutate(t, l, want, 300, 500)
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(dir, newList(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSame(t, l.List(), want)
+	mutate(t, l, want, 500, 600)
+	l.Close()
+
+	l, err = Open(dir, newList(t), WithSyncInterval(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	assertSame(t, l.List(), want)
+}
+
+// TestTornTail 测试日志尾部写了一半的记录会被丢弃并截断
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, newList(t))
+	for i := 0; i < 10; i++ {
+		l.Insert(skiplist.CmpInstanceInt(i), i)
+	}
+	l.Close()
+
+	path := filepath.Join(dir, logFileName)
+	stat, _ := os.Stat(path)
+	full := stat.Size()
+	os.Truncate(path, full-3)
+
+	l, err := Open(dir, newList(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := l.List().GetLength(); n != 9 {
+		t.Fatalf("应恢复 9 条记录, got: %d", n)
+	}
+	//截断后可以继续正常追加
+	l.Insert(skiplist.CmpInstanceInt(100), 100)
+	l.Close()
+
+	l, _ = Open(dir, newList(t))
+	defer l.Close()
+	if n := l.List().GetLength(); n != 10 || l.List().GetTail() != 100 {
+		t.Fatalf("追加后恢复错误, length: %d tail: %v", n, l.List().GetTail())
+	}
+}
+
+// TestCorruptMiddle 测试中间的记录损坏时返回错误，不截断之后的记录
+func TestCorruptMiddle(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, newList(t))
+	for i := 0; i < 3; i++ {
+		l.Insert(skiplist.CmpInstanceInt(i), i)
+	}
+	l.Close()
+
+	path := filepath.Join(dir, logFileName)
+	data, _ := os.ReadFile(path)
+	rr := newRecordReader(bytes.NewReader(data))
+	rr.next()
+	data[rr.Offset+frame.HeaderSize+1] ^= 0xff
+	os.WriteFile(path, data, 0644)
+
+	if _, err := Open(dir, newList(t)); !errors.Is(err, recordErr) {
+		t.Fatalf("want recordErr, got %v", err)
+	}
+	if stat, _ := os.Stat(path); stat.Size() != int64(len(data)) {
+		t.Fatalf("损坏的日志被截断, size: %d want %d", stat.Size(), len(data))
+	}
+}
+
+// TestSnapshotBeforeTruncate 测试快照重命名后、截断日志前崩溃，重放不会重复执行日志
+func TestSnapshotBeforeTruncate(t *testing.T) {
+	dir := t.TempDir()
+	want := newList(t)
+	l, _ := Open(dir, newList(t), WithSyncPolicy(SyncNever))
+	mutate(t, l, want, 0, 200)
+	path := filepath.Join(dir, logFileName)
+	old, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	//模拟未截断的日志
+	if err = os.WriteFile(path, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(dir, newList(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSame(t, l.List(), want)
+	mutate(t, l, want, 200, 300)
+	l.Close()
+
+	l, _ = Open(dir, newList(t))
+	defer l.Close()
+	assertSame(t, l.List(), want)
+}
+
+// 只写入一半数据后返回错误的日志文件
+type tornFile struct {
+	logFile
+	fail bool
+}
+
+func (f *tornFile) Write(p []byte) (int, error) {
+	if f.fail {
+		f.fail = false
+		n, _ := f.logFile.Write(p[:len(p)/2])
+		return n, errors.New("disk full")
+	}
+	return f.logFile.Write(p)
+}
+
+// TestTornWrite 测试写入失败后截断写了一半的记录，之后追加的记录可以正常重放
+func TestTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, newList(t))
+	for i := 0; i < 5; i++ {
+		l.Insert(skiplist.CmpInstanceInt(i), i)
+	}
+	l.f = &tornFile{logFile: l.f, fail: true}
+	if _, _, err := l.Insert(skiplist.CmpInstanceInt(5), 5); err == nil {
+		t.Fatal("写入失败时应返回错误")
+	}
+	for i := 6; i < 10; i++ {
+		if _, _, err := l.Insert(skiplist.CmpInstanceInt(i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	l, _ = Open(dir, newList(t))
+	defer l.Close()
+	if n := l.List().GetLength(); n != 9 || l.List().GetTail() != 9 {
+		t.Fatalf("应恢复写入成功的 9 条记录, length: %d tail: %v", n, l.List().GetTail())
+	}
+}
+
+// TestClosed 测试关闭后的写入
+func TestClosed(t *testing.T) {
+	l, _ := Open(t.TempDir(), newList(t))
+	l.Close()
+	if _, _, err := l.Insert(skiplist.CmpInstanceInt(1), 1); err != closedErr {
+		t.Fatalf("关闭后写入应失败, got: %v", err)
+	}
+	if err := l.Close(); err != closedErr {
+		t.Fatalf("重复关闭应失败, got: %v", err)
+	}
+}